@@ -0,0 +1,214 @@
+// Package analyzer は cire の依存関係の解析を go/analysis の Analyzer として提供する。
+//
+// go vet -vettool や multichecker に組み込むと、ルート構造体を定義したファイル（既定では cire.go）の
+// プロバイダの不足・曖昧さ・循環を通常の lint と同じように報告する。
+//
+//	go vet -vettool=$(which cirevet) ./...
+//
+// go/analysis のパスからは依存パッケージの API の一部しか参照できないため、
+// cire generate と同じく cire.yaml に従ってパッケージをロードして解析し、結果をパスの位置で報告する。
+// ルート構造体を定義したファイルには通常 //go:build cire が付いており go vet のビルドから除外されるが、
+// 除外されたファイル（pass.IgnoredFiles）も探すため -tags cire を付ける必要は無い
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/rmocchy/cire/internal/diag"
+	"golang.org/x/tools/go/analysis"
+)
+
+const doc = `report missing, ambiguous and cyclic dependencies of cire root structs
+
+The cire analyzer resolves the providers of every field of the root structs
+declared in cire files (see -files) with the same configuration as
+cire generate, and reports the problems at the root field or, when it is in
+the analyzed package, at the constructor.`
+
+// Analyzer は cire のルート構造体の依存関係を検査する
+var Analyzer = &analysis.Analyzer{
+	Name: "cire",
+	Doc:  doc,
+	URL:  "https://github.com/rmocchy/cire",
+	Run:  run,
+}
+
+// files はルート構造体を定義したファイル名（カンマ区切り）
+var files string
+
+func init() {
+	Analyzer.Flags.StringVar(&files, "files", app.InitFileName, "comma-separated base names of files that define root structs")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	for _, f := range pass.Files {
+		// テストを含むパッケージでは同じルート構造体を二重に報告しないよう、テストを含まないパッケージだけを解析する
+		if strings.HasSuffix(pass.Fset.File(f.Pos()).Name(), "_test.go") {
+			return nil, nil
+		}
+	}
+	names := strings.Split(files, ",")
+	paths := make([]string, 0, len(pass.Files)+len(pass.IgnoredFiles))
+	for _, f := range pass.Files {
+		paths = append(paths, pass.Fset.File(f.Pos()).Name())
+	}
+	paths = append(paths, pass.IgnoredFiles...)
+	for _, path := range paths {
+		if !slices.Contains(names, filepath.Base(path)) {
+			continue
+		}
+		if slices.Contains(pass.IgnoredFiles, path) {
+			if err := addIgnoredFile(pass, path); err != nil {
+				return nil, err
+			}
+		}
+		if err := check(pass, path); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// addIgnoredFile はビルドタグで除外されたファイルを pass.Fset に加え、そのファイルの位置で報告できるようにする
+func addIgnoredFile(pass *analysis.Pass, path string) error {
+	if tokenFile(pass, path) != nil {
+		return nil
+	}
+	content, err := pass.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pass.Fset.AddFile(path, -1, len(content)).SetLinesForContent(content)
+	return nil
+}
+
+// tokenFile は解析中のパッケージのファイル（addIgnoredFile で加えた除外されたファイルを含む）を返す
+func tokenFile(pass *analysis.Pass, filename string) *token.File {
+	for _, f := range pass.Files {
+		if tf := pass.Fset.File(f.Pos()); tf.Name() == filename {
+			return tf
+		}
+	}
+	if !slices.Contains(pass.IgnoredFiles, filename) {
+		return nil
+	}
+	var found *token.File
+	pass.Fset.Iterate(func(tf *token.File) bool {
+		if tf.Name() == filename {
+			found = tf
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// check はルート構造体を定義したファイルを cire generate と同じ設定でロードし、ルート構造体のフィールドごとに解析する
+func check(pass *analysis.Pass, path string) error {
+	cfg, err := app.LoadConfig(path, "", nil)
+	if err != nil {
+		return err
+	}
+	proj, err := app.LoadProject(path, cfg)
+	if err != nil {
+		return err
+	}
+
+	fields, err := proj.AnalyzeFields()
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fieldPos := position(pass, proj.RootPkg.Fset.Position(f.Field.Pos()))
+		for _, d := range f.Diagnostics {
+			pass.Report(newDiagnostic(pass, d, fieldPos))
+		}
+	}
+	return nil
+}
+
+// newDiagnostic は cire の診断を analysis.Diagnostic に変換する。
+// 診断の位置が解析中のパッケージに無い場合は、問題の原因になったルート構造体のフィールドで報告する
+func newDiagnostic(pass *analysis.Pass, d *diag.Diagnostic, fieldPos token.Pos) analysis.Diagnostic {
+	result := analysis.Diagnostic{
+		Pos:      fieldPos,
+		Category: string(d.Code),
+		Message:  fmt.Sprintf("%s: %s", d.Code, d.Message),
+	}
+	if pos := position(pass, d.Position); pos.IsValid() {
+		result.Pos = pos
+	} else if d.Position.Filename != "" {
+		result.Related = append(result.Related, related(pass, result.Pos, d.Position, "reported here"))
+	}
+	for _, r := range d.Related {
+		result.Related = append(result.Related, related(pass, result.Pos, r.Position, r.Message))
+	}
+	if d.Code == diag.AmbiguousProvider {
+		result.SuggestedFixes = ignoreFixes(pass, d)
+	}
+	return result
+}
+
+// related は関連する位置を返す。解析中のパッケージに無い位置は、診断の位置に "file:line:col" を添えて示す
+func related(pass *analysis.Pass, diagPos token.Pos, p token.Position, message string) analysis.RelatedInformation {
+	if pos := position(pass, p); pos.IsValid() {
+		return analysis.RelatedInformation{Pos: pos, Message: message}
+	}
+	if formatted := diag.FormatPosition(p); formatted != "" {
+		message = formatted + ": " + message
+	}
+	return analysis.RelatedInformation{Pos: diagPos, Message: message}
+}
+
+// ignoreFixes は曖昧なプロバイダの候補の関数ごとに、IgnoreDirective を付けて候補から外す修正を返す。
+// 修正できるのは解析中のパッケージで宣言された関数だけ（他のパッケージは構文木が無いため位置を確定できない）
+func ignoreFixes(pass *analysis.Pass, d *diag.Diagnostic) []analysis.SuggestedFix {
+	fixes := make([]analysis.SuggestedFix, 0)
+	for _, r := range d.Related {
+		fd := funcDeclAt(pass, position(pass, r.Position))
+		if fd == nil {
+			continue
+		}
+		fixes = append(fixes, analysis.SuggestedFix{
+			Message:   fmt.Sprintf("Add %s to %s", analyze.IgnoreDirective, fd.Name.Name),
+			TextEdits: []analysis.TextEdit{{Pos: fd.Pos(), End: fd.Pos(), NewText: []byte(analyze.IgnoreDirective + "\n")}},
+		})
+	}
+	return fixes
+}
+
+// funcDeclAt は名前の位置が pos であるパッケージレベルの関数の宣言を返す
+func funcDeclAt(pass *analysis.Pass, pos token.Pos) *ast.FuncDecl {
+	if !pos.IsValid() {
+		return nil
+	}
+	for _, f := range pass.Files {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Pos() == pos {
+				return fd
+			}
+		}
+	}
+	return nil
+}
+
+// position は解析で解決した位置を pass.Fset の位置に戻す。
+// 解析中のパッケージのファイルに無い位置は token.NoPos（依存パッケージの位置はエクスポートデータから復元したもので正確でないため）
+func position(pass *analysis.Pass, p token.Position) token.Pos {
+	if p.Filename == "" || p.Line == 0 {
+		return token.NoPos
+	}
+	tf := tokenFile(pass, p.Filename)
+	if tf == nil || p.Line > tf.LineCount() {
+		return token.NoPos
+	}
+	offset := tf.Offset(tf.LineStart(p.Line)) + max(p.Column-1, 0)
+	return tf.Pos(min(offset, tf.Size()))
+}
@@ -0,0 +1,55 @@
+package analyzer_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/cire/analyzer"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestAnalyzer(t *testing.T) {
+	// analysistest はビルドに含まれるファイルからしか // want を読まないため、cire.go をビルドに含める
+	t.Setenv("GOFLAGS", "-tags=cire")
+	dir := filepath.Join(analysistest.TestData(), "app")
+	analysistest.RunWithSuggestedFixes(t, dir, analyzer.Analyzer, "./...")
+}
+
+func TestAnalyzer_IgnoredFile(t *testing.T) {
+	// go vet と同じく -tags cire を付けずにロードすると、cire.go は pass.IgnoredFiles に入る
+	dir := filepath.Join(analysistest.TestData(), "app")
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadAllSyntax, Dir: dir}, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := checker.Analyze([]*analysis.Analyzer{analyzer.Analyzer}, pkgs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0)
+	for act := range graph.All() {
+		if act.Analyzer != analyzer.Analyzer || act.Err != nil {
+			continue
+		}
+		for _, d := range act.Diagnostics {
+			posn := act.Package.Fset.Position(d.Pos)
+			got = append(got, fmt.Sprintf("%s:%d: %s", filepath.Base(posn.Filename), posn.Line, d.Category))
+		}
+	}
+	want := []string{
+		"cire.go:8: CIRE002",
+		"cire.go:9: CIRE002",
+		"cire.go:10: CIRE001",
+		"cire.go:11: CIRE003",
+		"handler.go:9: CIRE001",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
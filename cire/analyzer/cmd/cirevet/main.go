@@ -0,0 +1,11 @@
+// Command cirevet は cire の Analyzer を単体で、または go vet -vettool から実行する
+package main
+
+import (
+	"github.com/rmocchy/cire/cire/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
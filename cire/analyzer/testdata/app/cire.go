@@ -0,0 +1,14 @@
+//go:build cire
+
+package app
+
+import "example.com/app/service"
+
+type App struct {
+	svc     *service.Service // want `CIRE002: multiple providers found for example.com/app/service.Service \(required by field App.svc\): NewAltService, NewService`
+	logger  *Logger          // want `CIRE002: multiple providers found for example.com/app.Logger \(required by field App.logger\): NewDebugLogger, NewLogger`
+	cache   *Cache           // want `CIRE001: no provider found for \*example.com/app.Cache \(required by field App.cache\)`
+	loop    *service.Loop    // want `CIRE003: dependency cycle: example.com/app/service.Loop -> example.com/app/service.Ring -> example.com/app/service.Loop`
+	handler *Handler
+	store   *service.Store
+}
@@ -0,0 +1,22 @@
+package app
+
+type Cache struct{}
+
+type Clock struct{}
+
+type Handler struct{}
+
+func NewHandler(clock Clock) *Handler { // want `CIRE001: no provider found for example.com/app.Clock \(required by parameter clock of app.NewHandler\)`
+	return &Handler{}
+}
+
+type Logger struct{}
+
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+// NewDebugLogger は詳細なログを出力する
+func NewDebugLogger() *Logger {
+	return &Logger{}
+}
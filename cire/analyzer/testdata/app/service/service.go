@@ -0,0 +1,36 @@
+package service
+
+type Service struct{}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func NewAltService() *Service {
+	return &Service{}
+}
+
+type Loop struct{}
+
+type Ring struct{}
+
+func NewLoop(r *Ring) *Loop {
+	return &Loop{}
+}
+
+func NewRing(l *Loop) *Ring {
+	return &Ring{}
+}
+
+type Store struct{}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+// NewMockStore は依存パッケージのアノテーションも解析に使うことを確認する
+//
+//cire:ignore
+func NewMockStore() *Store {
+	return &Store{}
+}
@@ -0,0 +1,187 @@
+// Package cire は cire の解析とコード生成を他のツールから呼び出すための API を提供する。
+//
+// CLI の cire generate と同じ処理を Load、Analyze、Generate の3段階に分けて実行できる。
+//
+//	project, err := cire.Load(cire.Options{File: "./cire.go"})
+//	graph, err := cire.Analyze(project)
+//	code, err := cire.Generate(graph, cire.BackendWire, &cire.GenerateOptions{Output: w})
+//
+// このパッケージで公開している型と関数のシグネチャは互換性を保って変更する
+package cire
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/rmocchy/cire/internal/config"
+)
+
+// BackendWire は Google Wire 向けのコードを生成するバックエンド
+const BackendWire = config.BackendWire
+
+// Options はパッケージのロードの設定
+type Options struct {
+	// File はルート構造体を定義したファイル（必須）
+	File string
+	// ConfigPath は設定ファイルのパス。空の場合は File のディレクトリから上方向に cire.yaml を探す
+	ConfigPath string
+	// 以下は指定した場合に設定ファイルの値を上書きする（nil の場合は設定ファイルの値を使う）
+	Patterns  []string
+	Exclude   []string
+	BuildTags []string
+	// Resolvers は組み込みのリゾルバ（関数・インターフェースの束縛・構造体・フィールド）に加えて使うリゾルバ
+	Resolvers []ProviderResolver
+	// Overlay はファイルのパスから、ディスク上の内容の代わりに使う内容への対応（保存されていないバッファなど）
+	Overlay map[string][]byte
+}
+
+// ProviderResolver は型のプロバイダの候補を探す拡張点。
+// 全てのリゾルバの候補のうち Rank が最も小さいものを使い、複数ある場合は曖昧として診断を返す
+type ProviderResolver = analyze.ProviderResolver
+
+// ResolveRequest はリゾルバに渡す、プロバイダを探す型とロード済みのパッケージ
+type ResolveRequest = analyze.ResolveRequest
+
+// ResolvedProvider はリゾルバが見つけたプロバイダの候補
+type ResolvedProvider = analyze.ResolvedProvider
+
+// ProviderKind はプロバイダの種類
+type ProviderKind = analyze.ProviderKind
+
+const (
+	ProviderFunc   = analyze.ProviderFunc
+	ProviderBind   = analyze.ProviderBind
+	ProviderStruct = analyze.ProviderStruct
+	ProviderField  = analyze.ProviderField
+)
+
+// 組み込みのリゾルバが返す候補の優先度
+const (
+	RankFunction = analyze.RankFunction
+	RankBinding  = analyze.RankBinding
+	RankStruct   = analyze.RankStruct
+	RankField    = analyze.RankField
+)
+
+// Project はロード済みのパッケージと解析対象のルート構造体
+type Project struct {
+	proj *app.Project
+}
+
+// Load は設定ファイルを読み込み、パッケージをロードして File のルート構造体を取り出す
+func Load(opts Options) (*Project, error) {
+	if opts.File == "" {
+		return nil, fmt.Errorf("no input file specified")
+	}
+	cfg, err := app.LoadConfig(opts.File, opts.ConfigPath, &app.ConfigOverrides{
+		Patterns:  opts.Patterns,
+		Exclude:   opts.Exclude,
+		BuildTags: opts.BuildTags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := absOverlay(opts.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	proj, err := app.LoadProjectWithOverlay(opts.File, cfg, overlay)
+	if err != nil {
+		return nil, err
+	}
+	proj.Resolvers = opts.Resolvers
+	return &Project{proj: proj}, nil
+}
+
+// absOverlay はオーバーレイのキーを絶対パスにする（packages.Config.Overlay は絶対パスで照合するため）
+func absOverlay(overlay map[string][]byte) (map[string][]byte, error) {
+	if len(overlay) == 0 {
+		return nil, nil
+	}
+	result := make(map[string][]byte, len(overlay))
+	for path, content := range overlay {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve overlay path: %w", err)
+		}
+		result[abs] = content
+	}
+	return result, nil
+}
+
+// Roots はルート構造体の名前をソースコード上の定義順に返す
+func (p *Project) Roots() []string {
+	names := make([]string, 0, len(p.proj.Structs))
+	for _, s := range p.proj.Structs {
+		names = append(names, s.Obj().Name())
+	}
+	return names
+}
+
+// PackagePath は File のパッケージのパスを返す
+func (p *Project) PackagePath() string {
+	return p.proj.RootPkg.PkgPath
+}
+
+// Analyze はルート構造体の依存関係を解析する。roots を省略した場合は全てのルート構造体を解析する。
+// 解析で見つかった問題はエラーではなく Graph.Diagnostics として返す
+func Analyze(p *Project, roots ...string) (*Graph, error) {
+	proj := p.proj
+	if len(roots) > 0 {
+		selected := *p.proj
+		selected.Structs = nil
+		for _, name := range roots {
+			i := slices.Index(p.Roots(), name)
+			if i < 0 {
+				return nil, fmt.Errorf("root struct %q not found in %s", name, p.proj.InputPath)
+			}
+			selected.Structs = append(selected.Structs, p.proj.Structs[i])
+		}
+		proj = &selected
+	}
+
+	analysis, err := app.Analyze(proj)
+	if err != nil {
+		return nil, err
+	}
+	return newGraph(analysis), nil
+}
+
+// GenerateOptions はコード生成の設定
+type GenerateOptions struct {
+	// Template は TemplateData を受け取る text/template のテキスト。空の場合は組み込みのテンプレートを使う
+	Template string
+	// Command は生成ファイルのヘッダーに記録する再生成用のコマンドライン
+	Command string
+	// Output が nil でない場合は生成したコードを書き出す
+	Output io.Writer
+}
+
+// Generate は解析結果からコードを生成する。backend が空の場合は設定ファイルのバックエンドを使う。
+// Graph に error の診断がある場合はエラーを返す
+func Generate(g *Graph, backend string, opts *GenerateOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &GenerateOptions{}
+	}
+	if backend == "" {
+		backend = g.analysis.Project.Config.Backend
+	}
+	if backend != BackendWire {
+		return nil, fmt.Errorf("unsupported backend: %q", backend)
+	}
+
+	code, _, err := g.analysis.Generate(&app.WireOptions{Template: opts.Template, Command: opts.Command})
+	if err != nil {
+		return nil, err
+	}
+	if opts.Output != nil {
+		if _, err := opts.Output.Write(code); err != nil {
+			return nil, fmt.Errorf("failed to write generated code: %w", err)
+		}
+	}
+	return code, nil
+}
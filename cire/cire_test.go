@@ -0,0 +1,202 @@
+package cire_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/cire"
+)
+
+// 公開 API のシグネチャが変わった場合にコンパイルエラーで検出する
+var (
+	_ func(cire.Options) (*cire.Project, error)                        = cire.Load
+	_ func(*cire.Project, ...string) (*cire.Graph, error)              = cire.Analyze
+	_ func(*cire.Graph, string, *cire.GenerateOptions) ([]byte, error) = cire.Generate
+	_ func(*cire.Project) []string                                     = (*cire.Project).Roots
+	_ func(*cire.Project) string                                       = (*cire.Project).PackagePath
+	_ func(*cire.Graph) bool                                           = (*cire.Graph).HasErrors
+	_ func(cire.Diagnostic) string                                     = cire.Diagnostic.String
+	_                                                                  = cire.Options{File: "", ConfigPath: "", Patterns: nil, Exclude: nil, BuildTags: nil, Resolvers: nil}
+	_                                                                  = cire.GenerateOptions{Template: "", Command: "", Output: io.Discard}
+	_                                                                  = cire.Related{Position: cire.Diagnostic{}.Position, Message: ""}
+	_                                                                  = cire.Provider{ID: "", Name: "", PkgPath: "", PkgName: "", Kind: cire.ProviderFunc, Params: nil, Results: nil, Dependencies: nil}
+	_                                                                  = cire.Root{Name: "", Fields: nil, Providers: nil}
+	_                                                                  = cire.Graph{PackageName: "", PackagePath: "", Roots: nil, Diagnostics: nil}
+	_ cire.Severity                                                    = cire.SeverityError
+	_ cire.Severity                                                    = cire.SeverityWarning
+	_                                                                  = cire.BackendWire
+	_ cire.ProviderResolver                                            = (*clockResolver)(nil)
+	_                                                                  = cire.ResolvedProvider{Kind: cire.ProviderBind, Rank: cire.RankBinding}
+	_                                                                  = cire.TemplateData{Version: cire.TemplateDataVersion, Generator: cire.TemplateGenerator{}, PackageName: "", PackagePath: "", Profile: "", TestTag: "", Imports: []cire.TemplateImport{{Alias: "", Path: ""}}, ProviderSets: []cire.TemplateProviderSet{}, SharedSets: []cire.TemplateSharedSet{}}
+	_                                                                  = cire.TemplateProviderSet{StructName: "", SetName: "", InjectorName: "", Providers: []cire.TemplateProvider{}, SharedSets: nil}
+	_                                                                  = cire.TemplateProvider{PkgPath: "", PkgName: "", Name: "", Alias: "", Kind: cire.TemplateKindBind, Source: &cire.TemplateTypeRef{Pointer: true}, Field: ""}
+)
+
+// clockResolver は cire.ProviderResolver を外部のパッケージから実装できることを確認する
+type clockResolver struct{}
+
+func (clockResolver) Name() string                                          { return "clock" }
+func (clockResolver) Resolve(*cire.ResolveRequest) []*cire.ResolvedProvider { return nil }
+
+func TestGenerate_Basic(t *testing.T) {
+	project, err := cire.Load(cire.Options{File: "../sample/basic/cire.go"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := project.Roots(); !slices.Equal(got, []string{"App"}) {
+		t.Errorf("Roots() = %v, want [App]", got)
+	}
+	if got := project.PackagePath(); got != "github.com/rmocchy/cire/sample/basic" {
+		t.Errorf("PackagePath() = %q", got)
+	}
+
+	graph, err := cire.Analyze(project)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if graph.HasErrors() || len(graph.Diagnostics) != 0 {
+		t.Fatalf("Diagnostics = %v, want none", graph.Diagnostics)
+	}
+	if len(graph.Roots) != 1 {
+		t.Fatalf("len(Roots) = %d, want 1", len(graph.Roots))
+	}
+	names := make([]string, 0)
+	for _, p := range graph.Roots[0].Providers {
+		names = append(names, p.Name)
+		if p.Kind != cire.ProviderFunc {
+			t.Errorf("%s.Kind = %q, want %q", p.ID, p.Kind, cire.ProviderFunc)
+		}
+		if p.Position.Line == 0 {
+			t.Errorf("%s の位置が設定されていません", p.ID)
+		}
+	}
+	if want := []string{"NewUserHandler", "NewUserService", "NewUserRepository", "NewConfig"}; !slices.Equal(names, want) {
+		t.Errorf("Providers = %v, want %v", names, want)
+	}
+	handler := graph.Roots[0].Providers[0]
+	if want := []string{"github.com/rmocchy/cire/sample/basic/service.NewUserService"}; !slices.Equal(handler.Dependencies, want) {
+		t.Errorf("NewUserHandler.Dependencies = %v, want %v", handler.Dependencies, want)
+	}
+
+	var buf bytes.Buffer
+	code, err := cire.Generate(graph, "", &cire.GenerateOptions{Command: "go generate", Output: &buf})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), code) {
+		t.Errorf("Output に書き出した内容が返り値と一致しません")
+	}
+	for _, want := range []string{"package main", "func InitializeApp(", "wire.Struct(new(App)", "go generate"} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestAnalyze_SelectRoots(t *testing.T) {
+	project, err := cire.Load(cire.Options{File: "../sample/complex/cire.go"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	graph, err := cire.Analyze(project, "OrderApp")
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if len(graph.Roots) != 1 || graph.Roots[0].Name != "OrderApp" {
+		t.Errorf("Roots = %v, want only OrderApp", graph.Roots)
+	}
+
+	if _, err := cire.Analyze(project, "Unknown"); err == nil {
+		t.Error("Analyze() with an unknown root error = nil, want error")
+	}
+}
+
+func TestGenerate_Diagnostics(t *testing.T) {
+	project, err := cire.Load(cire.Options{File: "../sample/duplicate/cire.go"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	graph, err := cire.Analyze(project)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if !graph.HasErrors() {
+		t.Fatal("HasErrors() = false, want true")
+	}
+	d := graph.Diagnostics[0]
+	if d.Code != "CIRE002" || d.Severity != cire.SeverityError || d.Position.Line == 0 || len(d.Related) != 2 {
+		t.Errorf("Diagnostics[0] = %+v", d)
+	}
+
+	if _, err := cire.Generate(graph, cire.BackendWire, nil); err == nil {
+		t.Error("Generate() error = nil, want error")
+	}
+}
+
+func TestGenerate_UnsupportedBackend(t *testing.T) {
+	project, err := cire.Load(cire.Options{File: "../sample/basic/cire.go"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	graph, err := cire.Analyze(project)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if _, err := cire.Generate(graph, "dig", nil); err == nil {
+		t.Error("Generate() error = nil, want error")
+	}
+}
+
+func TestGenerate_Template(t *testing.T) {
+	project, err := cire.Load(cire.Options{File: "../sample/basic/cire.go"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	graph, err := cire.Analyze(project)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	// TemplateData のドキュメントに記載したフィールドとヘルパーだけで書いたテンプレート
+	tmpl := `// data model v{{.Version}}
+
+package {{.PackageName}}
+
+import (
+	"github.com/google/wire"
+{{- range .Imports}}
+	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{- end}}
+)
+{{range .ProviderSets}}
+var {{.SetName}} = wire.NewSet(
+{{- range .Providers}}
+	{{qualify .}},
+{{- end}}
+)
+
+func {{.InjectorName}}() (*{{.StructName}}, error) {
+	wire.Build({{.SetName}})
+	return nil, nil
+}
+{{end}}`
+	code, err := cire.Generate(graph, "", &cire.GenerateOptions{Template: tmpl})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("// data model v%d", cire.TemplateDataVersion),
+		"var AppSet = wire.NewSet(",
+		"service.NewUserService,",
+		"func InitializeApp() (*App, error) {",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
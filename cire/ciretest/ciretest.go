@@ -0,0 +1,269 @@
+// Package ciretest は cire の解析結果の依存関係をテストで検証するためのヘルパーを提供する。
+//
+// DI の構成の退行（意図しないパッケージへの依存や、プロバイダの差し替わり）を go test で検出できる。
+//
+//	func TestWiring(t *testing.T) {
+//		g := ciretest.Resolve(t, "./cire.go")
+//		g.NoDiagnostics(t)
+//		g.NotDependsOn(t, "OrderApp", "github.com/acme/payments/...")
+//		if got := g.ProviderFor(t, "repository.UserRepository"); got != "postgres.NewUserRepository" {
+//			t.Errorf("UserRepository is provided by %s", got)
+//		}
+//	}
+//
+// プロバイダの指定（セレクタ）には次のいずれかを使える。
+//   - プロバイダの ID（"github.com/acme/service.NewUserService"）または「パッケージ名.名前」（"service.NewUserService"）
+//   - パッケージパス（"github.com/acme/payments"）または "/..." で終わるパッケージパターン
+//   - プロバイダが返す型（"*handler.UserHandler" や完全修飾名。ポインタの有無は区別しない）
+package ciretest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rmocchy/cire/cire"
+	"github.com/rmocchy/cire/internal/graph"
+)
+
+// TestingT は *testing.T のうち ciretest が使うメソッド
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// Graph はアサーションを持つ解析結果
+type Graph struct {
+	*cire.Graph
+	// providers はプロバイダの ID からプロバイダを引く。同じプロバイダは全てのルート構造体で同じ依存を持つ
+	providers map[string]*cire.Provider
+	// order はプロバイダの ID を最初に現れた順に並べたもの（結果を決定的にするために使う）
+	order []string
+}
+
+// Resolve はルート構造体を定義したファイルを cire generate と同じ設定で解析する。
+// ロードや解析に失敗した場合はテストを中断する。解析で見つかった問題は NoDiagnostics で検証する
+func Resolve(t TestingT, file string) *Graph {
+	t.Helper()
+	return ResolveOptions(t, cire.Options{File: file})
+}
+
+// ResolveOptions は Resolve と同じく解析する。パッケージパターンやオーバーレイを指定する場合に使う
+func ResolveOptions(t TestingT, opts cire.Options) *Graph {
+	t.Helper()
+	project, err := cire.Load(opts)
+	if err != nil {
+		t.Fatalf("ciretest: failed to load %s: %v", opts.File, err)
+		return nil
+	}
+	g, err := cire.Analyze(project)
+	if err != nil {
+		t.Fatalf("ciretest: failed to analyze %s: %v", opts.File, err)
+		return nil
+	}
+	return newGraph(g)
+}
+
+func newGraph(g *cire.Graph) *Graph {
+	result := &Graph{Graph: g, providers: make(map[string]*cire.Provider)}
+	for _, root := range g.Roots {
+		for i := range root.Providers {
+			p := &root.Providers[i]
+			if _, ok := result.providers[p.ID]; ok {
+				continue
+			}
+			result.providers[p.ID] = p
+			result.order = append(result.order, p.ID)
+		}
+	}
+	return result
+}
+
+// NoDiagnostics は解析で問題（警告を含む）が見つからなかったことを検証する
+func (g *Graph) NoDiagnostics(t TestingT) {
+	t.Helper()
+	if len(g.Diagnostics) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(g.Diagnostics))
+	for _, d := range g.Diagnostics {
+		lines = append(lines, "\t"+d.String())
+	}
+	t.Errorf("ciretest: expected no diagnostics, got %d:\n%s", len(g.Diagnostics), strings.Join(lines, "\n"))
+}
+
+// DependsOn は from（ルート構造体名またはプロバイダのセレクタ）から to に一致するプロバイダに到達することを検証する
+func (g *Graph) DependsOn(t TestingT, from, to string) {
+	t.Helper()
+	starts, ok := g.starts(t, from)
+	if !ok {
+		return
+	}
+	if path := g.path(starts, to); path != nil {
+		return
+	}
+	reached := make([]string, 0)
+	for _, id := range g.reachable(starts) {
+		reached = append(reached, shortName(g.providers[id]))
+	}
+	t.Errorf("ciretest: %s does not depend on %s\n\treachable providers: %s", from, to, strings.Join(reached, ", "))
+}
+
+// NotDependsOn は from（ルート構造体名またはプロバイダのセレクタ）から to に一致するプロバイダに到達しないことを検証する。
+// 失敗した場合は到達する経路を示す
+func (g *Graph) NotDependsOn(t TestingT, from, to string) {
+	t.Helper()
+	starts, ok := g.starts(t, from)
+	if !ok {
+		return
+	}
+	path := g.path(starts, to)
+	if path == nil {
+		return
+	}
+	names := make([]string, 0, len(path)+1)
+	if g.root(from) != nil {
+		names = append(names, from)
+	}
+	for _, id := range path {
+		names = append(names, shortName(g.providers[id]))
+	}
+	t.Errorf("ciretest: %s must not depend on %s, but it does via\n\t%s", from, to, strings.Join(names, " -> "))
+}
+
+// ProviderFor は型を供給するプロバイダの「パッケージ名.名前」（例: "repository.NewUserRepository"）を返す。
+// インターフェースの束縛は実装のプロバイダを返す。見つからない場合はテストを失敗させて空文字列を返す
+func (g *Graph) ProviderFor(t TestingT, typ string) string {
+	t.Helper()
+	for _, id := range g.order {
+		p := g.providers[id]
+		if !returnsType(p, typ) {
+			continue
+		}
+		if p.Kind == cire.ProviderBind && len(p.Dependencies) > 0 {
+			if impl, ok := g.providers[p.Dependencies[0]]; ok {
+				p = impl
+			}
+		}
+		return shortName(p)
+	}
+	t.Errorf("ciretest: no provider for %s in the graph", typ)
+	return ""
+}
+
+// root は名前が name のルート構造体を返す。見つからない場合は nil
+func (g *Graph) root(name string) *cire.Root {
+	for i := range g.Roots {
+		if g.Roots[i].Name == name {
+			return &g.Roots[i]
+		}
+	}
+	return nil
+}
+
+// starts は from がルート構造体名ならそのフィールドのプロバイダ、そうでなければセレクタに一致するプロバイダを返す
+func (g *Graph) starts(t TestingT, from string) ([]string, bool) {
+	t.Helper()
+	if root := g.root(from); root != nil {
+		return root.Fields, true
+	}
+	starts := make([]string, 0)
+	for _, id := range g.order {
+		if matches(g.providers[id], from) {
+			starts = append(starts, id)
+		}
+	}
+	if len(starts) == 0 {
+		t.Errorf("ciretest: %s is neither a root struct nor a provider in the graph", from)
+		return nil, false
+	}
+	return starts, true
+}
+
+// path は starts から幅優先で辿り、to に一致するプロバイダまでの最短の経路（プロバイダの ID）を返す。到達しない場合は nil
+func (g *Graph) path(starts []string, to string) []string {
+	prev := make(map[string]string)
+	queue := make([]string, 0, len(starts))
+	for _, id := range starts {
+		if _, ok := prev[id]; !ok {
+			prev[id] = ""
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		p, ok := g.providers[id]
+		if !ok {
+			continue
+		}
+		if matches(p, to) {
+			path := []string{id}
+			for prev[id] != "" {
+				id = prev[id]
+				path = append(path, id)
+			}
+			slices.Reverse(path)
+			return path
+		}
+		for _, dep := range p.Dependencies {
+			if _, ok := prev[dep]; !ok {
+				prev[dep] = id
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// reachable は starts から到達する全てのプロバイダの ID を返す
+func (g *Graph) reachable(starts []string) []string {
+	visited := make(map[string]bool)
+	result := make([]string, 0)
+	var visit func(id string)
+	visit = func(id string) {
+		p, ok := g.providers[id]
+		if visited[id] || !ok {
+			return
+		}
+		visited[id] = true
+		result = append(result, id)
+		for _, dep := range p.Dependencies {
+			visit(dep)
+		}
+	}
+	for _, id := range starts {
+		visit(id)
+	}
+	return result
+}
+
+// matches はプロバイダがセレクタに一致するかどうかを返す
+func matches(p *cire.Provider, selector string) bool {
+	switch {
+	case p.ID == selector, shortName(p) == selector, p.PkgPath == selector:
+		return true
+	case strings.HasSuffix(selector, "/..."):
+		prefix := strings.TrimSuffix(selector, "/...")
+		return p.PkgPath == prefix || strings.HasPrefix(p.PkgPath, prefix+"/")
+	}
+	return returnsType(p, selector)
+}
+
+// returnsType はプロバイダが型 typ（完全修飾名または「パッケージ名.型名」。ポインタの有無は区別しない）を返すかどうかを返す
+func returnsType(p *cire.Provider, typ string) bool {
+	typ = strings.TrimPrefix(typ, "*")
+	for _, r := range p.Results {
+		r = strings.TrimPrefix(r, "*")
+		if r == typ || graph.ShortType(r) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// shortName はプロバイダを「パッケージ名.名前」で返す
+func shortName(p *cire.Provider) string {
+	return fmt.Sprintf("%s.%s", p.PkgName, p.Name)
+}
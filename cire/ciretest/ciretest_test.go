@@ -0,0 +1,114 @@
+package ciretest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/cire/ciretest"
+)
+
+// recorder は失敗メッセージを記録する ciretest.TestingT
+type recorder struct {
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.fatal = true
+}
+
+var _ ciretest.TestingT = (*testing.T)(nil)
+
+func TestGraph_Pass(t *testing.T) {
+	g := ciretest.Resolve(t, "../../sample/complex/cire.go")
+	g.NoDiagnostics(t)
+
+	g.DependsOn(t, "OrderApp", "repository.NewUserRepository")
+	g.DependsOn(t, "OrderApp", "github.com/rmocchy/cire/sample/complex/repository")
+	g.DependsOn(t, "handler.NewOrderHandler", "*github.com/rmocchy/cire/sample/complex/repository.ProductRepository")
+	g.NotDependsOn(t, "UserApp", "repository.NewProductRepository")
+	g.NotDependsOn(t, "service.NewUserService", "github.com/rmocchy/cire/sample/complex/handler/...")
+
+	if got := g.ProviderFor(t, "repository.UserRepository"); got != "repository.NewUserRepository" {
+		t.Errorf("ProviderFor(repository.UserRepository) = %q", got)
+	}
+	if got := g.ProviderFor(t, "*github.com/rmocchy/cire/sample/complex/handler.OrderHandler"); got != "handler.NewOrderHandler" {
+		t.Errorf("ProviderFor(*handler.OrderHandler) = %q", got)
+	}
+}
+
+func TestGraph_Fail(t *testing.T) {
+	g := ciretest.Resolve(t, "../../sample/complex/cire.go")
+
+	tests := []struct {
+		name   string
+		assert func(r *recorder)
+		want   []string
+	}{
+		{
+			name:   "依存していないパッケージ",
+			assert: func(r *recorder) { g.DependsOn(r, "UserApp", "repository.NewProductRepository") },
+			want:   []string{"UserApp does not depend on repository.NewProductRepository", "reachable providers: handler.NewUserHandler, service.NewUserService, repository.NewUserRepository"},
+		},
+		{
+			name: "依存している経路を示す",
+			assert: func(r *recorder) {
+				g.NotDependsOn(r, "OrderApp", "github.com/rmocchy/cire/sample/complex/repository/...")
+			},
+			want: []string{"OrderApp must not depend on", "OrderApp -> handler.NewProductHandler -> service.NewProductService -> repository.NewProductRepository"},
+		},
+		{
+			name:   "プロバイダから辿る経路",
+			assert: func(r *recorder) { g.NotDependsOn(r, "handler.NewOrderHandler", "repository.UserRepository") },
+			want:   []string{"handler.NewOrderHandler -> service.NewOrderService -> repository.NewUserRepository"},
+		},
+		{
+			name:   "存在しないルート構造体",
+			assert: func(r *recorder) { g.DependsOn(r, "AdminApp", "repository.NewUserRepository") },
+			want:   []string{"AdminApp is neither a root struct nor a provider in the graph"},
+		},
+		{
+			name:   "供給されない型",
+			assert: func(r *recorder) { g.ProviderFor(r, "payments.Client") },
+			want:   []string{"no provider for payments.Client"},
+		},
+		{
+			name: "解析の問題",
+			assert: func(r *recorder) {
+				ciretest.Resolve(r, "../../sample/duplicate/cire.go").NoDiagnostics(r)
+			},
+			want: []string{"expected no diagnostics", "CIRE002"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			tt.assert(r)
+			if len(r.errors) != 1 {
+				t.Fatalf("errors = %q, want exactly one", r.errors)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(r.errors[0], want) {
+					t.Errorf("error = %q, want to contain %q", r.errors[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolve_LoadError(t *testing.T) {
+	r := &recorder{}
+	ciretest.Resolve(r, "../../sample/missing/cire.go")
+	if !r.fatal || !strings.Contains(r.errors[0], "failed to load") {
+		t.Errorf("errors = %q, fatal = %v", r.errors, r.fatal)
+	}
+}
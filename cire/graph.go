@@ -0,0 +1,149 @@
+package cire
+
+import (
+	"go/token"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/rmocchy/cire/internal/diag"
+	"golang.org/x/tools/go/packages"
+)
+
+// Graph はルート構造体ごとのプロバイダの依存関係
+type Graph struct {
+	// PackageName と PackagePath は生成ファイルのパッケージ
+	PackageName string
+	PackagePath string
+	// Roots は解析したルート構造体（ソースコード上の定義順）
+	Roots []Root
+	// Diagnostics は解析で見つかった全ての問題
+	Diagnostics []Diagnostic
+
+	analysis *app.Analysis
+}
+
+// Root はルート構造体と、その生成に必要なプロバイダ
+type Root struct {
+	Name string
+	// Fields はフィールドの値を供給するプロバイダの ID（フィールドの定義順。プロバイダが見つからないフィールドは含まない）
+	Fields []string
+	// Providers はルート構造体のフィールドから深さ優先で辿った順のプロバイダ（重複なし）
+	Providers []Provider
+}
+
+// Provider はプロバイダ
+type Provider struct {
+	// ID は「パッケージパス.関数名」。関数以外のプロバイダでは「パッケージパス.型名」（フィールドは「パッケージパス.構造体名.フィールド名」）
+	ID      string
+	Name    string
+	PkgPath string
+	PkgName string
+	Kind    ProviderKind
+	// Position は関数または型の宣言の位置
+	Position token.Position
+	// Params は引数の型
+	Params []string
+	// Results は返り値の型
+	Results []string
+	// Dependencies は引数の値を供給するプロバイダの ID。どのプロバイダからも供給されない引数は含まない
+	Dependencies []string
+}
+
+// Severity は診断の重大度
+type Severity string
+
+const (
+	// SeverityError はコード生成を妨げる問題
+	SeverityError Severity = Severity(diag.SeverityError)
+	// SeverityWarning はコード生成を妨げないが注意が必要な問題
+	SeverityWarning Severity = Severity(diag.SeverityWarning)
+)
+
+// Diagnostic は解析で見つかった問題
+type Diagnostic struct {
+	// Code は問題の種類を表すコード（例: CIRE001）
+	Code     string
+	Severity Severity
+	Message  string
+	// Position は問題の位置。不明な場合は無効な位置（Line が 0）
+	Position token.Position
+	Related  []Related
+}
+
+// Related は診断に関連する位置
+type Related struct {
+	Position token.Position
+	Message  string
+}
+
+// String は "file:line:col: error CIRE001: message" の形式で診断を返す
+func (d Diagnostic) String() string {
+	return (&diag.Diagnostic{Code: diag.Code(d.Code), Severity: diag.Severity(d.Severity), Message: d.Message, Position: d.Position}).Error()
+}
+
+// HasErrors は重大度が error の診断を含むかどうかを返す
+func (g *Graph) HasErrors() bool {
+	return g.analysis.Diagnostics.HasErrors()
+}
+
+func newGraph(analysis *app.Analysis) *Graph {
+	positions := make(map[string]token.Position)
+	packages.Visit(analysis.Project.Pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil {
+			return
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			positions[pkg.PkgPath+"."+name] = pkg.Fset.Position(scope.Lookup(name).Pos())
+		}
+	})
+
+	g := &Graph{
+		PackageName: analysis.PackageName,
+		PackagePath: analysis.Project.RootPkg.PkgPath,
+		Roots:       make([]Root, 0, len(analysis.Trees)),
+		Diagnostics: make([]Diagnostic, 0, len(analysis.Diagnostics)),
+		analysis:    analysis,
+	}
+	for _, tree := range analysis.Trees {
+		converter := analyze.NewConvertTreeToUniqueList()
+		for _, node := range tree.Trees {
+			converter.Execute(node)
+		}
+		root := Root{Name: tree.Name, Fields: make([]string, 0, len(tree.Trees)), Providers: make([]Provider, 0, len(converter.List()))}
+		for _, node := range tree.Trees {
+			root.Fields = append(root.Fields, node.PkgPath+"."+node.Name)
+		}
+		for _, node := range converter.List() {
+			id := node.PkgPath + "." + node.Name
+			pos := positions[id]
+			if node.Source != nil {
+				pos = positions[node.Source.PkgPath+"."+node.Source.Name]
+			}
+			p := Provider{
+				ID:           id,
+				Name:         node.Name,
+				PkgPath:      node.PkgPath,
+				PkgName:      node.PkgName,
+				Kind:         node.Kind,
+				Position:     pos,
+				Params:       node.Params,
+				Results:      node.ReturnTypes,
+				Dependencies: make([]string, 0, len(node.Childs)),
+			}
+			for _, child := range node.Childs {
+				p.Dependencies = append(p.Dependencies, child.PkgPath+"."+child.Name)
+			}
+			root.Providers = append(root.Providers, p)
+		}
+		g.Roots = append(g.Roots, root)
+	}
+	for _, d := range analysis.Diagnostics {
+		pd := Diagnostic{Code: string(d.Code), Severity: Severity(d.Severity), Message: d.Message, Position: d.Position}
+		for _, r := range d.Related {
+			pd.Related = append(pd.Related, Related{Position: r.Position, Message: r.Message})
+		}
+		g.Diagnostics = append(g.Diagnostics, pd)
+	}
+	return g
+}
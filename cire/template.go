@@ -0,0 +1,89 @@
+package cire
+
+import "github.com/rmocchy/cire/internal/generate"
+
+// TemplateDataVersion は GenerateOptions.Template に渡すデータモデル（TemplateData）のバージョン。
+// フィールドの削除や意味の変更など、既存のテンプレートが壊れうる変更を行った場合に上げる
+const TemplateDataVersion = generate.DataVersion
+
+// TemplateData は GenerateOptions.Template（cire generate --template）のテンプレートに渡すデータ。
+//
+//   - Version: データモデルのバージョン（TemplateDataVersion）
+//   - Generator: 生成ファイルのヘッダーに記録する生成元の情報（TemplateGenerator）
+//   - PackageName, PackagePath: 生成ファイルのパッケージ名とインポートパス
+//   - Profile: 生成ファイルのプロファイル（cire generate --profile）。空でない場合はビルドタグとして使う
+//   - TestTag: テスト用のインジェクタがある場合に、通常の生成ファイルのビルドタグで否定して使うタグ（"ciretest"）。無い場合は空
+//   - Imports: プロバイダが参照するパッケージの import 宣言（[]TemplateImport、パスの昇順）。
+//     github.com/google/wire と生成ファイル自身のパッケージは含まない
+//   - ProviderSets: ルート構造体ごとのプロバイダセット（[]TemplateProviderSet、ルート構造体の定義順）
+//   - SharedSets: 複数のルート構造体が使うプロバイダをパッケージごとにまとめたセット（[]TemplateSharedSet、パッケージパスの昇順）。
+//     shared_sets が無効の場合は空
+//
+// テンプレートでは次のヘルパー関数を使える。
+//
+//   - qualify: TemplateProvider を生成コード内の参照式（例: "service.NewUserService"）に変換する
+//   - lowerCamel: 識別子を lowerCamelCase に変換する（例: "HTTPServer" → "httpServer"）
+//   - sortedImports: import 宣言に追加のパスを加え、重複を除いてパスの昇順に並べる（例: {{range sortedImports .Imports "context"}}）
+type TemplateData = generate.WireData
+
+// TemplateGenerator は生成ファイルを生成した cire と入力の情報。
+//
+//   - Version: cire のバージョン
+//   - Command: 生成に使ったコマンドライン（GenerateOptions.Command）。空の場合はヘッダーに出力しない
+//   - InputHash: 解析した入力のハッシュ（例: "sha256:..."）
+type TemplateGenerator = generate.GeneratorData
+
+// TemplateImport は生成ファイルの import 宣言。
+//
+//   - Alias: import のエイリアス。パッケージ名とパスの末尾が一致する場合は空
+//   - Path: インポートパス
+type TemplateImport = generate.ImportData
+
+// TemplateProviderSet はルート構造体のプロバイダセットとインジェクタ。
+//
+//   - StructName: ルート構造体名
+//   - SetName: プロバイダセットの変数名（naming.set）
+//   - InjectorName: インジェクタ関数名（naming.injector）
+//   - Providers: ルート構造体の生成に必要なプロバイダ（[]TemplateProvider、参照式の昇順）。SharedSets に含まれるプロバイダは除く
+//   - SharedSets: 参照する共通のプロバイダセットの変数名（名前の昇順）
+type TemplateProviderSet = generate.ProviderSetData
+
+// TemplateSharedSet は複数のルート構造体で共有するプロバイダセット。
+//
+//   - Name: プロバイダセットの変数名（例: "RepositorySharedSet"）
+//   - Roots: セットのプロバイダを使うルート構造体名
+//   - Providers: セットに含めるプロバイダ（[]TemplateProvider）
+type TemplateSharedSet = generate.SharedSetData
+
+// TemplateProvider はプロバイダセットの要素。通常は qualify で参照式に変換して使う。
+//
+//   - PkgPath, PkgName: プロバイダを宣言したパッケージのパスと名前
+//   - Name: パッケージ修飾なしの名前。関数では関数名、"bind" ではインターフェース名、"struct" では構造体名、"set" ではセットの変数名
+//   - Alias: 生成コード内でパッケージを参照する名前。生成ファイルと同じパッケージのプロバイダでは空
+//   - Kind: プロバイダの種類（TemplateProviderKind）
+//   - Source: "bind" の実装の型、"field" のフィールドを持つ構造体（*TemplateTypeRef）
+//   - Field: "field" のフィールド名
+type TemplateProvider = generate.Provider
+
+// TemplateTypeRef は生成コードから参照する名前付き型。Expr で参照式（例: "*service.Config"）に変換できる。
+//
+//   - PkgPath, PkgName, Name: 型のパッケージのパスと名前、型名
+//   - Pointer: 型をポインタとして参照するかどうか
+//   - Alias: 生成コード内でパッケージを参照する名前
+type TemplateTypeRef = generate.TypeRef
+
+// TemplateProviderKind は TemplateProvider の種類
+type TemplateProviderKind = generate.ProviderKind
+
+const (
+	// TemplateKindFunc はコンストラクタ関数
+	TemplateKindFunc = generate.KindFunc
+	// TemplateKindBind はインターフェースを実装の型に束縛する（wire.Bind）
+	TemplateKindBind = generate.KindBind
+	// TemplateKindStruct は構造体のフィールドを全て注入して生成する（wire.Struct）
+	TemplateKindStruct = generate.KindStruct
+	// TemplateKindField は構造体のフィールドの値を使う（wire.FieldsOf）
+	TemplateKindField = generate.KindField
+	// TemplateKindSet は他のパッケージの公開のプロバイダセット（package_sets）
+	TemplateKindSet = generate.KindSet
+)
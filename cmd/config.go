@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configValidatePath string
+	configFilePath     string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the cire.yaml project configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate cire.yaml and report unknown keys",
+	Long: `Validate the cire.yaml project configuration.
+The config file is discovered upward from the given file (up to the directory containing go.mod)
+unless --config is specified. Unknown keys and invalid values are all reported.`,
+	Example: `  cire config validate
+  cire config validate --file ./cmd/api/cire.go
+  cire config validate --config ./cire.yaml`,
+	RunE: runConfigValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+
+	configValidateCmd.Flags().StringVarP(&configFilePath, "file", "f", ".", "File or directory from which cire.yaml is discovered")
+	configValidateCmd.Flags().StringVarP(&configValidatePath, "config", "c", "", "Config file path to validate")
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	input := app.ConfigValidateInput{
+		ConfigPath: configValidatePath,
+		FilePath:   configFilePath,
+	}
+	return app.RunConfigValidate(&input)
+}
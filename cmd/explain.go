@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var explainInput app.ExplainInput
+
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Show why and how a type is provided",
+	Long: `Explain how a type is provided to the root structs defined in a cire file.
+Prints the chosen provider with its location, every path from the root struct to the type,
+and the alternative candidates with the reason each was rejected.`,
+	Example: `  cire explain -f ./cire.go --type github.com/acme/db.DB --root OrderApp
+  cire explain -f ./cire.go --type repository.UserRepository`,
+	RunE: runExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVarP(&explainInput.FilePath, "file", "f", "", "Go file path containing root struct definitions (required)")
+	explainCmd.Flags().StringVarP(&explainInput.ConfigPath, "config", "c", "", "Config file path (default: cire.yaml found upward from the input file)")
+	explainCmd.Flags().StringVar(&explainInput.OverlayPath, "overlay", "", "Overlay file in the go command's -overlay format, replacing file contents without writing to disk (\"-\" reads it from stdin)")
+	explainCmd.Flags().StringVar(&explainInput.TypeName, "type", "", "Type to explain, e.g. github.com/acme/db.DB or db.DB (required)")
+	explainCmd.Flags().StringVar(&explainInput.Root, "root", "", "Root struct name to show paths from (default: all roots)")
+
+	explainCmd.MarkFlagRequired("file")
+	explainCmd.MarkFlagRequired("type")
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	return app.RunExplain(&explainInput)
+}
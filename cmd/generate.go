@@ -1,13 +1,29 @@
 package cmd
 
 import (
+	"os"
+	"strings"
+
 	"github.com/rmocchy/cire/internal/app"
 	"github.com/spf13/cobra"
 )
 
 var (
-	filePath string
-	genJson  bool
+	filePath   string
+	genJson    bool
+	configPath string
+	overlay    string
+	tmplPath   string
+	check      bool
+	outputPath string
+	buildTags  []string
+	patterns   []string
+	excludes   []string
+	backend    string
+	diagFormat string
+	profiles   []string
+	shareSets  bool
+	pkgSets    bool
 )
 
 var generateCmd = &cobra.Command{
@@ -16,7 +32,12 @@ var generateCmd = &cobra.Command{
 	Long: `Analyze structs defined in a file with //go:build cire tag and generate wire.go file.
 The target file must have the build tag "//go:build cire" and contain struct definitions.`,
 	Example: `  cire generate --file ./cire.go
-  cire generate -f ./cire.go --yaml`,
+  cire generate -f ./cire.go --json
+  cire generate -f ./cire.go --template ./wire.go.tmpl
+  cire generate -f ./cire.go --check
+  cire generate -f ./cire.go --check --overlay overlay.json
+  cire generate -f ./cire.go --profile dev,prod
+  cire generate -f ./cire.go --diagnostics-format sarif > cire.sarif`,
 	RunE: runGenerate,
 }
 
@@ -24,15 +45,66 @@ func init() {
 	rootCmd.AddCommand(generateCmd)
 
 	generateCmd.Flags().StringVarP(&filePath, "file", "f", "", "Go file path with //go:build cire tag containing struct definitions (required)")
-	generateCmd.Flags().BoolVarP(&genJson, "json", "j", false, "Generate YAML file in the same directory as the input file")
+	generateCmd.Flags().BoolVarP(&genJson, "json", "j", false, "Write the JSON analysis report (output.json, default dep_tree.json) next to the input file")
+
+	generateCmd.Flags().StringVarP(&configPath, "config", "c", "", "Config file path (default: cire.yaml found upward from the input file)")
+	generateCmd.Flags().StringVar(&overlay, "overlay", "", "Overlay file in the go command's -overlay format, replacing file contents without writing to disk (\"-\" reads it from stdin)")
+	generateCmd.Flags().StringVar(&tmplPath, "template", "", "User-supplied text/template file for the generated code (receives generate.WireData)")
+	generateCmd.Flags().BoolVar(&check, "check", false, "Do not write files; fail if the existing output is stale or edited by hand")
+	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output file path relative to the input file (overrides output.wire)")
+	generateCmd.Flags().StringSliceVar(&buildTags, "tags", nil, "Build tags used when loading packages (overrides build_tags)")
+	generateCmd.Flags().StringSliceVar(&patterns, "pattern", nil, "Package patterns searched for providers (overrides patterns)")
+	generateCmd.Flags().StringSliceVar(&excludes, "exclude", nil, "Package patterns excluded from provider search (overrides exclude)")
+	generateCmd.Flags().StringVar(&backend, "backend", "", "Code generation backend (overrides backend)")
+	generateCmd.Flags().StringVar(&diagFormat, "diagnostics-format", "text", "Diagnostics format: text, json or sarif")
+	// --format は --diagnostics-format の旧名。既存の呼び出しが壊れないよう同じ変数に割り当てて残す
+	generateCmd.Flags().StringVar(&diagFormat, "format", "text", "Diagnostics format: text, json or sarif")
+	generateCmd.Flags().MarkDeprecated("format", "use --diagnostics-format instead")
+	generateCmd.Flags().BoolVar(&shareSets, "shared-sets", false, "Factor providers used by several root structs into shared per-package provider sets (overrides shared_sets)")
+	generateCmd.Flags().BoolVar(&pkgSets, "package-sets", false, "Write an exported ProviderSet (output.providers, default providers_gen.go) into every module package that provides dependencies (overrides package_sets)")
+	generateCmd.Flags().StringSliceVar(&profiles, "profile", nil, "Profiles (//cire:profile) resolved separately, each written to a build-tagged file such as wire_dev.go instead of wire.go")
 
 	generateCmd.MarkFlagRequired("file")
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
 	input := app.GenerateInput{
-		FilePath: filePath,
-		GenJson:  genJson,
+		FilePath:     filePath,
+		GenJson:      genJson,
+		ConfigPath:   configPath,
+		OverlayPath:  overlay,
+		TemplatePath: tmplPath,
+		Command:      commandLine(),
+		Check:        check,
+		Output:       outputPath,
+		Backend:      backend,
+		Format:       diagFormat,
+		Profiles:     profiles,
+		SharedSets:   shareSets,
+		PackageSets:  pkgSets,
+	}
+	// 明示的に指定されたフラグのみ設定ファイルの値を上書きする
+	if cmd.Flags().Changed("tags") {
+		input.BuildTags = buildTags
+	}
+	if cmd.Flags().Changed("pattern") {
+		input.Patterns = patterns
+	}
+	if cmd.Flags().Changed("exclude") {
+		input.Exclude = excludes
 	}
 	return app.RunGenerate(&input)
 }
+
+// commandLine は生成ファイルのヘッダーに記録する再生成用のコマンドラインを返す。
+// --check は再生成には不要なため除外する
+func commandLine() string {
+	args := []string{"cire"}
+	for _, arg := range os.Args[1:] {
+		if arg == "--check" || arg == "--check=true" {
+			continue
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var graphInput app.GraphInput
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Render the provider graph as DOT or Mermaid",
+	Long: `Analyze structs defined in a cire file and render the provider graph of each root struct.
+Nodes are labelled with the constructor, its package and the returned type.
+Interface bindings and external inputs (parameters no provider supplies) are styled differently.`,
+	Example: `  cire graph -f ./cire.go --format dot | dot -Tsvg > graph.svg
+  cire graph -f ./cire.go --format mermaid --collapse-packages
+  cire graph -f ./cire.go --focus service.OrderService`,
+	RunE: runGraph,
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringVarP(&graphInput.FilePath, "file", "f", "", "Go file path containing root struct definitions (required)")
+	graphCmd.Flags().StringVarP(&graphInput.ConfigPath, "config", "c", "", "Config file path (default: cire.yaml found upward from the input file)")
+	graphCmd.Flags().StringVar(&graphInput.OverlayPath, "overlay", "", "Overlay file in the go command's -overlay format, replacing file contents without writing to disk (\"-\" reads it from stdin)")
+	graphCmd.Flags().StringVar(&graphInput.Format, "format", "dot", "Output format: dot or mermaid")
+	graphCmd.Flags().BoolVar(&graphInput.CollapseByPackage, "collapse-packages", false, "Collapse providers into one node per package")
+	graphCmd.Flags().StringVar(&graphInput.Focus, "focus", "", "Only render the subgraph under the provider of this type (e.g. service.UserService)")
+	graphCmd.Flags().StringVarP(&graphInput.OutputPath, "output", "o", "", "Output file path (default: stdout)")
+
+	graphCmd.MarkFlagRequired("file")
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	return app.RunGraph(&graphInput)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var initInput app.InitInput
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Scaffold a cire.go root file from the code base",
+	Long: `Scan the module and write a cire.go with a root struct in the given directory.
+Proposed root fields are the types that no other provider consumes, such as handlers.
+The chosen fields and the reason for each are printed.`,
+	Example: `  cire init --dir ./cmd/api
+  cire init --dir ./cmd/api --filter ./internal/handler/... --root API`,
+	RunE: runInit,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVar(&initInput.Dir, "dir", ".", "Directory to write cire.go into")
+	initCmd.Flags().StringVarP(&initInput.ConfigPath, "config", "c", "", "Config file path (default: cire.yaml found upward from the directory)")
+	initCmd.Flags().StringVar(&initInput.RootName, "root", "App", "Name of the root struct")
+	initCmd.Flags().StringSliceVar(&initInput.Filter, "filter", nil, "Only propose types from packages matching these patterns")
+	initCmd.Flags().BoolVar(&initInput.Force, "force", false, "Overwrite an existing cire.go")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	return app.RunInit(&initInput)
+}
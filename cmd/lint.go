@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintFilePaths  []string
+	lintConfigPath string
+	lintOverlay    string
+	lintPatterns   []string
+	lintExcludes   []string
+	lintFormat     string
+	lintFailOn     string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Report unused constructors and ambiguous providers",
+	Long: `Report constructor-shaped functions that are not reachable from any root struct,
+and types that have more than one candidate provider, even when they are not currently used.
+Functions marked with //cire:ignore are not reported.`,
+	Example: `  cire lint -f ./cire.go
+  cire lint -f ./cmd/api/cire.go -f ./cmd/worker/cire.go
+  cire lint -f ./cire.go --pattern ./internal/...
+  cire lint -f ./cire.go --diagnostics-format sarif > cire.sarif
+  cire lint -f ./cire.go --fail-on warning`,
+	RunE: runLint,
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringSliceVarP(&lintFilePaths, "file", "f", nil, "Go files containing root struct definitions; repeat for every root file in the module (required)")
+	lintCmd.Flags().StringVarP(&lintConfigPath, "config", "c", "", "Config file path (default: cire.yaml found upward from the first input file)")
+	lintCmd.Flags().StringVar(&lintOverlay, "overlay", "", "Overlay file in the go command's -overlay format, replacing file contents without writing to disk (\"-\" reads it from stdin)")
+	lintCmd.Flags().StringSliceVar(&lintPatterns, "pattern", nil, "Package patterns searched for providers (overrides patterns)")
+	lintCmd.Flags().StringSliceVar(&lintExcludes, "exclude", nil, "Package patterns excluded from provider search (overrides exclude)")
+
+	lintCmd.Flags().StringVar(&lintFormat, "diagnostics-format", "text", "Diagnostics format: text, json or sarif")
+	// --format は --diagnostics-format の旧名。既存の呼び出しが壊れないよう同じ変数に割り当てて残す
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "Diagnostics format: text, json or sarif")
+	lintCmd.Flags().MarkDeprecated("format", "use --diagnostics-format instead")
+
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", "error", "Lowest diagnostic severity that makes lint exit non-zero: error or warning")
+
+	lintCmd.MarkFlagRequired("file")
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	input := app.LintInput{
+		FilePaths:   lintFilePaths,
+		ConfigPath:  lintConfigPath,
+		OverlayPath: lintOverlay,
+		Format:      lintFormat,
+		FailOn:      lintFailOn,
+	}
+	// 明示的に指定されたフラグのみ設定ファイルの値を上書きする
+	if cmd.Flags().Changed("pattern") {
+		input.Patterns = lintPatterns
+	}
+	if cmd.Flags().Changed("exclude") {
+		input.Exclude = lintExcludes
+	}
+	return app.RunLint(&input)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/rmocchy/cire/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server for cire files over stdio",
+	Long: `Run a Language Server Protocol server on stdin/stdout.
+Open cire files (cire.go by default; set initializationOptions.files to change it) are analyzed
+including unsaved changes, and diagnostics are published on every edit.
+The server also provides code lenses to regenerate wire.go and show the dependency graph,
+hover on root fields with the provider chain, and go-to-definition to the chosen constructor.`,
+	Example: `  cire lsp`,
+	Args:    cobra.NoArgs,
+	RunE:    runLSP,
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+func runLSP(cmd *cobra.Command, args []string) error {
+	return lsp.Serve(os.Stdin, os.Stdout)
+}
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/rmocchy/cire/internal/app"
 	"github.com/spf13/cobra"
 )
 
@@ -12,12 +14,19 @@ var rootCmd = &cobra.Command{
 	Short: "Cire - Convenient Wire generator",
 	Long: `Cire is a CLI tool that generates wire.go from struct dependencies in Go projects.
 It analyzes struct dependencies and generates Wire injection code automatically.`,
+	// エラーは Execute が一度だけ出力して終了コードに変換する。使い方を出すと診断の出力が埋もれるため出さない
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		var exitErr *app.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
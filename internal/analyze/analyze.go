@@ -2,26 +2,53 @@ package analyze
 
 import (
 	"errors"
+	"fmt"
+	"go/token"
 	"go/types"
+	"strings"
+
+	"github.com/rmocchy/cire/internal/diag"
 )
 
 type Analyze interface {
+	// ExecuteFromStruct はルート構造体のフィールドごとに依存関係を解析する。
+	// 問題が見つかっても解析を続け、解析できた範囲のツリーと全ての問題を diag.List として返す
 	ExecuteFromStruct(structure *types.Named) ([]*FnDITreeNode, error)
 }
 
+// NewAnalyze はアナライザを作成する。resolvers はプロバイダの候補を探すリゾルバで、
+// 省略した場合は DefaultResolvers を使う
 func NewAnalyze(
 	functionCache FunctionCache,
 	analysisCache AnalysisCache,
+	resolvers ...ProviderResolver,
 ) Analyze {
+	if len(resolvers) == 0 {
+		resolvers = DefaultResolvers(functionCache)
+	}
 	return &analyze{
 		functionCache: functionCache,
 		analysisCache: analysisCache,
+		resolvers:     resolvers,
 	}
 }
 
 type analyze struct {
 	functionCache FunctionCache
 	analysisCache AnalysisCache
+	resolvers     []ProviderResolver
+	// diags は実行中のルート構造体で見つかった問題
+	diags diag.List
+}
+
+// requirement は型を必要としている箇所
+type requirement struct {
+	pos token.Pos
+	// by は型を必要としているもの（例: "field App.Handler", "parameter repo of service.NewService"）
+	by string
+	// typ は必要とされている型（ポインタかどうかを含む）。wire.Bind の実装や wire.FieldsOf の構造体のように
+	// 値とポインタのどちらでもよい場合は nil
+	typ types.Type
 }
 
 func (a *analyze) ExecuteFromStruct(structure *types.Named) ([]*FnDITreeNode, error) {
@@ -29,63 +56,233 @@ func (a *analyze) ExecuteFromStruct(structure *types.Named) ([]*FnDITreeNode, er
 	if !ok {
 		return nil, errors.New("not a struct type")
 	}
+	a.diags = make(diag.List, 0)
 	var allNodes []*FnDITreeNode
 	for i := 0; i < st.NumFields(); i++ {
-		fieldType, ok := Deref(st.Field(i).Type()).(*types.Named)
+		field := st.Field(i)
+		req := requirement{pos: field.Pos(), by: fmt.Sprintf("field %s.%s", structure.Obj().Name(), field.Name()), typ: field.Type()}
+		fieldType, ok := Deref(field.Type()).(*types.Named)
 		if !ok {
+			a.diags = append(a.diags, diag.Errorf(diag.UnsupportedField, a.functionCache.Position(req.pos),
+				"%s has type %s, which cannot be supplied by a provider", req.by, field.Type().String()))
 			continue
 		}
-		nodes, err := a.recursiveAnalyze(fieldType)
-		if err != nil {
-			return nil, err
-		}
-		allNodes = append(allNodes, nodes...)
+		allNodes = append(allNodes, a.recursiveAnalyze(fieldType, req, nil)...)
+	}
+	if len(a.diags) > 0 {
+		return allNodes, a.uniqueDiagnostics()
 	}
 	return allNodes, nil
 }
 
-func (a *analyze) recursiveAnalyze(retrunType *types.Named) ([]*FnDITreeNode, error) {
-	cached, ok := a.analysisCache.Get(retrunType)
-	if ok {
-		return cached, nil
+// uniqueDiagnostics は複数のフィールドや依存から同じ箇所で見つかった問題を一度だけ含む診断を返す
+func (a *analyze) uniqueDiagnostics() diag.List {
+	diags := make(diag.List, 0, len(a.diags))
+	reported := make(map[string]bool)
+	for _, d := range a.diags {
+		if !reported[d.Error()] {
+			reported[d.Error()] = true
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+// recursiveAnalyze は retrunType を返すプロバイダとその依存を解析する。stack は解析中の型（循環の検出に使う）。
+// 問題が見つからなかった型だけを解析結果のキャッシュに入れ、問題のある型は必要とされるたびに解析し直して、
+// 別のルート構造体やフィールドからもその箇所（required by）を添えて問題を報告する
+func (a *analyze) recursiveAnalyze(retrunType *types.Named, req requirement, stack []*types.Named) []*FnDITreeNode {
+	for i, t := range stack {
+		if types.Identical(t, retrunType) {
+			a.diags = append(a.diags, a.cycleDiagnostic(append(stack[i:], retrunType), req))
+			return nil
+		}
 	}
-	fns := a.functionCache.BulkGet(retrunType)
-	if len(fns) == 0 {
-		return nil, errors.New("no function found with the specified return type: " + retrunType.String())
+	// 値とポインタのどちらでもよい場合は候補の絞り込みが異なるため、キャッシュを使わない
+	if req.typ != nil {
+		if cached, ok := a.analysisCache.Get(req.typ); ok {
+			return cached
+		}
+	}
+	reported := len(a.diags)
+	providers, mismatched := matchPointer(a.resolve(retrunType), req.typ)
+	switch {
+	case len(providers) == 0:
+		a.diags = append(a.diags, a.missingDiagnostic(retrunType, req, mismatched))
+		a.diags = append(a.diags, a.invalidShapeDiagnostics(retrunType, req)...)
+		return nil
+	case len(providers) > 1:
+		a.diags = append(a.diags, a.ambiguousDiagnostic(retrunType, req, providers))
 	}
 
-	treeNodes := make([]*FnDITreeNode, 0, len(fns))
-	for _, fn := range fns {
+	stack = append(stack[:len(stack):len(stack)], retrunType)
+	treeNodes := make([]*FnDITreeNode, 0, len(providers))
+	for _, p := range providers {
 		childs := make([]*FnDITreeNode, 0)
-		params := fn.Signature().Params()
-		for i := 0; i < params.Len(); i++ {
-			paramType := Deref(params.At(i).Type())
-			named, ok := paramType.(*types.Named)
+		deps := p.dependencies()
+		paramTypes := make([]string, 0, len(deps))
+		for _, dep := range deps {
+			paramTypes = append(paramTypes, dep.Type().String())
+			named, ok := Deref(dep.Type()).(*types.Named)
 			if !ok {
 				continue
 			}
-			dependFns, err := a.recursiveAnalyze(named)
-			if err != nil {
-				return nil, err
+			depReq := requirement{pos: dep.Pos(), by: p.requiredBy(dep)}
+			if p.Kind == ProviderFunc || p.Kind == ProviderStruct {
+				depReq.typ = dep.Type()
 			}
-			childs = append(childs, dependFns...)
+			childs = append(childs, a.recursiveAnalyze(named, depReq, stack)...)
 		}
+		treeNodes = append(treeNodes, newTreeNode(p, childs, paramTypes))
+	}
+
+	// 循環の途中の型は子を持たない不完全なノードになるため、問題があった型はキャッシュしない
+	if len(a.diags) == reported && req.typ != nil {
+		a.analysisCache.Set(req.typ, treeNodes)
+	}
+	return treeNodes
+}
 
-		rets := fn.Signature().Results()
-		returnTypes := make([]string, 0, rets.Len())
-		for i := 0; i < rets.Len(); i++ {
-			returnTypes = append(returnTypes, rets.At(i).Type().String())
+// resolve は全てのリゾルバの候補のうち、Rank が最も小さい候補を返す
+func (a *analyze) resolve(t *types.Named) []*ResolvedProvider {
+	req := &ResolveRequest{Type: t, Pkgs: a.functionCache.Packages()}
+	var best []*ResolvedProvider
+	for _, r := range a.resolvers {
+		for _, p := range r.Resolve(req) {
+			switch {
+			case len(best) == 0 || p.Rank < best[0].Rank:
+				best = []*ResolvedProvider{p}
+			case p.Rank == best[0].Rank:
+				best = append(best, p)
+			}
 		}
+	}
+	return best
+}
 
-		node := FnDITreeNode{
-			Name:        fn.Name(),
-			PkgPath:     fn.Pkg().Path(),
-			Childs:      childs,
-			ReturnTypes: returnTypes,
+// matchPointer は関数の候補を、返り値がポインタかどうかが必要とされている型 typ と一致するものと一致しないものに分ける。
+// wire は T と *T を別の型として扱うため、一致しない関数は使えない。typ が nil の場合は全ての候補が一致する
+func matchPointer(providers []*ResolvedProvider, typ types.Type) (matched, mismatched []*ResolvedProvider) {
+	for _, p := range providers {
+		if typ != nil && p.Kind == ProviderFunc && isPointer(p.Func.Signature().Results().At(0).Type()) != isPointer(typ) {
+			mismatched = append(mismatched, p)
+			continue
 		}
+		matched = append(matched, p)
+	}
+	return matched, mismatched
+}
 
-		treeNodes = append(treeNodes, &node)
+// newTreeNode は候補と解析済みの依存からノードを作成する
+func newTreeNode(p *ResolvedProvider, childs []*FnDITreeNode, paramTypes []string) *FnDITreeNode {
+	node := &FnDITreeNode{
+		Name:        p.Type.Obj().Name(),
+		PkgPath:     p.Type.Obj().Pkg().Path(),
+		PkgName:     p.Type.Obj().Pkg().Name(),
+		Childs:      childs,
+		ReturnTypes: p.results(),
+		Params:      paramTypes,
+		Kind:        p.Kind,
 	}
+	switch p.Kind {
+	case ProviderFunc:
+		node.Name, node.PkgPath, node.PkgName = p.Func.Name(), p.Func.Pkg().Path(), p.Func.Pkg().Name()
+		rets := p.Func.Signature().Results()
+		node.ReturnsInterface = rets.Len() > 0 && types.IsInterface(rets.At(0).Type())
+	case ProviderBind, ProviderField:
+		node.Source = &TypeRef{
+			PkgPath: p.Source.Obj().Pkg().Path(),
+			PkgName: p.Source.Obj().Pkg().Name(),
+			Name:    p.Source.Obj().Name(),
+			// 構造体のプロバイダは値とポインタの両方を供給するため、ポインタとして参照する
+			Pointer: len(childs) == 0 || childs[0].Kind == ProviderStruct || strings.HasPrefix(childs[0].ReturnTypes[0], "*"),
+		}
+		if p.Kind == ProviderBind {
+			node.ReturnsInterface = true
+		} else {
+			node.Name, node.PkgPath, node.PkgName = p.Source.Obj().Name()+"."+p.Field, node.Source.PkgPath, node.Source.PkgName
+			node.Field = p.Field
+		}
+	}
+	return node
+}
+
+// missingDiagnostic はプロバイダが見つからない問題を作る。mismatched は返り値がポインタかどうかだけが異なる関数
+func (a *analyze) missingDiagnostic(t *types.Named, req requirement, mismatched []*ResolvedProvider) *diag.Diagnostic {
+	required := t.String()
+	if req.typ != nil {
+		required = req.typ.String()
+	}
+	d := diag.Errorf(diag.MissingProvider, a.functionCache.Position(req.pos),
+		"no provider found for %s (required by %s)", required, req.by)
+	// 型を返すが採用されなかった関数があれば理由を添える。
+	// 非公開の関数は NearMisses が unexported として、ポインタかどうかだけが異なる関数は pointer-mismatch として示す
+	for _, c := range a.functionCache.Candidates(t) {
+		if c.Selected() || c.Reason == RejectVisibility {
+			continue
+		}
+		d.Related = append(d.Related, diag.Related{
+			Position: c.Position,
+			Message:  fmt.Sprintf("%s.%s was rejected (%s: %s)", c.Func.Pkg().Name(), c.Func.Name(), c.Reason, c.Detail),
+		})
+	}
+	misses := make([]*NearMiss, 0, len(mismatched))
+	for _, p := range mismatched {
+		misses = append(misses, &NearMiss{
+			Name:     qualifiedName(p.Func),
+			Position: a.functionCache.Position(p.Pos),
+			Reason:   NearMissPointerMismatch,
+			Detail:   fmt.Sprintf("returns %s, but %s is required", p.Func.Signature().Results().At(0).Type().String(), required),
+		})
+	}
+	for _, m := range append(misses, a.functionCache.NearMisses(t)...) {
+		d.Related = append(d.Related, diag.Related{
+			Position: m.Position,
+			Message:  fmt.Sprintf("did you mean %s? (%s: %s)", m.Name, m.Reason, m.Detail),
+		})
+	}
+	return d
+}
+
+// invalidShapeDiagnostics は型を返すがシグネチャがプロバイダの形でない関数を、関数の位置で報告する
+func (a *analyze) invalidShapeDiagnostics(t *types.Named, req requirement) diag.List {
+	diags := make(diag.List, 0)
+	for _, c := range a.functionCache.Candidates(t) {
+		if c.Reason != RejectSignature {
+			continue
+		}
+		d := diag.Errorf(diag.InvalidProvider, c.Position,
+			"%s.%s returns %s but cannot be used as a provider: %s", c.Func.Pkg().Name(), c.Func.Name(), t.String(), c.Detail)
+		d.Related = append(d.Related, diag.Related{
+			Position: a.functionCache.Position(req.pos),
+			Message:  fmt.Sprintf("%s is required by %s", t.String(), req.by),
+		})
+		diags = append(diags, d)
+	}
+	return diags
+}
 
-	return treeNodes, nil
+func (a *analyze) ambiguousDiagnostic(t *types.Named, req requirement, providers []*ResolvedProvider) *diag.Diagnostic {
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.shortName())
+	}
+	d := diag.Errorf(diag.AmbiguousProvider, a.functionCache.Position(req.pos),
+		"multiple providers found for %s (required by %s): %s", t.String(), req.by, strings.Join(names, ", "))
+	for _, p := range providers {
+		d.Related = append(d.Related, diag.Related{
+			Position: a.functionCache.Position(p.Pos),
+			Message:  "candidate " + p.String(),
+		})
+	}
+	return d
+}
+
+func (a *analyze) cycleDiagnostic(cycle []*types.Named, req requirement) *diag.Diagnostic {
+	names := make([]string, 0, len(cycle))
+	for _, t := range cycle {
+		names = append(names, t.String())
+	}
+	return diag.Errorf(diag.DependencyCycle, a.functionCache.Position(req.pos),
+		"dependency cycle: %s (required by %s)", strings.Join(names, " -> "), req.by)
 }
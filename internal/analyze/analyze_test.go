@@ -1,14 +1,18 @@
 package analyze
 
 import (
+	"errors"
 	"go/types"
+	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/rmocchy/cire/internal/diag"
 	"golang.org/x/tools/go/packages"
 )
 
-// loadTestPackages はテスト用のパッケージをロードする
-func loadTestPackages(t *testing.T, workDir string) []*packages.Package {
+// loadTestPackages はテスト用のパッケージをロードする。patterns を省略した場合は ./... をロードする
+func loadTestPackages(t *testing.T, workDir string, patterns ...string) []*packages.Package {
 	t.Helper()
 
 	cfg := &packages.Config{
@@ -18,7 +22,10 @@ func loadTestPackages(t *testing.T, workDir string) []*packages.Package {
 		Dir: workDir,
 	}
 
-	pkgs, err := packages.Load(cfg, "./...")
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
 		t.Fatalf("Failed to load packages: %v", err)
 	}
@@ -37,7 +44,7 @@ func setupTestAnalyzer(t *testing.T, workDir string) (Analyze, []*packages.Packa
 	t.Helper()
 
 	pkgs := loadTestPackages(t, workDir)
-	functionCache := NewFunctionCache(pkgs)
+	functionCache := NewFunctionCache(pkgs, "")
 	analysisCache := NewAnalysisCache()
 	analyzer := NewAnalyze(functionCache, analysisCache)
 
@@ -91,7 +98,7 @@ func TestNewAnalyze(t *testing.T) {
 func TestFunctionCache_BulkGet(t *testing.T) {
 	workDir := "../../sample/basic"
 	pkgs := loadTestPackages(t, workDir)
-	functionCache := NewFunctionCache(pkgs)
+	functionCache := NewFunctionCache(pkgs, "")
 
 	tests := []struct {
 		name        string
@@ -259,3 +266,219 @@ func TestFnDITreeNode(t *testing.T) {
 		t.Errorf("Expected ChildFunc, got %s", parent.Childs[0].Name)
 	}
 }
+
+func TestFunctionCache_Candidates(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/candidates")
+	pkgPath := "github.com/rmocchy/cire/internal/analyze/testdata/candidates"
+	functionCache := NewFunctionCache(pkgs, "example.com/app")
+
+	tests := []struct {
+		name        string
+		typeName    string
+		wantReasons map[string]RejectReason
+		wantBulk    []string
+	}{
+		{
+			name:     "アノテーション・公開範囲・シグネチャで除外される",
+			typeName: "DB",
+			wantReasons: map[string]RejectReason{
+				"NewDB":       "",
+				"NewMemoryDB": RejectAnnotation,
+				"newLocalDB":  RejectVisibility,
+				"OpenDB":      RejectSignature,
+			},
+			wantBulk: []string{"NewDB"},
+		},
+		{
+			name:     "採用できる候補が複数ある",
+			typeName: "Cache",
+			wantReasons: map[string]RejectReason{
+				"NewCache":      RejectAmbiguous,
+				"NewRedisCache": RejectAmbiguous,
+			},
+			wantBulk: []string{"NewCache", "NewRedisCache"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			returnType := findNamedType(t, pkgs, pkgPath, tt.typeName)
+
+			candidates := functionCache.Candidates(returnType)
+			if len(candidates) != len(tt.wantReasons) {
+				t.Errorf("len(Candidates()) = %d, want %d", len(candidates), len(tt.wantReasons))
+			}
+			for _, c := range candidates {
+				want, ok := tt.wantReasons[c.Func.Name()]
+				if !ok {
+					t.Errorf("想定外の候補 %s", c.Func.Name())
+					continue
+				}
+				if c.Reason != want {
+					t.Errorf("%s の Reason = %q, want %q (%s)", c.Func.Name(), c.Reason, want, c.Detail)
+				}
+				if c.Position.Line == 0 {
+					t.Errorf("%s の位置が設定されていません", c.Func.Name())
+				}
+			}
+
+			fns := functionCache.BulkGet(returnType)
+			got := make([]string, 0, len(fns))
+			for _, fn := range fns {
+				got = append(got, fn.Name())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantBulk, ",") {
+				t.Errorf("BulkGet() = %v, want %v", got, tt.wantBulk)
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/candidates")
+	pkgPath := "github.com/rmocchy/cire/internal/analyze/testdata/candidates"
+	functionCache := NewFunctionCache(pkgs, "example.com/app")
+	trees := RootTrees{
+		{Name: "App", Trees: []*FnDITreeNode{{Name: "NewDB", PkgPath: pkgPath, PkgName: "candidates"}}},
+	}
+
+	got := make([]string, 0)
+	for _, d := range Lint(functionCache, trees, regexp.MustCompile("^New")) {
+		got = append(got, string(d.Code)+": "+d.Message)
+		for _, r := range d.Related {
+			got = append(got, "  "+r.Message)
+		}
+	}
+	want := []string{
+		"CIRE006: candidates.NewCache is not reachable from any root",
+		"CIRE002: " + pkgPath + ".Cache has multiple providers",
+		"  candidate candidates.NewCache",
+		"  candidate candidates.NewRedisCache",
+		"CIRE006: candidates.NewLogger is not reachable from any root",
+		"CIRE006: candidates.NewRedisCache is not reachable from any root",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLint_ConstructorPattern(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/candidates")
+	pkgPath := "github.com/rmocchy/cire/internal/analyze/testdata/candidates"
+	functionCache := NewFunctionCache(pkgs, "example.com/app")
+
+	// 補助関数もコンストラクタとして扱う設定では、それらも報告する
+	got := make([]string, 0)
+	for _, d := range Lint(functionCache, nil, regexp.MustCompile("^(Default|Load)")) {
+		got = append(got, string(d.Code)+": "+d.Message)
+	}
+	want := []string{
+		"CIRE006: candidates.DefaultSettings is not reachable from any root",
+		"CIRE002: " + pkgPath + ".Settings has multiple providers",
+		"CIRE006: candidates.LoadSettings is not reachable from any root",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyze_ExecuteFromStruct_CollectsDiagnostics(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/diagnostics")
+	pkgPath := "github.com/rmocchy/cire/internal/analyze/testdata/diagnostics"
+	analyzer := NewAnalyze(NewFunctionCache(pkgs, ""), NewAnalysisCache())
+
+	nodes, err := analyzer.ExecuteFromStruct(findNamedType(t, pkgs, pkgPath, "App"))
+	var diags diag.List
+	if !errors.As(err, &diags) {
+		t.Fatalf("ExecuteFromStruct() error = %v, want diag.List", err)
+	}
+	if !collectNodeNames(nodes)["NewService"] {
+		t.Errorf("解析できたプロバイダ NewService がツリーに含まれていません")
+	}
+
+	got := make([]string, 0, len(diags))
+	for _, d := range diags {
+		if d.Position.Line == 0 {
+			t.Errorf("%s の位置が設定されていません", d.Code)
+		}
+		got = append(got, string(d.Code)+": "+d.Message)
+	}
+	want := []string{
+		"CIRE001: no provider found for *" + pkgPath + ".Config (required by parameter cfg of diagnostics.NewService)",
+		"CIRE001: no provider found for *" + pkgPath + ".Queue (required by parameter queue of diagnostics.NewService)",
+		"CIRE007: diagnostics.OpenQueue returns " + pkgPath + ".Queue but cannot be used as a provider: Queue is not the first result",
+		"CIRE005: field App.Count has type int, which cannot be supplied by a provider",
+		"CIRE001: no provider found for *" + pkgPath + ".Queue (required by field App.Queue)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyze_ExecuteFromStruct_ReportsSharedProblemsForEachRoot(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/diagnostics")
+	pkgPath := "github.com/rmocchy/cire/internal/analyze/testdata/diagnostics"
+	// 解析結果のキャッシュを共有しても、問題のある型を必要とする全てのルート構造体で問題を報告する
+	analyzer := NewAnalyze(NewFunctionCache(pkgs, ""), NewAnalysisCache())
+
+	messages := func(root string) []string {
+		t.Helper()
+		_, err := analyzer.ExecuteFromStruct(findNamedType(t, pkgs, pkgPath, root))
+		var diags diag.List
+		if !errors.As(err, &diags) {
+			t.Fatalf("ExecuteFromStruct(%s) error = %v, want diag.List", root, err)
+		}
+		got := make([]string, 0, len(diags))
+		for _, d := range diags {
+			if d.Code != diag.InvalidProvider {
+				got = append(got, string(d.Code)+": "+d.Message)
+			}
+		}
+		return got
+	}
+
+	messages("App")
+	want := []string{
+		"CIRE001: no provider found for *" + pkgPath + ".Config (required by parameter cfg of diagnostics.NewService)",
+		"CIRE001: no provider found for *" + pkgPath + ".Queue (required by parameter queue of diagnostics.NewService)",
+		"CIRE003: dependency cycle: " + pkgPath + ".Loop -> " + pkgPath + ".Ring -> " + pkgPath + ".Loop (required by parameter loop of diagnostics.NewRing)",
+	}
+	if got := messages("Admin"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Admin diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// 循環の途中の Ring をキャッシュしていないため、Ring から始まる循環も報告する
+	want = []string{
+		"CIRE003: dependency cycle: " + pkgPath + ".Ring -> " + pkgPath + ".Loop -> " + pkgPath + ".Ring (required by parameter ring of diagnostics.NewLoop)",
+	}
+	if got := messages("Ops"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Ops diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyze_ExecuteFromStruct_MatchesPointer(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/pointer")
+	pkgPath := "github.com/rmocchy/cire/internal/analyze/testdata/pointer"
+	analyzer := NewAnalyze(NewFunctionCache(pkgs, ""), NewAnalysisCache())
+
+	// wire は T と *T を区別するため、Clock を返す NewClock は Clock には使えるが *Clock には使えない。
+	// Clock の解析結果がキャッシュされた後でも *Clock は別に解析する
+	nodes, err := analyzer.ExecuteFromStruct(findNamedType(t, pkgs, pkgPath, "App"))
+	var diags diag.List
+	if !errors.As(err, &diags) {
+		t.Fatalf("ExecuteFromStruct() error = %v, want diag.List", err)
+	}
+	names := collectNodeNames(nodes)
+	if !names["NewClock"] || !names["NewStore"] {
+		t.Errorf("nodes = %v, want NewClock and NewStore", names)
+	}
+	got := make([]string, 0, len(diags))
+	for _, d := range diags {
+		got = append(got, string(d.Code)+": "+d.Message)
+	}
+	want := []string{
+		"CIRE001: no provider found for *" + pkgPath + ".Clock (required by field App.Now)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -2,9 +2,10 @@ package analyze
 
 import "go/types"
 
+// AnalysisCache は必要とされた型（T と *T は区別する）ごとの解析結果を保持する
 type AnalysisCache interface {
-	Get(namedType *types.Named) ([]*FnDITreeNode, bool)
-	Set(namedType *types.Named, functions []*FnDITreeNode)
+	Get(t types.Type) ([]*FnDITreeNode, bool)
+	Set(t types.Type, functions []*FnDITreeNode)
 }
 
 type analysisCache struct {
@@ -17,20 +18,22 @@ func NewAnalysisCache() AnalysisCache {
 	}
 }
 
-func (ac *analysisCache) Get(namedType *types.Named) ([]*FnDITreeNode, bool) {
-	key := getIdenticalTypeName(namedType)
+func (ac *analysisCache) Get(t types.Type) ([]*FnDITreeNode, bool) {
+	key := getIdenticalTypeName(t)
 	functions, found := ac.cache[key]
 	return functions, found
 }
 
-func (ac *analysisCache) Set(namedType *types.Named, functions []*FnDITreeNode) {
-	key := getIdenticalTypeName(namedType)
+func (ac *analysisCache) Set(t types.Type, functions []*FnDITreeNode) {
+	key := getIdenticalTypeName(t)
 	ac.cache[key] = functions
 }
 
-// pkgPath + defName
+// pkgPath + defName（ポインタの場合は "*" を前置する）
 func getIdenticalTypeName(t types.Type) string {
 	switch tt := t.(type) {
+	case *types.Pointer:
+		return "*" + getIdenticalTypeName(tt.Elem())
 	case *types.Named:
 		obj := tt.Obj()
 		return obj.Pkg().Path() + "." + obj.Name()
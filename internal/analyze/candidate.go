@@ -0,0 +1,156 @@
+package analyze
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// IgnoreDirective はプロバイダの候補から除外する関数に付けるアノテーション
+const IgnoreDirective = "//cire:ignore"
+
+// RejectReason はプロバイダの候補が採用されなかった理由
+type RejectReason string
+
+const (
+	// RejectAnnotation は IgnoreDirective で除外された候補
+	RejectAnnotation RejectReason = "annotation"
+	// RejectProfile は ProfileDirective で別のプロファイルに限定された候補
+	RejectProfile RejectReason = "profile"
+	// RejectVisibility は生成ファイルのパッケージから参照できない（非公開の）候補
+	RejectVisibility RejectReason = "visibility"
+	// RejectSignature は wire のプロバイダとして使えない関数シグネチャの候補
+	RejectSignature RejectReason = "signature"
+	// RejectAmbiguous は同じ型を返す候補が他にもあり、一つに決められない候補
+	RejectAmbiguous RejectReason = "ambiguity"
+)
+
+// Candidate は型を返す関数（プロバイダの候補）とその判定結果
+type Candidate struct {
+	Func *types.Func
+	// Type は関数が供給する型
+	Type     *types.Named
+	Position token.Position
+	// Reason は採用されなかった理由。採用された候補では空
+	Reason RejectReason
+	// Detail は Reason の説明
+	Detail string
+}
+
+// Selected は候補が採用されたかどうかを返す
+func (c *Candidate) Selected() bool {
+	return c.Reason == ""
+}
+
+// ignoredFuncs は IgnoreDirective または OverrideDirective が付いた関数を「パッケージパス.関数名」をキーに、
+// 付いているアノテーションとともに返す。差し替える関数はテスト用のインジェクタだけで使う
+func ignoredFuncs(pkgPath string, files []*ast.File) map[string]string {
+	ignored := make(map[string]string)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv != nil || fd.Doc == nil {
+				continue
+			}
+			for _, directive := range []string{IgnoreDirective, OverrideDirective} {
+				if hasDirective(fd.Doc, directive) {
+					ignored[pkgPath+"."+fd.Name.Name] = directive
+					break
+				}
+			}
+		}
+	}
+	return ignored
+}
+
+// hasDirective はコメントにアノテーションが含まれるかを返す。アノテーションの後には説明を続けられる
+func hasDirective(doc *ast.CommentGroup, directive string) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if c.Text == directive || strings.HasPrefix(c.Text, directive+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// returnsType は関数のいずれかの返り値が returnType（またはそのポインタ）かを返す
+func returnsType(fn *types.Func, returnType *types.Named) bool {
+	rets := fn.Signature().Results()
+	for i := 0; i < rets.Len(); i++ {
+		if types.Identical(Deref(rets.At(i).Type()), returnType) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSignature は関数が wire のプロバイダの形
+// （T, (T, error), (T, func()), (T, func(), error) のいずれか）かを検査する
+func checkSignature(fn *types.Func, returnType *types.Named) error {
+	sig := fn.Signature()
+	if sig.TypeParams().Len() > 0 {
+		return fmt.Errorf("generic functions cannot be providers")
+	}
+	rets := sig.Results()
+	if !types.Identical(Deref(rets.At(0).Type()), returnType) {
+		return fmt.Errorf("%s is not the first result", returnType.Obj().Name())
+	}
+	switch rets.Len() {
+	case 1:
+		return nil
+	case 2:
+		if isError(rets.At(1).Type()) || isCleanup(rets.At(1).Type()) {
+			return nil
+		}
+	case 3:
+		if isCleanup(rets.At(1).Type()) && isError(rets.At(2).Type()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("results must be (T), (T, error), (T, func()) or (T, func(), error)")
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+func isCleanup(t types.Type) bool {
+	sig, ok := t.Underlying().(*types.Signature)
+	return ok && sig.Params().Len() == 0 && sig.Results().Len() == 0
+}
+
+// providedType は関数の最初の返り値の名前付き型を返す。名前付き型でない場合や error の場合は nil
+func providedType(fn *types.Func) *types.Named {
+	rets := fn.Signature().Results()
+	if rets.Len() == 0 || isError(rets.At(0).Type()) {
+		return nil
+	}
+	named, _ := Deref(rets.At(0).Type()).(*types.Named)
+	return named
+}
+
+// injectableParams は関数の引数が全て名前付き型（またはそのポインタ）かを返す
+func injectableParams(fn *types.Func) bool {
+	params := fn.Signature().Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := Deref(params.At(i).Type()).(*types.Named); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ResultFlags はプロバイダの2つ目以降の返り値にクリーンアップ関数と error が含まれるかを返す
+func ResultFlags(fn *types.Func) (hasCleanup, returnsError bool) {
+	rets := fn.Signature().Results()
+	for i := 1; i < rets.Len(); i++ {
+		hasCleanup = hasCleanup || isCleanup(rets.At(i).Type())
+		returnsError = returnsError || isError(rets.At(i).Type())
+	}
+	return hasCleanup, returnsError
+}
@@ -1,25 +1,67 @@
 package analyze
 
 import (
+	"go/token"
 	"go/types"
+	"maps"
+	"slices"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
 
 type FunctionCache interface {
+	// BulkGet は returnType のプロバイダとして採用できる関数を返す
 	BulkGet(returnType *types.Named) []*types.Func
+	// Candidates は returnType を返す全ての関数を、採用されなかった理由とともに返す
+	Candidates(returnType *types.Named) []*Candidate
+	// Constructors はプロバイダとして使える形の公開関数を全て返す（IgnoreDirective が付いた関数や、別のプロファイルに限定された関数は除く）。
+	// 引数が全て cire の解決できる名前付き型の関数だけを対象にする
+	Constructors() []*Candidate
+	// NearMisses は returnType のプロバイダが見つからない場合に、使おうとしていた可能性のある関数や型を返す
+	NearMisses(returnType *types.Named) []*NearMiss
+	// Packages は関数をキャッシュしたパッケージを返す
+	Packages() []*packages.Package
+	// Position はキャッシュした関数と同じ FileSet で位置を解決する
+	Position(pos token.Pos) token.Position
 }
 
 type functionCache struct {
-	fns map[string]*types.Func
+	// fns はパッケージパスと関数名の順にソートされた関数の一覧
+	fns []*types.Func
+	// ignored は IgnoreDirective または OverrideDirective が付いた関数（キーは「パッケージパス.関数名」、値はアノテーション）
+	ignored map[string]string
+	// profiles は ProfileDirective が付いた関数のプロファイル名（キーは「パッケージパス.関数名」）
+	profiles map[string][]string
+	// profile は解析するプロファイル。空の場合はプロファイルに限定された関数を使わない
+	profile string
+	// targetPkgPath は生成ファイルのパッケージのパス。空の場合は公開範囲を検査しない
+	targetPkgPath string
+	fset          *token.FileSet
+	// pkgs は関数をキャッシュしたパッケージ（NearMisses で依存パッケージを辿るために使う）
+	pkgs []*packages.Package
 }
 
-func NewFunctionCache(pkgs []*packages.Package) FunctionCache {
+// NewFunctionCache はパッケージレベルの関数をキャッシュする。
+// targetPkgPath には生成ファイルのパッケージのパスを指定し、他のパッケージの非公開関数を候補から除外する
+func NewFunctionCache(pkgs []*packages.Package, targetPkgPath string) FunctionCache {
+	return NewProfileFunctionCache(pkgs, targetPkgPath, "")
+}
+
+// NewProfileFunctionCache は NewFunctionCache と同じく関数をキャッシュする。
+// ProfileDirective で限定された関数は、profile がそのプロファイルのいずれかに一致する場合だけ候補にする
+func NewProfileFunctionCache(pkgs []*packages.Package, targetPkgPath, profile string) FunctionCache {
 	// ここでは単純に全ての関数をキャッシュする例を示す
 	// 実際には必要な関数のみをキャッシュするように最適化することも可能
 	fns := make(map[string]*types.Func)
+	ignored := make(map[string]string)
+	profiles := make(map[string][]string)
+	var fset *token.FileSet
 
 	for _, pkg := range pkgs {
+		if fset == nil {
+			fset = pkg.Fset
+		}
 		scope := pkg.Types.Scope()
 		for _, name := range scope.Names() {
 			obj := scope.Lookup(name)
@@ -27,22 +69,106 @@ func NewFunctionCache(pkgs []*packages.Package) FunctionCache {
 				fns[pkg.PkgPath+"."+fn.Name()] = fn
 			}
 		}
+		maps.Copy(ignored, ignoredFuncs(pkg.PkgPath, pkg.Syntax))
+		maps.Copy(profiles, profileFuncs(pkg.PkgPath, pkg.Syntax))
+	}
+
+	// 出力が実行ごとに変わらないよう、キーの順に並べておく
+	keys := slices.Sorted(maps.Keys(fns))
+	sorted := make([]*types.Func, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, fns[key])
 	}
 
-	return &functionCache{fns: fns}
+	return &functionCache{fns: sorted, ignored: ignored, profiles: profiles, profile: profile, targetPkgPath: targetPkgPath, fset: fset, pkgs: pkgs}
 }
 
 func (fc *functionCache) BulkGet(returnType *types.Named) []*types.Func {
 	// キャッシュから指定された返り値の型を持つ関数を取得
 	result := make([]*types.Func, 0)
 	for _, fn := range fc.fns {
-		ret := fn.Signature().Results()
-		for i := 0; i < ret.Len(); i++ {
-			fnRet := Deref(ret.At(i).Type())
-			if types.Identical(fnRet, returnType) {
-				result = append(result, fn)
-			}
+		if !returnsType(fn, returnType) {
+			continue
+		}
+		if reason, _ := fc.reject(fn, returnType); reason == "" {
+			result = append(result, fn)
 		}
 	}
 	return result
 }
+
+func (fc *functionCache) Candidates(returnType *types.Named) []*Candidate {
+	candidates := make([]*Candidate, 0)
+	selected := make([]*Candidate, 0)
+	for _, fn := range fc.fns {
+		if !returnsType(fn, returnType) {
+			continue
+		}
+		reason, detail := fc.reject(fn, returnType)
+		c := fc.newCandidate(fn, returnType)
+		c.Reason, c.Detail = reason, detail
+		candidates = append(candidates, c)
+		if c.Selected() {
+			selected = append(selected, c)
+		}
+	}
+	// 採用できる候補が複数ある場合はどれも選べない
+	if len(selected) > 1 {
+		for _, c := range selected {
+			c.Reason = RejectAmbiguous
+			c.Detail = "multiple providers return this type"
+		}
+	}
+	return candidates
+}
+
+func (fc *functionCache) Constructors() []*Candidate {
+	constructors := make([]*Candidate, 0)
+	for _, fn := range fc.fns {
+		named := providedType(fn)
+		if named == nil || !fn.Exported() || !injectableParams(fn) {
+			continue
+		}
+		if reason, _ := fc.reject(fn, named); reason != "" {
+			continue
+		}
+		constructors = append(constructors, fc.newCandidate(fn, named))
+	}
+	return constructors
+}
+
+func (fc *functionCache) newCandidate(fn *types.Func, returnType *types.Named) *Candidate {
+	c := &Candidate{Func: fn, Type: returnType}
+	if fc.fset != nil {
+		c.Position = fc.fset.Position(fn.Pos())
+	}
+	return c
+}
+
+func (fc *functionCache) Packages() []*packages.Package {
+	return fc.pkgs
+}
+
+func (fc *functionCache) Position(pos token.Pos) token.Position {
+	if fc.fset == nil || !pos.IsValid() {
+		return token.Position{}
+	}
+	return fc.fset.Position(pos)
+}
+
+// reject は関数がプロバイダとして採用できない理由を返す
+func (fc *functionCache) reject(fn *types.Func, returnType *types.Named) (RejectReason, string) {
+	if directive, ok := fc.ignored[fn.Pkg().Path()+"."+fn.Name()]; ok {
+		return RejectAnnotation, "marked " + directive
+	}
+	if profiles, ok := fc.profiles[fn.Pkg().Path()+"."+fn.Name()]; ok && !slices.Contains(profiles, fc.profile) {
+		return RejectProfile, "only in profile " + strings.Join(profiles, ", ")
+	}
+	if !fn.Exported() && fc.targetPkgPath != "" && fn.Pkg().Path() != fc.targetPkgPath {
+		return RejectVisibility, "unexported function is not accessible from " + fc.targetPkgPath
+	}
+	if err := checkSignature(fn, returnType); err != nil {
+		return RejectSignature, err.Error()
+	}
+	return "", ""
+}
@@ -0,0 +1,55 @@
+package analyze
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// DetectImportCycle は生成ファイルのパッケージ（targetPkgPath）がプロバイダのパッケージから
+// 直接または間接的に import されていないかをチェックする。
+// import されている場合、生成ファイルがそのパッケージを import すると循環参照になるためエラーにする
+func DetectImportCycle(pkgs []*packages.Package, targetPkgPath string, providerPkgPaths []string) error {
+	byPath := make(map[string]*packages.Package)
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		byPath[p.PkgPath] = p
+	})
+
+	checked := make(map[string]bool)
+	for _, pkgPath := range providerPkgPaths {
+		if pkgPath == targetPkgPath || checked[pkgPath] {
+			continue
+		}
+		checked[pkgPath] = true
+		pkg, ok := byPath[pkgPath]
+		if !ok {
+			continue
+		}
+		if chain := findImportChain(pkg, targetPkgPath, make(map[string]bool)); chain != nil {
+			return fmt.Errorf("import cycle: generated package %s imports %s, but %s", targetPkgPath, pkgPath, strings.Join(chain, " imports "))
+		}
+	}
+	return nil
+}
+
+// findImportChain は from から target へ至る import の経路を返す。経路が無い場合は nil
+func findImportChain(from *packages.Package, target string, visited map[string]bool) []string {
+	if visited[from.PkgPath] {
+		return nil
+	}
+	visited[from.PkgPath] = true
+
+	// 出力が実行ごとに変わらないよう、import パスの順に探索する
+	for _, path := range slices.Sorted(maps.Keys(from.Imports)) {
+		if path == target {
+			return []string{from.PkgPath, target}
+		}
+		if chain := findImportChain(from.Imports[path], target, visited); chain != nil {
+			return append([]string{from.PkgPath}, chain...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package analyze
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestDetectImportCycle(t *testing.T) {
+	// app -> handler -> service, service -> app (循環する構成)
+	app := &packages.Package{PkgPath: "example.com/app", Imports: map[string]*packages.Package{}}
+	service := &packages.Package{PkgPath: "example.com/app/service", Imports: map[string]*packages.Package{"example.com/app": app}}
+	handler := &packages.Package{PkgPath: "example.com/app/handler", Imports: map[string]*packages.Package{"example.com/app/service": service}}
+	repo := &packages.Package{PkgPath: "example.com/app/repo", Imports: map[string]*packages.Package{}}
+	pkgs := []*packages.Package{app, service, handler, repo}
+
+	tests := []struct {
+		name        string
+		providers   []string
+		wantErr     bool
+		wantContain string
+	}{
+		{
+			name:      "循環なし",
+			providers: []string{"example.com/app/repo", "example.com/app"},
+			wantErr:   false,
+		},
+		{
+			name:        "直接 import される",
+			providers:   []string{"example.com/app/service"},
+			wantErr:     true,
+			wantContain: "example.com/app/service imports example.com/app",
+		},
+		{
+			name:        "間接的に import される",
+			providers:   []string{"example.com/app/handler"},
+			wantErr:     true,
+			wantContain: "example.com/app/handler imports example.com/app/service imports example.com/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DetectImportCycle(pkgs, "example.com/app", tt.providers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectImportCycle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantContain) {
+				t.Errorf("DetectImportCycle() error = %q, want to contain %q", err.Error(), tt.wantContain)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package analyze
+
+import (
+	"fmt"
+	"go/types"
+	"regexp"
+
+	"github.com/rmocchy/cire/internal/diag"
+)
+
+// Lint はどのルート構造体からも到達しないコンストラクタと、複数のプロバイダの候補がある型を検出する。
+// 複数の候補がある型は、現在ルート構造体から使われていなくても報告する。
+// 関数名が constructors に一致する関数だけをコンストラクタとして扱い、それ以外の補助関数は報告の起点にしない
+func Lint(functionCache FunctionCache, trees RootTrees, constructors *regexp.Regexp) diag.List {
+	reachable := make(map[string]bool)
+	for _, root := range trees {
+		converter := NewConvertTreeToUniqueList()
+		for _, tree := range root.Trees {
+			converter.Execute(tree)
+		}
+		for _, node := range converter.List() {
+			reachable[node.PkgPath+"."+node.Name] = true
+		}
+	}
+
+	diags := make(diag.List, 0)
+	checked := make(map[*types.Named]bool)
+	for _, c := range functionCache.Constructors() {
+		if !constructors.MatchString(c.Func.Name()) {
+			continue
+		}
+		name := c.Func.Pkg().Name() + "." + c.Func.Name()
+		if !reachable[c.Func.Pkg().Path()+"."+c.Func.Name()] {
+			diags = append(diags, diag.Warningf(diag.UnusedConstructor, c.Position, "%s is not reachable from any root", name))
+		}
+
+		if checked[c.Type] {
+			continue
+		}
+		checked[c.Type] = true
+		ambiguous := make([]*Candidate, 0)
+		for _, candidate := range functionCache.Candidates(c.Type) {
+			if candidate.Reason == RejectAmbiguous {
+				ambiguous = append(ambiguous, candidate)
+			}
+		}
+		if len(ambiguous) > 1 {
+			d := diag.Warningf(diag.AmbiguousProvider, c.Position, "%s has multiple providers", c.Type.String())
+			for _, candidate := range ambiguous {
+				d.Related = append(d.Related, diag.Related{
+					Position: candidate.Position,
+					Message:  fmt.Sprintf("candidate %s.%s", candidate.Func.Pkg().Name(), candidate.Func.Name()),
+				})
+			}
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
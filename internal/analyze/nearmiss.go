@@ -0,0 +1,182 @@
+package analyze
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// NearMissReason はプロバイダが見つからない型について、惜しい候補が使えなかった理由
+type NearMissReason string
+
+const (
+	// NearMissPointerMismatch は型を返すが、ポインタかどうかが必要とされている型と異なる関数（wire は T と *T を区別する）。
+	// 必要とされている型が分かる解析の中でだけ報告する
+	NearMissPointerMismatch NearMissReason = "pointer-mismatch"
+	// NearMissUnexported は型を返すが、非公開のため生成ファイルのパッケージから参照できない関数
+	NearMissUnexported NearMissReason = "unexported"
+	// NearMissOutsidePatterns は型を返すが、パッケージが patterns に含まれない（または exclude された）関数
+	NearMissOutsidePatterns NearMissReason = "outside-patterns"
+	// NearMissInterface は型が実装するインターフェースを返す関数
+	NearMissInterface NearMissReason = "interface"
+	// NearMissOtherPackage は別のパッケージにある同じ名前の型
+	NearMissOtherPackage NearMissReason = "other-package"
+	// NearMissSimilarName はコンストラクタらしい名前だが別の型を返す関数
+	NearMissSimilarName NearMissReason = "similar-name"
+)
+
+// nearMissOrder は NearMisses の並び順（原因である可能性が高い順）
+var nearMissOrder = []NearMissReason{NearMissPointerMismatch, NearMissUnexported, NearMissOutsidePatterns, NearMissInterface, NearMissOtherPackage, NearMissSimilarName}
+
+// NearMiss はプロバイダが見つからない型について、使おうとしていた可能性のある関数や型
+type NearMiss struct {
+	// Name はパッケージ名で修飾した関数名または型名
+	Name     string
+	Position token.Position
+	Reason   NearMissReason
+	// Detail は Reason の説明
+	Detail string
+}
+
+// maxSimilarNameDistance は名前が似ているとみなす編集距離の上限
+const maxSimilarNameDistance = 2
+
+// NearMisses は returnType を返す関数が見つからない場合に、使おうとしていた可能性のある関数や型を探す。
+// アノテーション・シグネチャで除外された関数は Candidates で分かるため含めない
+func (fc *functionCache) NearMisses(returnType *types.Named) []*NearMiss {
+	misses := make([]*NearMiss, 0)
+	reported := make(map[*types.Func]bool)
+	loaded := make(map[string]bool)
+	for _, pkg := range fc.pkgs {
+		loaded[pkg.PkgPath] = true
+	}
+
+	// patterns の外にあるパッケージの関数。型を返せるのは型のパッケージかそれを import するパッケージだけ
+	typePkgPath := returnType.Obj().Pkg().Path()
+	packages.Visit(fc.pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil || loaded[pkg.PkgPath] {
+			return
+		}
+		if _, ok := pkg.Imports[typePkgPath]; !ok && pkg.PkgPath != typePkgPath {
+			return
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			fn, ok := scope.Lookup(name).(*types.Func)
+			if !ok || !returnsType(fn, returnType) {
+				continue
+			}
+			reported[fn] = true
+			misses = append(misses, &NearMiss{
+				Name:     qualifiedName(fn),
+				Position: fc.Position(fn.Pos()),
+				Reason:   NearMissOutsidePatterns,
+				Detail:   fmt.Sprintf("package %s is not matched by patterns or is excluded", pkg.PkgPath),
+			})
+		}
+	})
+
+	// 生成ファイルのパッケージから参照できない非公開の関数
+	for _, fn := range fc.fns {
+		if !returnsType(fn, returnType) {
+			continue
+		}
+		if reason, _ := fc.reject(fn, returnType); reason != RejectVisibility {
+			continue
+		}
+		reported[fn] = true
+		misses = append(misses, &NearMiss{
+			Name:     qualifiedName(fn),
+			Position: fc.Position(fn.Pos()),
+			Reason:   NearMissUnexported,
+			Detail:   fmt.Sprintf("%s is not exported, so it cannot be used from package %s; export it", fn.Name(), fc.targetPkgPath),
+		})
+	}
+
+	for _, fn := range fc.fns {
+		named := providedType(fn)
+		if named == nil || types.Identical(named, returnType) || returnsType(fn, returnType) {
+			continue
+		}
+		if iface, ok := named.Underlying().(*types.Interface); ok && iface.NumMethods() > 0 && implements(returnType, iface) {
+			reported[fn] = true
+			misses = append(misses, &NearMiss{
+				Name:     qualifiedName(fn),
+				Position: fc.Position(fn.Pos()),
+				Reason:   NearMissInterface,
+				Detail:   fmt.Sprintf("returns interface %s, which %s implements; require %s instead", named.String(), returnType.Obj().Name(), named.Obj().Name()),
+			})
+		}
+	}
+
+	// 別のパッケージにある同じ名前の型
+	for _, pkg := range fc.pkgs {
+		if pkg.Types == nil || pkg.PkgPath == typePkgPath {
+			continue
+		}
+		if obj, ok := pkg.Types.Scope().Lookup(returnType.Obj().Name()).(*types.TypeName); ok {
+			misses = append(misses, &NearMiss{
+				Name:     pkg.Types.Name() + "." + obj.Name(),
+				Position: fc.Position(obj.Pos()),
+				Reason:   NearMissOtherPackage,
+				Detail:   fmt.Sprintf("%s is a different type with the same name", obj.Type().String()),
+			})
+		}
+	}
+
+	want := strings.ToLower("New" + returnType.Obj().Name())
+	for _, fn := range fc.fns {
+		if reported[fn] || returnsType(fn, returnType) || providedType(fn) == nil {
+			continue
+		}
+		if editDistance(strings.ToLower(fn.Name()), want) > maxSimilarNameDistance {
+			continue
+		}
+		misses = append(misses, &NearMiss{
+			Name:     qualifiedName(fn),
+			Position: fc.Position(fn.Pos()),
+			Reason:   NearMissSimilarName,
+			Detail:   fmt.Sprintf("returns %s, not %s", fn.Signature().Results().At(0).Type().String(), returnType.String()),
+		})
+	}
+
+	slices.SortStableFunc(misses, func(a, b *NearMiss) int {
+		return cmp.Compare(slices.Index(nearMissOrder, a.Reason), slices.Index(nearMissOrder, b.Reason))
+	})
+	return misses
+}
+
+// implements は型またはそのポインタがインターフェースを実装するかを返す
+func implements(t *types.Named, iface *types.Interface) bool {
+	return types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface)
+}
+
+func qualifiedName(fn *types.Func) string {
+	return fn.Pkg().Name() + "." + fn.Name()
+}
+
+// editDistance は2つの文字列のレーベンシュタイン距離を返す
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
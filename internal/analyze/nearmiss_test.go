@@ -0,0 +1,124 @@
+package analyze
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/internal/diag"
+)
+
+func TestFunctionCache_NearMisses(t *testing.T) {
+	// extra は app から import されるが patterns に含まれない
+	pkgs := loadTestPackages(t, "testdata/nearmiss", "./model", "./other", "./app")
+	modelPath := "github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/model"
+	functionCache := NewFunctionCache(pkgs, "")
+
+	tests := []struct {
+		name     string
+		typeName string
+		want     []string
+	}{
+		{
+			name:     "patterns に含まれないパッケージのコンストラクタ",
+			typeName: "Store",
+			want: []string{
+				"extra.NewStore (outside-patterns): package github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/extra is not matched by patterns or is excluded",
+			},
+		},
+		{
+			name:     "型が実装するインターフェースを返すコンストラクタ",
+			typeName: "Cache",
+			want: []string{
+				"model.NewGetter (interface): returns interface " + modelPath + ".Getter, which Cache implements; require Getter instead",
+			},
+		},
+		{
+			name:     "別パッケージの同名の型と名前の似た関数",
+			typeName: "Config",
+			want: []string{
+				"other.Config (other-package): github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/other.Config is a different type with the same name",
+				"model.NewConfg (similar-name): returns *" + modelPath + ".Settings, not " + modelPath + ".Config",
+				"other.NewConfig (similar-name): returns *github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/other.Config, not " + modelPath + ".Config",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, m := range functionCache.NearMisses(findNamedType(t, pkgs, modelPath, tt.typeName)) {
+				if m.Position.Line == 0 {
+					t.Errorf("%s の位置が設定されていません", m.Name)
+				}
+				got = append(got, m.Name+" ("+string(m.Reason)+"): "+m.Detail)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("NearMisses() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestFunctionCache_NearMisses_Unexported(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/nearmiss", "./model", "./other", "./app")
+	modelPath := "github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/model"
+	appPath := "github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/app"
+	// 生成ファイルのパッケージ app から model の非公開関数は参照できない
+	functionCache := NewFunctionCache(pkgs, appPath)
+
+	got := make([]string, 0)
+	for _, m := range functionCache.NearMisses(findNamedType(t, pkgs, modelPath, "Token")) {
+		got = append(got, m.Name+" ("+string(m.Reason)+"): "+m.Detail)
+	}
+	want := []string{
+		"model.newToken (unexported): newToken is not exported, so it cannot be used from package " + appPath + "; export it",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("NearMisses() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyze_ExecuteFromStruct_ReportsPointerMismatch(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/nearmiss", "./model", "./other", "./app")
+	modelPath := "github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/model"
+	appPath := "github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/app"
+	analyzer := NewAnalyze(NewFunctionCache(pkgs, appPath), NewAnalysisCache())
+
+	_, err := analyzer.ExecuteFromStruct(findNamedType(t, pkgs, appPath, "App"))
+	var diags diag.List
+	if !errors.As(err, &diags) {
+		t.Fatalf("ExecuteFromStruct() error = %v, want diag.List", err)
+	}
+	// NewClock は Clock を返すため、*Clock を必要とするフィールドには使えない
+	var clock *diag.Diagnostic
+	for _, d := range diags {
+		if strings.Contains(d.Message, "*"+modelPath+".Clock") {
+			clock = d
+		}
+	}
+	if clock == nil || clock.Code != diag.MissingProvider {
+		t.Fatalf("diagnostics = %v, want %s for *model.Clock", diags, diag.MissingProvider)
+	}
+	want := "did you mean model.NewClock? (pointer-mismatch: returns " + modelPath + ".Clock, but *" + modelPath + ".Clock is required)"
+	if len(clock.Related) == 0 || clock.Related[0].Message != want {
+		t.Errorf("Related = %v, want first %q", clock.Related, want)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "newconfig", b: "newconfig", want: 0},
+		{a: "newconfg", b: "newconfig", want: 1},
+		{a: "newconf", b: "newconfig", want: 2},
+		{a: "", b: "new", want: 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package analyze
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// OverrideDirective はテスト用のインジェクタでプロバイダを差し替える関数に付けるアノテーション。
+// 差し替える型を続けて書く（例: //cire:override repository.UserRepository）
+const OverrideDirective = "//cire:override"
+
+// RankOverride は差し替えの候補の優先度。組み込みのリゾルバのどの候補よりも優先される
+const RankOverride = -10
+
+// Override はテスト用のインジェクタで型のプロバイダを置き換える関数
+type Override struct {
+	// Type は差し替える型
+	Type *types.Named
+	Func *types.Func
+}
+
+// OverrideAnnotation は OverrideDirective の付いた関数と、アノテーションに書かれた型名
+type OverrideAnnotation struct {
+	Func *types.Func
+	// TypeName はアノテーションに書かれた型名（例: "repository.UserRepository"）。書かれていない場合は空
+	TypeName string
+}
+
+// FindOverrideAnnotations はパッケージのファイルから OverrideDirective の付いたパッケージレベルの関数を探す。
+// wire はテストファイルを読まないため、差し替える関数はテストファイル以外に宣言する
+func FindOverrideAnnotations(pkg *packages.Package) []*OverrideAnnotation {
+	annotations := make([]*OverrideAnnotation, 0)
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv != nil {
+				continue
+			}
+			typeName, ok := directiveArg(fd.Doc, OverrideDirective)
+			if !ok {
+				continue
+			}
+			if fn, ok := pkg.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+				annotations = append(annotations, &OverrideAnnotation{Func: fn, TypeName: typeName})
+			}
+		}
+	}
+	return annotations
+}
+
+// directiveArg はコメントのアノテーションに続く最初の語を返す
+func directiveArg(doc *ast.CommentGroup, directive string) (string, bool) {
+	args, ok := directiveArgs(doc, directive)
+	if len(args) == 0 {
+		return "", ok
+	}
+	return args[0], true
+}
+
+// CheckOverride は差し替える関数が型を返すプロバイダの形かを検査する
+func CheckOverride(o *Override) error {
+	provided := providedType(o.Func)
+	if provided == nil || getIdenticalTypeName(provided) != getIdenticalTypeName(o.Type) {
+		return fmt.Errorf("%s does not return %s", qualifiedName(o.Func), o.Type.String())
+	}
+	return checkSignature(o.Func, provided)
+}
+
+type overrideResolver struct {
+	overrides map[string]*Override
+}
+
+// NewOverrideResolver は差し替える関数を RankOverride の候補にするリゾルバを作成する
+func NewOverrideResolver(overrides []*Override) ProviderResolver {
+	r := &overrideResolver{overrides: make(map[string]*Override, len(overrides))}
+	for _, o := range overrides {
+		r.overrides[getIdenticalTypeName(o.Type)] = o
+	}
+	return r
+}
+
+func (r *overrideResolver) Name() string {
+	return "override"
+}
+
+func (r *overrideResolver) Resolve(req *ResolveRequest) []*ResolvedProvider {
+	o, ok := r.overrides[getIdenticalTypeName(req.Type)]
+	if !ok {
+		return nil
+	}
+	return []*ResolvedProvider{{Kind: ProviderFunc, Type: req.Type, Func: o.Func, Rank: RankOverride, Pos: o.Func.Pos()}}
+}
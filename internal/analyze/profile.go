@@ -0,0 +1,63 @@
+package analyze
+
+import (
+	"go/ast"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ProfileDirective はプロファイル（dev や prod などのビルドの種類）でだけ使うプロバイダに付けるアノテーション。
+// プロファイル名を空白またはカンマで区切って続ける（例: //cire:profile dev staging）。
+// アノテーションの無い関数は全てのプロファイルで使う
+const ProfileDirective = "//cire:profile"
+
+// profileFuncs は ProfileDirective が付いた関数を「パッケージパス.関数名」からプロファイル名への対応で返す
+func profileFuncs(pkgPath string, files []*ast.File) map[string][]string {
+	profiles := make(map[string][]string)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv != nil || fd.Doc == nil {
+				continue
+			}
+			if names, ok := directiveArgs(fd.Doc, ProfileDirective); ok {
+				profiles[pkgPath+"."+fd.Name.Name] = names
+			}
+		}
+	}
+	return profiles
+}
+
+// DeclaredProfiles はパッケージの関数の ProfileDirective に書かれたプロファイル名を重複を除いて昇順で返す
+func DeclaredProfiles(pkgs []*packages.Package) []string {
+	declared := make(map[string]bool)
+	for _, pkg := range pkgs {
+		for _, names := range profileFuncs(pkg.PkgPath, pkg.Syntax) {
+			for _, name := range names {
+				declared[name] = true
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(declared))
+}
+
+// directiveArgs はコメントのアノテーションに続く語を、空白またはカンマで区切って返す
+func directiveArgs(doc *ast.CommentGroup, directive string) ([]string, bool) {
+	if doc == nil {
+		return nil, false
+	}
+	for _, c := range doc.List {
+		if c.Text == directive {
+			return nil, true
+		}
+		if rest, ok := strings.CutPrefix(c.Text, directive+" "); ok {
+			return strings.FieldsFunc(rest, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			}), true
+		}
+	}
+	return nil, false
+}
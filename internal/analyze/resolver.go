@@ -0,0 +1,329 @@
+package analyze
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ProviderKind はプロバイダの種類
+type ProviderKind string
+
+const (
+	// ProviderFunc はコンストラクタ関数
+	ProviderFunc ProviderKind = "func"
+	// ProviderBind はインターフェースを実装の型に束縛する（wire.Bind）
+	ProviderBind ProviderKind = "bind"
+	// ProviderStruct は構造体のフィールドを全て注入して生成する（wire.Struct）
+	ProviderStruct ProviderKind = "struct"
+	// ProviderField は構造体のフィールドの値を使う（wire.FieldsOf）
+	ProviderField ProviderKind = "field"
+)
+
+// 組み込みのリゾルバが返す候補の優先度。小さいほど優先される
+const (
+	RankFunction = 0
+	RankBinding  = 10
+	RankStruct   = 10
+	RankField    = 20
+)
+
+const (
+	// StructDirective は wire.Struct で生成する構造体に付けるアノテーション
+	StructDirective = "//cire:struct"
+	// FieldsDirective はフィールドの値をプロバイダとして使う構造体に付けるアノテーション
+	FieldsDirective = "//cire:fields"
+)
+
+// ResolveRequest はリゾルバに渡す、プロバイダを探す型とロード済みのパッケージ
+type ResolveRequest struct {
+	Type *types.Named
+	// Pkgs はプロバイダを探索するパッケージ（exclude で除外されたパッケージは含まない）
+	Pkgs []*packages.Package
+}
+
+// ResolvedProvider はリゾルバが見つけたプロバイダの候補
+type ResolvedProvider struct {
+	Kind ProviderKind
+	// Type は候補が供給する型
+	Type *types.Named
+	// Func は ProviderFunc の関数
+	Func *types.Func
+	// Source は ProviderBind の実装の型、ProviderField のフィールドを持つ構造体
+	Source *types.Named
+	// Field は ProviderField のフィールド名
+	Field string
+	// Rank は候補の優先度。全てのリゾルバの候補のうち Rank が最も小さいものを使い、複数ある場合は曖昧とする
+	Rank int
+	// Pos は候補を宣言した位置
+	Pos token.Pos
+}
+
+// ProviderResolver は型のプロバイダの候補を探す拡張点。
+// analyze.NewAnalyze に複数のリゾルバを渡すと、全てのリゾルバの候補から Rank で選ぶ
+type ProviderResolver interface {
+	// Name はリゾルバの名前
+	Name() string
+	// Resolve は型のプロバイダの候補を返す。候補が無い場合は空
+	Resolve(req *ResolveRequest) []*ResolvedProvider
+}
+
+// DefaultResolvers は組み込みのリゾルバ（関数・インターフェースの束縛・構造体・フィールド）を返す
+func DefaultResolvers(functionCache FunctionCache) []ProviderResolver {
+	return []ProviderResolver{
+		NewFunctionResolver(functionCache),
+		NewBindingResolver(functionCache),
+		NewStructResolver(),
+		NewFieldResolver(),
+	}
+}
+
+// String はパッケージ名で修飾した候補の説明を返す（例: "service.NewService", "struct service.Config"）
+func (p *ResolvedProvider) String() string {
+	switch p.Kind {
+	case ProviderBind:
+		return fmt.Sprintf("binding %s to %s", qualifiedType(p.Type), qualifiedType(p.Source))
+	case ProviderStruct:
+		return "struct " + qualifiedType(p.Type)
+	case ProviderField:
+		return fmt.Sprintf("field %s.%s", qualifiedType(p.Source), p.Field)
+	}
+	return qualifiedName(p.Func)
+}
+
+// shortName は候補を一覧で示すときの名前を返す。関数はパッケージ名を付けない
+func (p *ResolvedProvider) shortName() string {
+	if p.Kind == ProviderFunc {
+		return p.Func.Name()
+	}
+	return p.String()
+}
+
+// dependencies は候補の生成に必要な値（関数の引数、束縛する実装、構造体のフィールド、フィールドを持つ構造体）を返す
+func (p *ResolvedProvider) dependencies() []*types.Var {
+	deps := make([]*types.Var, 0)
+	switch p.Kind {
+	case ProviderFunc:
+		params := p.Func.Signature().Params()
+		for i := 0; i < params.Len(); i++ {
+			deps = append(deps, params.At(i))
+		}
+	case ProviderStruct:
+		st := p.Type.Underlying().(*types.Struct)
+		for i := 0; i < st.NumFields(); i++ {
+			deps = append(deps, st.Field(i))
+		}
+	case ProviderBind, ProviderField:
+		deps = append(deps, types.NewVar(p.Pos, p.Source.Obj().Pkg(), p.Source.Obj().Name(), p.Source))
+	}
+	return deps
+}
+
+// requiredBy は依存する値の説明を返す（診断の "required by" に使う）
+func (p *ResolvedProvider) requiredBy(dep *types.Var) string {
+	switch p.Kind {
+	case ProviderFunc:
+		return fmt.Sprintf("parameter %s of %s", dep.Name(), qualifiedName(p.Func))
+	case ProviderStruct:
+		return fmt.Sprintf("field %s.%s", p.Type.Obj().Name(), dep.Name())
+	}
+	return p.String()
+}
+
+// results は候補が返す値の型を返す
+func (p *ResolvedProvider) results() []string {
+	switch p.Kind {
+	case ProviderFunc:
+		rets := p.Func.Signature().Results()
+		results := make([]string, 0, rets.Len())
+		for i := 0; i < rets.Len(); i++ {
+			results = append(results, rets.At(i).Type().String())
+		}
+		return results
+	case ProviderField:
+		st := p.Source.Underlying().(*types.Struct)
+		for i := 0; i < st.NumFields(); i++ {
+			if st.Field(i).Name() == p.Field {
+				return []string{st.Field(i).Type().String()}
+			}
+		}
+	}
+	return []string{p.Type.String()}
+}
+
+func qualifiedType(t *types.Named) string {
+	return t.Obj().Pkg().Name() + "." + t.Obj().Name()
+}
+
+type functionResolver struct {
+	functionCache FunctionCache
+}
+
+// NewFunctionResolver は型を返すコンストラクタ関数を候補にするリゾルバを作成する
+func NewFunctionResolver(functionCache FunctionCache) ProviderResolver {
+	return &functionResolver{functionCache: functionCache}
+}
+
+func (r *functionResolver) Name() string {
+	return "function"
+}
+
+func (r *functionResolver) Resolve(req *ResolveRequest) []*ResolvedProvider {
+	providers := make([]*ResolvedProvider, 0)
+	for _, fn := range r.functionCache.BulkGet(req.Type) {
+		providers = append(providers, &ResolvedProvider{Kind: ProviderFunc, Type: req.Type, Func: fn, Rank: RankFunction, Pos: fn.Pos()})
+	}
+	return providers
+}
+
+type bindingResolver struct {
+	functionCache FunctionCache
+	// named は探索するパッケージの名前付き型。最初の Resolve で集める
+	named []*types.Named
+}
+
+// NewBindingResolver はインターフェースを、それを実装しコンストラクタ関数を持つ型に束縛する候補を作成するリゾルバを作成する
+func NewBindingResolver(functionCache FunctionCache) ProviderResolver {
+	return &bindingResolver{functionCache: functionCache}
+}
+
+func (r *bindingResolver) Name() string {
+	return "binding"
+}
+
+func (r *bindingResolver) Resolve(req *ResolveRequest) []*ResolvedProvider {
+	iface, ok := req.Type.Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() == 0 {
+		return nil
+	}
+	if r.named == nil {
+		r.named = namedTypes(req.Pkgs)
+	}
+	providers := make([]*ResolvedProvider, 0)
+	for _, named := range r.named {
+		if types.IsInterface(named) || named.TypeParams().Len() > 0 || !implements(named, iface) {
+			continue
+		}
+		if len(r.functionCache.BulkGet(named)) == 0 {
+			continue
+		}
+		providers = append(providers, &ResolvedProvider{Kind: ProviderBind, Type: req.Type, Source: named, Rank: RankBinding, Pos: named.Obj().Pos()})
+	}
+	return providers
+}
+
+type structResolver struct {
+	// annotated は StructDirective が付いた構造体（キーは「パッケージパス.型名」）。最初の Resolve で集める
+	annotated map[string]bool
+}
+
+// NewStructResolver は StructDirective が付いた構造体を、フィールドを全て注入して生成する候補にするリゾルバを作成する
+func NewStructResolver() ProviderResolver {
+	return &structResolver{}
+}
+
+func (r *structResolver) Name() string {
+	return "struct"
+}
+
+func (r *structResolver) Resolve(req *ResolveRequest) []*ResolvedProvider {
+	if r.annotated == nil {
+		r.annotated = annotatedTypes(req.Pkgs, StructDirective)
+	}
+	if _, ok := req.Type.Underlying().(*types.Struct); !ok || !r.annotated[typeKey(req.Type)] {
+		return nil
+	}
+	return []*ResolvedProvider{{Kind: ProviderStruct, Type: req.Type, Rank: RankStruct, Pos: req.Type.Obj().Pos()}}
+}
+
+type fieldResolver struct {
+	// structs は FieldsDirective が付いた構造体。最初の Resolve で集める
+	structs []*types.Named
+}
+
+// NewFieldResolver は FieldsDirective が付いた構造体の公開フィールドを、フィールドの型の候補にするリゾルバを作成する
+func NewFieldResolver() ProviderResolver {
+	return &fieldResolver{}
+}
+
+func (r *fieldResolver) Name() string {
+	return "field"
+}
+
+func (r *fieldResolver) Resolve(req *ResolveRequest) []*ResolvedProvider {
+	if r.structs == nil {
+		annotated := annotatedTypes(req.Pkgs, FieldsDirective)
+		r.structs = make([]*types.Named, 0)
+		for _, named := range namedTypes(req.Pkgs) {
+			if _, ok := named.Underlying().(*types.Struct); ok && annotated[typeKey(named)] {
+				r.structs = append(r.structs, named)
+			}
+		}
+	}
+	providers := make([]*ResolvedProvider, 0)
+	for _, s := range r.structs {
+		st := s.Underlying().(*types.Struct)
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			if !field.Exported() || !types.Identical(Deref(field.Type()), req.Type) {
+				continue
+			}
+			providers = append(providers, &ResolvedProvider{Kind: ProviderField, Type: req.Type, Source: s, Field: field.Name(), Rank: RankField, Pos: field.Pos()})
+		}
+	}
+	return providers
+}
+
+func typeKey(t *types.Named) string {
+	return t.Obj().Pkg().Path() + "." + t.Obj().Name()
+}
+
+// namedTypes はパッケージレベルで宣言された名前付き型を、パッケージパスと型名の順に返す
+func namedTypes(pkgs []*packages.Package) []*types.Named {
+	result := make([]*types.Named, 0)
+	for _, pkg := range pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			if tn, ok := scope.Lookup(name).(*types.TypeName); ok && !tn.IsAlias() {
+				if named, ok := tn.Type().(*types.Named); ok {
+					result = append(result, named)
+				}
+			}
+		}
+	}
+	slices.SortFunc(result, func(a, b *types.Named) int {
+		return strings.Compare(typeKey(a), typeKey(b))
+	})
+	return result
+}
+
+// annotatedTypes はアノテーションが付いた型を「パッケージパス.型名」で返す
+func annotatedTypes(pkgs []*packages.Package, directive string) map[string]bool {
+	annotated := make(map[string]bool)
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Syntax {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					// 1つの型だけを宣言する場合、コメントは type キーワードの前に書かれる
+					if hasDirective(ts.Doc, directive) || (len(gd.Specs) == 1 && hasDirective(gd.Doc, directive)) {
+						annotated[pkg.PkgPath+"."+ts.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return annotated
+}
@@ -0,0 +1,124 @@
+package analyze
+
+import (
+	"errors"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/internal/diag"
+)
+
+const resolverPkgPath = "github.com/rmocchy/cire/internal/analyze/testdata/resolver"
+
+// clockResolver は SystemClock を Clock のプロバイダにするカスタムのリゾルバ
+type clockResolver struct {
+	rank int
+}
+
+func (r *clockResolver) Name() string {
+	return "clock"
+}
+
+func (r *clockResolver) Resolve(req *ResolveRequest) []*ResolvedProvider {
+	if req.Type.Obj().Name() != "Clock" {
+		return nil
+	}
+	fn, ok := req.Type.Obj().Pkg().Scope().Lookup("SystemClock").(*types.Func)
+	if !ok {
+		return nil
+	}
+	return []*ResolvedProvider{{Kind: ProviderFunc, Type: req.Type, Func: fn, Rank: r.rank, Pos: fn.Pos()}}
+}
+
+// describeNodes はツリーを深さ優先で「種類 名前 <- 依存」の形式に変換する
+func describeNodes(nodes []*FnDITreeNode) []string {
+	lines := make([]string, 0)
+	for _, node := range nodes {
+		line := string(node.Kind) + " " + node.Name
+		if node.Source != nil {
+			line += " (" + node.Source.String() + ")"
+		}
+		childs := make([]string, 0, len(node.Childs))
+		for _, child := range node.Childs {
+			childs = append(childs, child.Name)
+		}
+		if len(childs) > 0 {
+			line += " <- " + strings.Join(childs, ", ")
+		}
+		lines = append(lines, line)
+		lines = append(lines, describeNodes(node.Childs)...)
+	}
+	return lines
+}
+
+func TestAnalyze_Resolvers(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/resolver")
+	fc := NewFunctionCache(pkgs, "")
+	resolvers := append(DefaultResolvers(fc), &clockResolver{rank: RankFunction})
+	analyzer := NewAnalyze(fc, NewAnalysisCache(), resolvers...)
+
+	nodes, err := analyzer.ExecuteFromStruct(findNamedType(t, pkgs, resolverPkgPath, "App"))
+	if err != nil {
+		t.Fatalf("ExecuteFromStruct() error = %v", err)
+	}
+	got := describeNodes(nodes)
+	want := []string{
+		"bind Store (*" + resolverPkgPath + ".MemoryStore) <- NewMemoryStore",
+		"func NewMemoryStore <- Config",
+		"struct Config",
+		"field Env.Region (*" + resolverPkgPath + ".Env) <- NewEnv",
+		"func NewEnv",
+		"func SystemClock",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("tree =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyze_Resolvers_Rank(t *testing.T) {
+	pkgs := loadTestPackages(t, "testdata/resolver")
+	fc := NewFunctionCache(pkgs, "")
+	clock := findNamedType(t, pkgs, resolverPkgPath, "Clock")
+
+	tests := []struct {
+		name      string
+		resolvers []ProviderResolver
+		wantCode  diag.Code
+	}{
+		{
+			name:      "組み込みのリゾルバだけでは見つからない",
+			resolvers: DefaultResolvers(fc),
+			wantCode:  diag.MissingProvider,
+		},
+		{
+			name:      "同じ Rank の候補は曖昧",
+			resolvers: []ProviderResolver{&clockResolver{rank: RankFunction}, &clockResolver{rank: RankFunction}},
+			wantCode:  diag.AmbiguousProvider,
+		},
+		{
+			name:      "Rank が小さい候補を優先する",
+			resolvers: []ProviderResolver{&clockResolver{rank: RankFunction}, &clockResolver{rank: RankField}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyze(fc, NewAnalysisCache(), tt.resolvers...)
+			structure := types.NewNamed(types.NewTypeName(0, clock.Obj().Pkg(), "Root", nil), nil, nil)
+			structure.SetUnderlying(types.NewStruct([]*types.Var{types.NewField(0, clock.Obj().Pkg(), "Clock", clock, false)}, nil))
+
+			_, err := analyzer.ExecuteFromStruct(structure)
+			var diags diag.List
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("ExecuteFromStruct() error = %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &diags) || diags[0].Code != tt.wantCode {
+				t.Errorf("ExecuteFromStruct() error = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+}
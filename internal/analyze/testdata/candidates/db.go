@@ -0,0 +1,49 @@
+package candidates
+
+import "errors"
+
+type DB struct{}
+
+type Cache struct{}
+
+type Logger struct{}
+
+func NewDB() (*DB, func(), error) {
+	return &DB{}, func() {}, nil
+}
+
+//cire:ignore テスト用のインメモリ実装
+func NewMemoryDB() *DB {
+	return &DB{}
+}
+
+func newLocalDB() *DB {
+	return &DB{}
+}
+
+func OpenDB() (error, *DB) {
+	return errors.New("not implemented"), nil
+}
+
+func NewCache() *Cache {
+	return &Cache{}
+}
+
+func NewRedisCache() (*Cache, error) {
+	return &Cache{}, nil
+}
+
+func NewLogger() Logger {
+	return Logger{}
+}
+
+type Settings struct{}
+
+// LoadSettings と DefaultSettings はコンストラクタの名前ではない補助関数
+func LoadSettings() (*Settings, error) {
+	return &Settings{}, nil
+}
+
+func DefaultSettings() *Settings {
+	return &Settings{}
+}
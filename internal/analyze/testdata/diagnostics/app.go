@@ -0,0 +1,42 @@
+package diagnostics
+
+type Config struct{}
+
+type Queue struct{}
+
+type Service struct{}
+
+func OpenQueue() (error, *Queue) {
+	return nil, &Queue{}
+}
+
+func NewService(cfg *Config, queue *Queue) *Service {
+	return &Service{}
+}
+
+type App struct {
+	Service *Service
+	Count   int
+	Queue   *Queue
+}
+
+type Loop struct{}
+
+type Ring struct{}
+
+func NewLoop(ring *Ring) *Loop {
+	return &Loop{}
+}
+
+func NewRing(loop *Loop) *Ring {
+	return &Ring{}
+}
+
+type Admin struct {
+	Service *Service
+	Loop    *Loop
+}
+
+type Ops struct {
+	Ring *Ring
+}
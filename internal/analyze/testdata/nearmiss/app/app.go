@@ -0,0 +1,16 @@
+package app
+
+import (
+	"github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/extra"
+	"github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/model"
+)
+
+type App struct {
+	Config *model.Config
+	Store  *model.Store
+	Cache  *model.Cache
+	Clock  *model.Clock
+	Token  *model.Token
+}
+
+var _ = extra.NewStore
@@ -0,0 +1,7 @@
+package extra
+
+import "github.com/rmocchy/cire/internal/analyze/testdata/nearmiss/model"
+
+func NewStore() *model.Store {
+	return &model.Store{}
+}
@@ -0,0 +1,37 @@
+package model
+
+type Config struct{}
+
+type Settings struct{}
+
+type Store struct{}
+
+type Cache struct{}
+
+func (c *Cache) Get() string {
+	return ""
+}
+
+type Getter interface {
+	Get() string
+}
+
+func NewGetter() Getter {
+	return &Cache{}
+}
+
+func NewConfg() *Settings {
+	return &Settings{}
+}
+
+type Clock struct{}
+
+func NewClock() Clock {
+	return Clock{}
+}
+
+type Token struct{}
+
+func newToken() *Token {
+	return &Token{}
+}
@@ -0,0 +1,7 @@
+package other
+
+type Config struct{}
+
+func NewConfig() *Config {
+	return &Config{}
+}
@@ -0,0 +1,19 @@
+package pointer
+
+type Clock struct{}
+
+func NewClock() Clock {
+	return Clock{}
+}
+
+type Store struct{}
+
+func NewStore(clock Clock) *Store {
+	return &Store{}
+}
+
+type App struct {
+	Clock Clock
+	Store *Store
+	Now   *Clock
+}
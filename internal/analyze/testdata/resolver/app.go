@@ -0,0 +1,57 @@
+package resolver
+
+// Config は wire.Struct で生成する
+//
+//cire:struct
+type Config struct {
+	DSN string
+}
+
+// Env はフィールドの値をプロバイダとして使う
+//
+//cire:fields
+type Env struct {
+	Region Region
+	// secret は非公開のため使わない
+	secret Secret
+}
+
+type Region string
+
+type Secret string
+
+func NewEnv() *Env {
+	return &Env{}
+}
+
+type Store interface {
+	Get(key string) string
+}
+
+type MemoryStore struct {
+	cfg Config
+}
+
+func (s *MemoryStore) Get(key string) string {
+	return s.cfg.DSN + key
+}
+
+func NewMemoryStore(cfg Config) *MemoryStore {
+	return &MemoryStore{cfg: cfg}
+}
+
+// Clock はカスタムのリゾルバで解決する
+type Clock struct{}
+
+// SystemClock は除外されているため、組み込みのリゾルバでは見つからない
+//
+//cire:ignore
+func SystemClock() Clock {
+	return Clock{}
+}
+
+type App struct {
+	store  Store
+	region Region
+	clock  Clock
+}
@@ -3,6 +3,44 @@ package analyze
 type FnDITreeNode struct {
 	Name        string          `json:"name"`
 	PkgPath     string          `json:"pkg_path"`
+	PkgName     string          `json:"pkg_name"`
 	Childs      []*FnDITreeNode `json:"childs"`
 	ReturnTypes []string        `json:"return_types"`
+	// Params は引数の型。プロバイダで解決されない引数（基本型など）は外部からの入力になる
+	Params []string `json:"params"`
+	// ReturnsInterface はインターフェースを返すプロバイダ（実装をインターフェースに束縛する）かどうか
+	ReturnsInterface bool `json:"returns_interface"`
+	// Kind はプロバイダの種類。ProviderFunc 以外では Name は型名（ProviderField は「構造体名.フィールド名」）
+	Kind ProviderKind `json:"kind"`
+	// Source は ProviderBind の実装の型、ProviderField のフィールドを持つ構造体
+	Source *TypeRef `json:"source,omitempty"`
+	// Field は ProviderField のフィールド名
+	Field string `json:"field,omitempty"`
 }
+
+// TypeRef は生成コードから参照する名前付き型
+type TypeRef struct {
+	PkgPath string `json:"pkg_path"`
+	PkgName string `json:"pkg_name"`
+	Name    string `json:"name"`
+	// Pointer は型をポインタとして参照するかどうか
+	Pointer bool `json:"pointer"`
+}
+
+// String は "*pkg/path.Name" の形式で型を返す
+func (r *TypeRef) String() string {
+	s := r.PkgPath + "." + r.Name
+	if r.Pointer {
+		return "*" + s
+	}
+	return s
+}
+
+// RootTree はルート構造体ごとの解析結果
+type RootTree struct {
+	Name  string
+	Trees []*FnDITreeNode
+}
+
+// RootTrees はルート構造体ごとの解析結果の一覧（ルート構造体の定義順）
+type RootTrees []RootTree
@@ -30,6 +30,12 @@ func (c *convertTreeToUniqueList) List() []*FnDITreeNode {
 	return c.list
 }
 
+// isPointer は型がポインタ型かどうかを返す
+func isPointer(t types.Type) bool {
+	_, ok := t.(*types.Pointer)
+	return ok
+}
+
 // Deref は、ポインタ型の場合はその要素の型を返し、そうでない場合はそのままの型を返す
 func Deref(t types.Type) types.Type {
 	if ptr, ok := t.(*types.Pointer); ok {
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rmocchy/cire/internal/config"
+	"github.com/rmocchy/cire/internal/file"
+	"golang.org/x/tools/go/packages"
+)
+
+// ConfigOverrides は CLI フラグで明示的に指定された設定値。ゼロ値の項目は上書きしない
+type ConfigOverrides struct {
+	WireOutput string
+	BuildTags  []string
+	Patterns   []string
+	Exclude    []string
+	Backend    string
+	// SharedSets が true の場合は設定ファイルの値に関わらず共通のプロバイダセットを生成する
+	SharedSets bool
+	// PackageSets が true の場合は設定ファイルの値に関わらずパッケージごとの公開のプロバイダセットを生成する
+	PackageSets bool
+}
+
+// LoadConfig は設定ファイルを読み込み、CLI フラグの値で上書きする
+func LoadConfig(filePath, configPath string, overrides *ConfigOverrides) (*config.Config, error) {
+	var (
+		cfg *config.Config
+		err error
+	)
+	if configPath != "" {
+		cfg, err = config.Load(configPath)
+	} else {
+		cfg, err = config.LoadForInput(filePath)
+	}
+	if err != nil {
+		return nil, withExitCode(ExitLoadFailed, err)
+	}
+	if overrides == nil {
+		return cfg, nil
+	}
+
+	if overrides.WireOutput != "" {
+		cfg.Output.Wire = overrides.WireOutput
+	}
+	if overrides.BuildTags != nil {
+		cfg.BuildTags = overrides.BuildTags
+	}
+	if overrides.Patterns != nil {
+		cfg.Patterns = overrides.Patterns
+	}
+	if overrides.Exclude != nil {
+		cfg.Exclude = overrides.Exclude
+	}
+	if overrides.Backend != "" {
+		cfg.Backend = overrides.Backend
+	}
+	if overrides.SharedSets {
+		cfg.SharedSets = true
+	}
+	if overrides.PackageSets {
+		cfg.PackageSets = true
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, withExitCode(ExitLoadFailed, err)
+	}
+	return cfg, nil
+}
+
+// loadPackages は設定に従って入力ファイルを含むモジュールのパッケージをロードする
+func loadPackages(filePath string, cfg *config.Config, overlay map[string][]byte) ([]*packages.Package, error) {
+	return file.LoadAllPkgsFromPath(filePath, &file.LoadOptions{
+		Patterns:   cfg.Patterns,
+		BuildFlags: cfg.BuildFlags(),
+		Overlay:    overlay,
+	})
+}
+
+// loadOverlay は --overlay で指定されたオーバーレイファイルを読む。path が空の場合は nil を返す
+func loadOverlay(path string) (map[string][]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	overlay, err := file.LoadOverlay(path)
+	if err != nil {
+		return nil, withExitCode(ExitLoadFailed, err)
+	}
+	return overlay, nil
+}
+
+// filterExcluded は設定で除外されたパッケージを取り除く
+func filterExcluded(pkgs []*packages.Package, cfg *config.Config) []*packages.Package {
+	filtered := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		modulePath := ""
+		if pkg.Module != nil {
+			modulePath = pkg.Module.Path
+		}
+		if cfg.IsExcluded(pkg.PkgPath, modulePath) {
+			continue
+		}
+		filtered = append(filtered, pkg)
+	}
+	return filtered
+}
+
+type ConfigValidateInput struct {
+	// ConfigPath は検証する設定ファイルのパス。空の場合は FilePath から探索する
+	ConfigPath string
+	FilePath   string
+}
+
+// RunConfigValidate は設定ファイルを検証し、見つかった問題を全て出力する
+func RunConfigValidate(input *ConfigValidateInput) error {
+	path := input.ConfigPath
+	if path == "" {
+		found, err := config.Find(input.FilePath)
+		if err != nil {
+			return err
+		}
+		if found == "" {
+			return fmt.Errorf("%s not found from %s", config.FileName, input.FilePath)
+		}
+		path = found
+	}
+
+	problems, err := config.ValidateFile(path)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, p)
+		}
+		return errors.New("config validation failed")
+	}
+
+	fmt.Printf("Config is valid: %s\n", path)
+	return nil
+}
@@ -0,0 +1,36 @@
+package app
+
+import "errors"
+
+// 終了コード。それ以外のエラーは 1 で終了する
+const (
+	// ExitAnalysisFailed は解析で error の診断が見つかった
+	ExitAnalysisFailed = 2
+	// ExitLoadFailed は設定ファイルやパッケージの読み込みに失敗した
+	ExitLoadFailed = 3
+	// ExitStaleOutput は --check で生成ファイルが最新でなかった
+	ExitStaleOutput = 4
+)
+
+// ExitError は終了コードを指定するエラー
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// withExitCode は err を終了コード付きのエラーにする。err が nil の場合や既に終了コードを持つ場合はそのまま返す
+func withExitCode(code int, err error) error {
+	var exitErr *ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		return err
+	}
+	return &ExitError{Code: code, Err: err}
+}
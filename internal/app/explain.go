@@ -0,0 +1,190 @@
+package app
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"golang.org/x/tools/go/packages"
+)
+
+type ExplainInput struct {
+	FilePath   string
+	ConfigPath string
+	// OverlayPath は go コマンドの -overlay と同じ形式のオーバーレイファイル（"-" は標準入力）
+	OverlayPath string
+	// TypeName は説明する型（例: "github.com/acme/db.DB" または "db.DB"）
+	TypeName string
+	// Root は経路を表示するルート構造体名。空の場合は全てのルート構造体を対象にする
+	Root string
+}
+
+// RunExplain は型がどのプロバイダから供給されるか、どの経路で使われるか、
+// 採用されなかった候補とその理由を標準出力に出力する
+func RunExplain(input *ExplainInput) error {
+	return explain(os.Stdout, input)
+}
+
+func explain(w io.Writer, input *ExplainInput) error {
+	cfg, err := LoadConfig(input.FilePath, input.ConfigPath, nil)
+	if err != nil {
+		return err
+	}
+	overlay, err := loadOverlay(input.OverlayPath)
+	if err != nil {
+		return err
+	}
+	proj, err := LoadProjectWithOverlay(input.FilePath, cfg, overlay)
+	if err != nil {
+		return err
+	}
+
+	// 短縮形の型名が他のルートファイル向けのパッケージと衝突しないよう、入力ファイルのパッケージの依存から先に探す
+	named, err := lookupNamedType([]*packages.Package{proj.RootPkg}, input.TypeName)
+	if err != nil {
+		named, err = lookupNamedType(proj.Pkgs, input.TypeName)
+	}
+	if err != nil {
+		return err
+	}
+	candidates := proj.FunctionCache.Candidates(named)
+
+	trees, diags, err := proj.AnalyzeRoots()
+	if err != nil {
+		return err
+	}
+	reportDiagnostics(diags)
+	if input.Root != "" {
+		trees, err = selectRoot(trees, input.Root)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "Type: %s\n", named.String())
+
+	fmt.Fprintln(w, "\nProvider:")
+	selected := 0
+	for _, c := range candidates {
+		if c.Selected() {
+			fmt.Fprintf(w, "  %s (%s)\n", candidateName(c), relPosition(c))
+			selected++
+		}
+	}
+	if selected == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+
+	fmt.Fprintln(w, "\nPaths:")
+	paths := providerPaths(trees, named.String())
+	for _, path := range paths {
+		fmt.Fprintf(w, "  %s\n", strings.Join(path, " -> "))
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(w, "  (not reachable from any root)")
+	}
+
+	fmt.Fprintln(w, "\nCandidates:")
+	for _, c := range candidates {
+		status := "selected"
+		if !c.Selected() {
+			status = fmt.Sprintf("rejected (%s: %s)", c.Reason, c.Detail)
+		}
+		fmt.Fprintf(w, "  %s (%s): %s\n", candidateName(c), relPosition(c), status)
+	}
+	if len(candidates) == 0 {
+		fmt.Fprintln(w, "  (no function returns this type)")
+	}
+	return nil
+}
+
+// lookupNamedType はロードしたパッケージ（依存を含む）から型を探す。
+// "github.com/acme/db.DB" のような完全な型名か、"db.DB" のようにパッケージ名で修飾した型名を受け付ける
+func lookupNamedType(pkgs []*packages.Package, typeName string) (*types.Named, error) {
+	name := strings.TrimPrefix(typeName, "*")
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		return nil, fmt.Errorf("type name must be qualified with its package: %s", typeName)
+	}
+	qualifier, objName := name[:dot], name[dot+1:]
+
+	found := make([]*types.Named, 0)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil || (pkg.PkgPath != qualifier && (strings.Contains(qualifier, "/") || pkg.Name != qualifier)) {
+			return
+		}
+		if tn, ok := pkg.Types.Scope().Lookup(objName).(*types.TypeName); ok {
+			if named, ok := tn.Type().(*types.Named); ok {
+				found = append(found, named)
+			}
+		}
+	})
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("type not found: %s", typeName)
+	case 1:
+		return found[0], nil
+	}
+	matches := make([]string, 0, len(found))
+	for _, named := range found {
+		matches = append(matches, named.String())
+	}
+	return nil, fmt.Errorf("type name %s is ambiguous: %s", typeName, strings.Join(matches, ", "))
+}
+
+func selectRoot(trees analyze.RootTrees, name string) (analyze.RootTrees, error) {
+	for _, tree := range trees {
+		if tree.Name == name {
+			return analyze.RootTrees{tree}, nil
+		}
+	}
+	return nil, fmt.Errorf("root struct not found: %s", name)
+}
+
+// providerPaths はルート構造体から typeName を返すプロバイダまでの全ての経路を返す
+func providerPaths(trees analyze.RootTrees, typeName string) [][]string {
+	paths := make([][]string, 0)
+	var walk func(node *analyze.FnDITreeNode, path []string)
+	walk = func(node *analyze.FnDITreeNode, path []string) {
+		path = append(path[:len(path):len(path)], node.PkgName+"."+node.Name)
+		for _, ret := range node.ReturnTypes {
+			if strings.TrimPrefix(ret, "*") == typeName {
+				paths = append(paths, path)
+				return
+			}
+		}
+		for _, child := range node.Childs {
+			walk(child, path)
+		}
+	}
+	for _, root := range trees {
+		for _, tree := range root.Trees {
+			walk(tree, []string{root.Name})
+		}
+	}
+	return paths
+}
+
+func candidateName(c *analyze.Candidate) string {
+	return c.Func.Pkg().Name() + "." + c.Func.Name()
+}
+
+// relPosition は候補の定義位置をカレントディレクトリからの相対パスで返す
+func relPosition(c *analyze.Candidate) string {
+	return fmt.Sprintf("%s:%d", relPath(c.Position.Filename), c.Position.Line)
+}
+
+// relPath はファイルのパスをカレントディレクトリからの相対パスに変換する。変換できない場合はそのまま返す
+func relPath(filename string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, filename); err == nil {
+			return rel
+		}
+	}
+	return filename
+}
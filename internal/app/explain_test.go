@@ -0,0 +1,85 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       ExplainInput
+		wantErr     bool
+		wantContain []string
+		wantMissing []string
+	}{
+		{
+			name: "ルート構造体を指定した経路",
+			input: ExplainInput{
+				FilePath: "../../sample/complex/cire.go",
+				TypeName: "repository.UserRepository",
+				Root:     "OrderApp",
+			},
+			wantContain: []string{
+				"Type: github.com/rmocchy/cire/sample/complex/repository.UserRepository",
+				"repository.NewUserRepository (",
+				"user_repository.go:12)",
+				"OrderApp -> handler.NewOrderHandler -> service.NewOrderService -> repository.NewUserRepository",
+				": selected",
+			},
+			wantMissing: []string{"UserApp ->"},
+		},
+		{
+			name: "複数の候補がある型",
+			input: ExplainInput{
+				FilePath: "../../sample/duplicate/cire.go",
+				TypeName: "service.UserService",
+			},
+			wantContain: []string{
+				"(none)",
+				"service.NewAltUserService (",
+				"rejected (ambiguity: multiple providers return this type)",
+			},
+		},
+		{
+			name: "存在しないルート構造体",
+			input: ExplainInput{
+				FilePath: "../../sample/complex/cire.go",
+				TypeName: "repository.UserRepository",
+				Root:     "Unknown",
+			},
+			wantErr: true,
+		},
+		{
+			name: "存在しない型",
+			input: ExplainInput{
+				FilePath: "../../sample/complex/cire.go",
+				TypeName: "repository.Unknown",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := explain(&buf, &tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("explain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			output := buf.String()
+			for _, want := range tt.wantContain {
+				if !strings.Contains(output, want) {
+					t.Errorf("explain() 出力に %q が含まれていません\n出力:\n%s", want, output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(output, missing) {
+					t.Errorf("explain() 出力に %q が含まれています\n出力:\n%s", missing, output)
+				}
+			}
+		})
+	}
+}
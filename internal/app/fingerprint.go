@@ -0,0 +1,75 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/file"
+	"golang.org/x/tools/go/packages"
+)
+
+// inputFingerprint は生成結果に影響する入力（ルート構造体のファイル、プロバイダを宣言したファイル、メインモジュールの go.mod、
+// 設定ファイル、テンプレート）の内容と、プロバイダのパッケージパス・名前からハッシュを求める。
+// パッケージパスも含めるのは、同じ内容のファイルの移動やモジュール名の変更でも生成する import が変わるため
+func inputFingerprint(inputPath string, pkgs []*packages.Package, overlay map[string][]byte, nodes []*analyze.FnDITreeNode, extra ...[]byte) (string, error) {
+	absInput, err := filepath.Abs(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve input path: %w", err)
+	}
+	files := []string{absInput}
+	byPath := make(map[string]*packages.Package)
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		byPath[p.PkgPath] = p
+		if p.Module != nil && p.Module.Main && p.Module.GoMod != "" {
+			files = append(files, p.Module.GoMod)
+		}
+	})
+	idents := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ident := fmt.Sprintf("%s %s.%s", node.Kind, node.PkgPath, node.Name)
+		if node.Source != nil {
+			ident += fmt.Sprintf(" %s.%s", node.Source.PkgPath, node.Source.Name)
+		}
+		idents = append(idents, ident)
+	}
+	slices.Sort(idents)
+	idents = slices.Compact(idents)
+	for _, node := range nodes {
+		pkg, ok := byPath[node.PkgPath]
+		if !ok || pkg.Types == nil {
+			continue
+		}
+		obj := pkg.Types.Scope().Lookup(node.Name)
+		if obj == nil {
+			continue
+		}
+		if filename := pkg.Fset.Position(obj.Pos()).Filename; filename != "" {
+			files = append(files, filename)
+		}
+	}
+	slices.Sort(files)
+	files = slices.Compact(files)
+
+	h := sha256.New()
+	for _, f := range files {
+		content, err := file.ReadFile(f, overlay)
+		if err != nil {
+			return "", fmt.Errorf("failed to read input file: %w", err)
+		}
+		// ファイルの区切りが内容の一部と混同されないよう長さを前置する
+		fmt.Fprintf(h, "%d\n", len(content))
+		h.Write(content)
+	}
+	for _, ident := range idents {
+		fmt.Fprintf(h, "%d\n%s", len(ident), ident)
+	}
+	for _, e := range extra {
+		fmt.Fprintf(h, "%d\n", len(e))
+		h.Write(e)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
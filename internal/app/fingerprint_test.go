@@ -0,0 +1,27 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/rmocchy/cire/internal/analyze"
+)
+
+func TestInputFingerprint_PackagePaths(t *testing.T) {
+	// ファイルの内容が同じでも、プロバイダのパッケージパスが変われば生成する import が変わるためハッシュも変わる
+	const input = "testdata/profile/cire.go"
+	hash := func(pkgPath string) string {
+		t.Helper()
+		nodes := []*analyze.FnDITreeNode{{Name: "NewUserRepository", PkgPath: pkgPath, Kind: analyze.ProviderFunc}}
+		got, err := inputFingerprint(input, nil, nil, nodes)
+		if err != nil {
+			t.Fatalf("inputFingerprint() error = %v", err)
+		}
+		return got
+	}
+	if hash("example.com/app/repository") == hash("example.com/app/internal/repository") {
+		t.Error("hash does not change when the provider package moves")
+	}
+	if hash("example.com/app/repository") != hash("example.com/app/repository") {
+		t.Error("hash is not stable")
+	}
+}
@@ -1,103 +1,614 @@
 package app
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"go/token"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/config"
+	"github.com/rmocchy/cire/internal/diag"
 	"github.com/rmocchy/cire/internal/file"
 	"github.com/rmocchy/cire/internal/generate"
+	"github.com/rmocchy/cire/internal/report"
+	"github.com/rmocchy/cire/internal/version"
 )
 
 type GenerateInput struct {
 	FilePath string
 	GenJson  bool
+	// ConfigPath は明示的に指定された設定ファイルのパス。空の場合は入力ファイルから探索する
+	ConfigPath string
+	// TemplatePath はユーザー定義の生成テンプレートのパス。空の場合は組み込みのテンプレートを使う
+	TemplatePath string
+	// Command は生成ファイルのヘッダーに記録するコマンドライン
+	Command string
+	// OverlayPath は go コマンドの -overlay と同じ形式のオーバーレイファイル（"-" は標準入力）。空の場合はディスク上の内容だけを使う
+	OverlayPath string
+	// Check が true の場合はファイルを書き込まず、既存のファイルが最新かどうかを確認する
+	Check bool
+	// Format は診断の出力形式（"text", "json" または "sarif"）
+	Format string
+	// 以下は CLI フラグで明示的に指定された値。指定された場合は設定ファイルの値を上書きする
+	Output    string
+	BuildTags []string
+	Patterns  []string
+	Exclude   []string
+	Backend   string
+	// SharedSets は複数のルート構造体が使うプロバイダを共通のセットにまとめるかどうか（shared_sets を true で上書きする）
+	SharedSets bool
+	// PackageSets はパッケージごとの公開のプロバイダセットを生成するかどうか（package_sets を true で上書きする）
+	PackageSets bool
+	// Profiles は解析するプロファイル（//cire:profile）。指定した場合はプロファイルごとに
+	// ビルドタグ付きのファイル（wire_dev.go など）を生成し、wire.go は生成しない
+	Profiles []string
+	// Stdout と Stderr は生成結果のメッセージと診断の出力先。nil の場合は os.Stdout と os.Stderr を使う
+	Stdout io.Writer
+	Stderr io.Writer
 }
 
-func RunGenerate(input *GenerateInput) error {
-	dir := filepath.Dir(input.FilePath)
-	outputPath := filepath.Join(dir, "wire.go")
+func (input *GenerateInput) overrides() *ConfigOverrides {
+	return &ConfigOverrides{
+		WireOutput:  input.Output,
+		BuildTags:   input.BuildTags,
+		Patterns:    input.Patterns,
+		Exclude:     input.Exclude,
+		Backend:     input.Backend,
+		SharedSets:  input.SharedSets,
+		PackageSets: input.PackageSets,
+	}
+}
 
-	pkgs, err := file.LoadAllPkgsFromPath(input.FilePath)
+func RunGenerate(input *GenerateInput) error {
+	if err := diag.CheckFormat(input.Format); err != nil {
+		return err
+	}
+	results, err := buildGenerateResults(input)
 	if err != nil {
 		return err
 	}
 
-	structs, err := file.LoadNamedStructs(input.FilePath, pkgs)
-	if err != nil {
+	// テキスト形式の診断は標準エラー出力に書き出す。
+	// JSON や SARIF の場合はリダイレクトして取り込めるよう診断を標準出力に書き出し、生成結果のメッセージを標準エラー出力に回す
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if input.Stdout != nil {
+		stdout = input.Stdout
+	}
+	if input.Stderr != nil {
+		stderr = input.Stderr
+	}
+	status, diagOut := stdout, stderr
+	if input.Format == diag.FormatJSON || input.Format == diag.FormatSARIF {
+		status, diagOut = stderr, stdout
+	}
+
+	// 診断は全てのプロファイルの分をまとめて一つの出力にする
+	diags := make(diag.List, 0)
+	failed := false
+	for _, result := range results {
+		if input.GenJson || result.Diagnostics.HasErrors() {
+			if err := report.Write(result.JSONPath, result.Report); err != nil {
+				return err
+			}
+			fmt.Fprintf(status, "JSON file generated: %s\n", result.JSONPath)
+		}
+		diags = append(diags, result.Diagnostics...)
+		failed = failed || result.Diagnostics.HasErrors()
+	}
+	if err := writeDiagnostics(diagOut, diags, input.Format, sourceRoot(input.FilePath)); err != nil {
 		return err
 	}
+	if failed {
+		return withExitCode(ExitAnalysisFailed, fmt.Errorf("analysis failed for one or more structs"))
+	}
+
+	for _, result := range results {
+		if err := result.output(status, input.Check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// output は生成結果を出力先に書き込む。check が true の場合は書き込まずに既存のファイルが最新かどうかを確認する
+func (result *generateResult) output(status io.Writer, check bool) error {
+	if check {
+		if err := checkOutput(status, result.OutputPath, result.Wire, result.InputHash, result.Overlay); err != nil {
+			return err
+		}
+		for _, set := range result.PackageSets {
+			if err := checkOutput(status, set.OutputPath, set.Source, result.InputHash, result.Overlay); err != nil {
+				return err
+			}
+		}
+		if result.TestWire == nil {
+			return nil
+		}
+		return checkOutput(status, result.TestOutputPath, result.TestWire, result.TestInputHash, result.Overlay)
+	}
 
-	// キャッシュの準備
-	fnCache := analyze.NewFunctionCache(pkgs)
-	anCache := analyze.NewAnalysisCache()
-	analyzer := analyze.NewAnalyze(fnCache, anCache)
+	if err := writeOutput(status, result.OutputPath, result.Wire, result.Overlay); err != nil {
+		return err
+	}
+	for _, set := range result.PackageSets {
+		if err := writeOutput(status, set.OutputPath, set.Source, result.Overlay); err != nil {
+			return err
+		}
+	}
+	if result.TestWire == nil {
+		return nil
+	}
+	return writeOutput(status, result.TestOutputPath, result.TestWire, result.Overlay)
+}
 
-	// コード生成の準備
-	config := &generate.GenerateConfig{}
-	usePkgName, err := file.ExtractPackageName(input.FilePath)
+// writeOutput は既存のファイルとマージした生成結果を出力先に書き込む
+func writeOutput(status io.Writer, outputPath string, generated []byte, overlay map[string][]byte) error {
+	// 既存ファイルがある場合は利用者が書いた内容を引き継ぐ
+	output, err := mergeExisting(status, outputPath, generated, overlay)
 	if err != nil {
 		return err
 	}
-	config.SetPackageName(*usePkgName)
 
-	validationErrors := make([]error, 0)
-	mergedTree := make(map[string][]*analyze.FnDITreeNode, 0)
-	// 構造体ごとに解析実行
-	for _, s := range structs {
-		trees, err := analyzer.ExecuteFromStruct(s)
+	// 結果の出力
+	if err := os.WriteFile(outputPath, output, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(outputPath), err)
+	}
+
+	fmt.Fprintf(status, "Wire file generated: %s\n", outputPath)
+	return nil
+}
+
+// generateResult はファイルへ書き出す前の生成結果
+type generateResult struct {
+	OutputPath string
+	JSONPath   string
+	// Wire は生成した wire.go の内容。error の診断がある場合は nil
+	Wire []byte
+	// Report は解析レポート。Diagnostics も含む
+	Report      *report.Report
+	Diagnostics diag.List
+	// InputHash は解析した入力のハッシュ
+	InputHash string
+	// TestOutputPath はテスト用のインジェクタの出力先
+	TestOutputPath string
+	// TestWire は生成したテスト用のインジェクタのファイルの内容。差し替えが無い場合や error の診断がある場合は nil
+	TestWire []byte
+	// TestInputHash は差し替えた関数を含めて求めた入力のハッシュ
+	TestInputHash string
+	// PackageSets はパッケージごとの公開のプロバイダセットのファイル（package_sets）。ヘッダーには InputHash を記録する
+	PackageSets []*packageSetOutput
+	// Overlay は既存の生成ファイルを読む際にも使うオーバーレイ（ステージされた wire.go の --check など）
+	Overlay map[string][]byte
+}
+
+// buildGenerateResult はプロファイルを指定せずに、解析からコード生成までをファイルに書き出さずに実行する
+func buildGenerateResult(input *GenerateInput) (*generateResult, error) {
+	proj, opts, err := loadGenerateProject(input)
+	if err != nil {
+		return nil, err
+	}
+	return newGenerateResult(input.FilePath, proj, opts)
+}
+
+// buildGenerateResults は --profile で指定されたプロファイルごとに（指定が無い場合は一度）、
+// 解析からコード生成までをファイルに書き出さずに実行する。パッケージのロードは全てのプロファイルで共有する
+func buildGenerateResults(input *GenerateInput) ([]*generateResult, error) {
+	for _, profile := range input.Profiles {
+		if err := config.ValidateProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	proj, opts, err := loadGenerateProject(input)
+	if err != nil {
+		return nil, err
+	}
+	// providers_gen.go はビルドタグを持たないため、プロファイルごとに内容を変えられない
+	if proj.Config.PackageSets && len(input.Profiles) > 0 {
+		return nil, errors.New("package_sets cannot be used with --profile")
+	}
+	if len(input.Profiles) == 0 {
+		result, err := newGenerateResult(input.FilePath, proj, opts)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		mergedTree[s.Obj().Name()] = trees
-		converter := analyze.NewConvertTreeToUniqueList()
-		for _, tree := range trees {
-			converter.Execute(tree)
+		return []*generateResult{result}, nil
+	}
+	// プロファイルのないインジェクタのファイルも wireinject だけで有効になるため、wire gen -tags <profile> で
+	// プロファイルのファイルと一緒に読まれ、どのプロファイルの wire_gen.go にも同じインジェクタが生成されてしまう
+	base := config.ResolveOutput(input.FilePath, proj.Config.Output.Wire)
+	if _, err := os.Stat(base); err == nil {
+		return nil, fmt.Errorf("%s must be removed before generating profile files: it is also built with every profile tag", base)
+	}
+
+	// 綴りの誤りで全てのプロファイルに限定されたプロバイダが使われなくなるのを防ぐ
+	declared := analyze.DeclaredProfiles(proj.Pkgs)
+	results := make([]*generateResult, 0, len(input.Profiles))
+	// 宣言名に付けるプロファイル名（キー）と、そのプロファイル
+	suffixes := make(map[string]string, len(input.Profiles))
+	for i, profile := range input.Profiles {
+		if slices.Contains(input.Profiles[:i], profile) {
+			continue
+		}
+		if !slices.Contains(declared, profile) {
+			return nil, fmt.Errorf("profile %q is not declared by any %s annotation (declared: %s)", profile, analyze.ProfileDirective, strings.Join(declared, ", "))
+		}
+		// wire gen の出力はビルドタグを持たないため、プロファイルごとの宣言名は重ならないようにする
+		suffix := config.ProfileSuffix(profile)
+		if suffix == "" {
+			return nil, fmt.Errorf("profile %q cannot be used in declaration names", profile)
 		}
+		if other, ok := suffixes[suffix]; ok {
+			return nil, fmt.Errorf("profiles %q and %q produce the same declaration names (%s)", other, profile, suffix)
+		}
+		suffixes[suffix] = profile
+		result, err := newGenerateResult(input.FilePath, proj.ForProfile(profile), opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
 
-		// 依存関係から生成可能かどうかをチェック
-		if err := analyze.IsDepTreeSatisfiable(converter.List()); err != nil {
-			validationErrors = append(validationErrors, fmt.Errorf("dependency tree is not satisfiable for struct %s: %w", s.Obj().Name(), err))
+// loadGenerateProject は設定・オーバーレイ・テンプレートを読み、パッケージをロードする
+func loadGenerateProject(input *GenerateInput) (*Project, *WireOptions, error) {
+	cfg, err := LoadConfig(input.FilePath, input.ConfigPath, input.overrides())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	overlay, err := loadOverlay(input.OverlayPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	proj, err := LoadProjectWithOverlay(input.FilePath, cfg, overlay)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := &WireOptions{Command: input.Command}
+	if input.TemplatePath != "" {
+		text, err := os.ReadFile(input.TemplatePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		opts.Template = string(text)
+	}
+	return proj, opts, nil
+}
+
+// newGenerateResult はプロジェクトを解析し、生成ファイルと解析レポートを作る。
+// プロファイルを解析する場合は出力先のファイル名にプロファイル名を加える
+func newGenerateResult(inputPath string, proj *Project, opts *WireOptions) (*generateResult, error) {
+	analysis, err := Analyze(proj)
+	if err != nil {
+		return nil, err
+	}
+	cfg := proj.Config
+	output := func(path string) string {
+		if proj.Profile != "" {
+			path = config.ProfileOutput(path, proj.Profile)
 		}
+		return config.ResolveOutput(inputPath, path)
+	}
+	result := &generateResult{
+		OutputPath:     output(cfg.Output.Wire),
+		JSONPath:       output(cfg.Output.JSON),
+		TestOutputPath: output(cfg.Output.TestWire),
+		Diagnostics:    analysis.Diagnostics,
+		Overlay:        proj.Overlay,
+	}
+	result.Report = report.Build(&report.Source{
+		InputPath:   inputPath,
+		BaseDir:     filepath.Dir(result.JSONPath),
+		PackageName: analysis.PackageName,
+		PackagePath: proj.RootPkg.PkgPath,
+		Structs:     proj.Structs,
+		Trees:       analysis.Trees,
+		Pkgs:        proj.Pkgs,
+		Diagnostics: result.Diagnostics,
+		Version:     version.Version(),
+	})
+	if result.Diagnostics.HasErrors() {
+		return result, nil
+	}
+
+	result.Wire, result.InputHash, err = analysis.Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.PackageSets, err = analysis.GeneratePackageSets(opts); err != nil {
+		return nil, err
+	}
+	result.TestWire, result.TestInputHash, err = analysis.GenerateTest(opts)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-		providers := make([]generate.Provider, 0, len(converter.List()))
-		for _, node := range converter.List() {
-			providers = append(providers, generate.Provider{
-				PkgPath: node.PkgPath,
-				Name:    fmt.Sprintf("%s.%s", file.PkgNameFromPath(node.PkgPath), node.Name),
-			})
+// Analysis はプロジェクトのルート構造体の解析結果
+type Analysis struct {
+	Project *Project
+	// PackageName は生成ファイルのパッケージ名（入力ファイルの package 句）
+	PackageName string
+	Trees       analyze.RootTrees
+	// TestTrees は差し替え（//cire:override または設定ファイルの overrides）を使って解析し直した結果。
+	// 差し替えた関数を使うルート構造体だけを含み、差し替えが無い場合は空
+	TestTrees analyze.RootTrees
+	// Diagnostics は解析で見つかった全ての問題。error の診断がある場合はコードを生成できない
+	Diagnostics diag.List
+}
+
+// WireOptions はコード生成の設定のうち、設定ファイルに含まれないもの
+type WireOptions struct {
+	// Template は WireData を受け取る text/template のテキスト。空の場合は組み込みのテンプレートを使う
+	Template string
+	// Command は生成ファイルのヘッダーに記録する再生成用のコマンドライン
+	Command string
+}
+
+// Analyze はプロジェクトの全てのルート構造体を解析し、生成ファイルの import の循環も検査する
+func Analyze(proj *Project) (*Analysis, error) {
+	pkgName, err := file.ExtractPackageName(proj.InputPath, proj.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	trees, diags, err := proj.AnalyzeRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成ファイルが import するパッケージから生成ファイルのパッケージが import されていないかをチェック
+	providerPkgPaths := make([]string, 0)
+	for _, node := range treeNodes(trees) {
+		providerPkgPaths = append(providerPkgPaths, node.PkgPath)
+		if node.Source != nil {
+			providerPkgPaths = append(providerPkgPaths, node.Source.PkgPath)
+		}
+	}
+	if err := analyze.DetectImportCycle(proj.Pkgs, proj.RootPkg.PkgPath, providerPkgPaths); err != nil {
+		pos := token.Position{Filename: proj.InputPath}
+		if abs, err := filepath.Abs(proj.InputPath); err == nil {
+			pos.Filename = abs
 		}
-		config.AddStructSet(s.Obj().Name(), providers)
+		diags = append(diags, diag.Errorf(diag.ImportCycle, pos, "%v", err))
+	}
+	if proj.Config.PackageSets {
+		diags = append(diags, proj.checkPackageSets(trees)...)
 	}
 
-	if input.GenJson || len(validationErrors) > 0 {
-		jsonConfig := &analyze.JsonConfig{
-			Dir:  dir,
-			Data: mergedTree,
+	// テスト用のインジェクタのために、差し替えを使って解析し直す
+	overrides, overrideDiags := proj.collectOverrides()
+	diags = append(diags, overrideDiags...)
+	var testTrees analyze.RootTrees
+	if len(overrides) > 0 {
+		var checkDiags diag.List
+		overrides, checkDiags = checkOverrides(overrides, trees)
+		diags = append(diags, checkDiags...)
+	}
+	if len(overrides) > 0 {
+		var testDiags diag.List
+		if testTrees, testDiags, err = proj.analyzeWithOverrides(overrides); err != nil {
+			return nil, err
 		}
-		if err := analyze.WriteOnJsonFile(jsonConfig); err != nil {
-			return err
+		// 差し替えていない部分の問題は通常の解析と重複するため除く
+		reported := make(map[string]bool, len(diags))
+		for _, d := range diags {
+			reported[d.Error()] = true
+		}
+		for _, d := range testDiags {
+			if !reported[d.Error()] {
+				reported[d.Error()] = true
+				diags = append(diags, d)
+			}
 		}
 	}
-	if len(validationErrors) > 0 {
-		for _, verr := range validationErrors {
-			fmt.Fprintf(os.Stderr, "Validation error: %v\n", verr)
+	if proj.Profile != "" {
+		for _, d := range diags {
+			d.Profile = proj.Profile
 		}
-		return fmt.Errorf("validation failed for one or more structs")
+	}
+	return &Analysis{Project: proj, PackageName: *pkgName, Trees: trees, TestTrees: testTrees, Diagnostics: diags}, nil
+}
+
+// Generate は解析結果から生成ファイルの内容と、ヘッダーに記録した入力のハッシュを返す
+func (a *Analysis) Generate(opts *WireOptions) ([]byte, string, error) {
+	return a.generate(opts, a.Trees, false)
+}
+
+// GenerateTest は差し替えを使った解析結果からテスト用のインジェクタのファイルの内容と、ヘッダーに記録した入力のハッシュを返す。
+// 差し替えを使うルート構造体が無い場合は nil を返す。テスト用のインジェクタには常に組み込みのテンプレートを使う
+func (a *Analysis) GenerateTest(opts *WireOptions) ([]byte, string, error) {
+	if len(a.TestTrees) == 0 {
+		return nil, "", nil
+	}
+	testOpts := &WireOptions{}
+	if opts != nil {
+		testOpts.Command = opts.Command
+	}
+	return a.generate(testOpts, a.TestTrees, true)
+}
+
+func (a *Analysis) generate(opts *WireOptions, trees analyze.RootTrees, test bool) ([]byte, string, error) {
+	genConfig, err := a.generateConfig(opts, trees, test)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// コード生成
-	formatted, err := config.Generate()
+	code, err := genConfig.Generate()
+	if err != nil {
+		return nil, "", err
+	}
+	return code, genConfig.Generator.InputHash, nil
+}
+
+// generateConfig は解析結果からコード生成の設定を作り、入力のハッシュを求める。
+// テスト用のインジェクタでは差し替えと重複しないよう、パッケージごとの公開のプロバイダセットを使わない
+func (a *Analysis) generateConfig(opts *WireOptions, trees analyze.RootTrees, test bool) (*generate.GenerateConfig, error) {
+	if a.Diagnostics.HasErrors() {
+		return nil, fmt.Errorf("cannot generate code: analysis reported %d problem(s)", len(a.Diagnostics))
+	}
+	if opts == nil {
+		opts = &WireOptions{}
+	}
+	cfg := a.Project.Config
+	genConfig := &generate.GenerateConfig{Template: opts.Template, Test: test, Profile: a.Project.Profile, SharedSets: cfg.SharedSets}
+	// テスト用のインジェクタを生成する場合、wire gen -tags ciretest で通常のインジェクタが二重に生成されないようにする
+	genConfig.WithTest = !test && len(a.TestTrees) > 0
+	if cfg.PackageSets && !test {
+		for _, pkg := range a.Project.packageSetPkgs(trees) {
+			genConfig.PackageSets = append(genConfig.PackageSets, pkg.PkgPath)
+		}
+	}
+	genConfig.SetPackageName(a.PackageName)
+	genConfig.SetPackagePath(a.Project.RootPkg.PkgPath)
+	setNameOf, injectorNameOf := cfg.SetName, cfg.InjectorName
+	if test {
+		setNameOf, injectorNameOf = cfg.TestSetName, cfg.TestInjectorName
+	}
+
+	// プロファイルごとのファイルでは、ルート構造体名にプロファイル名を付けて命名する（例: AppDevSet, InitializeAppDev）。
+	// wire gen の出力はビルドタグを持たず全てのプロファイルの出力が一緒にビルドされるため、名前が重なってはいけない
+	nameRoot := func(root string) string { return root }
+	if a.Project.Profile != "" {
+		suffix := config.ProfileSuffix(a.Project.Profile)
+		nameRoot = func(root string) string { return root + suffix }
+	}
+
+	// ルート構造体ごとに生成するプロバイダを集める（ソースコード上の定義順）
+	for _, root := range trees {
+		nodes := treeNodes(analyze.RootTrees{root})
+		providers := make([]generate.Provider, 0, len(nodes))
+		for _, node := range nodes {
+			providers = append(providers, generateProvider(node))
+		}
+		setName, err := setNameOf(nameRoot(root.Name))
+		if err != nil {
+			return nil, err
+		}
+		injectorName, err := injectorNameOf(nameRoot(root.Name))
+		if err != nil {
+			return nil, err
+		}
+		genConfig.AddStructSet(generate.StructSet{
+			RootStructName: root.Name,
+			SetName:        setName,
+			InjectorName:   injectorName,
+			Providers:      providers,
+		})
+	}
+
+	configContent := []byte{}
+	if cfg.Path != "" {
+		var err error
+		if configContent, err = os.ReadFile(cfg.Path); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+	inputHash, err := inputFingerprint(a.Project.InputPath, a.Project.Pkgs, a.Project.Overlay, treeNodes(trees), configContent, []byte(genConfig.Template))
+	if err != nil {
+		return nil, err
+	}
+	genConfig.Generator = generate.GeneratorData{
+		Version:   version.Version(),
+		Command:   opts.Command,
+		InputHash: inputHash,
+	}
+	return genConfig, nil
+}
+
+// generateProvider は解析結果のノードを生成コードから参照するプロバイダに変換する
+func generateProvider(node *analyze.FnDITreeNode) generate.Provider {
+	p := generate.Provider{
+		PkgPath: node.PkgPath,
+		PkgName: node.PkgName,
+		Name:    node.Name,
+		Kind:    generate.ProviderKind(node.Kind),
+		Field:   node.Field,
+	}
+	if node.Kind == analyze.ProviderField {
+		// フィールドのノードの名前は「構造体名.フィールド名」のため、構造体を Source として参照する
+		p.Name = node.Source.Name
+	}
+	if node.Source != nil {
+		p.Source = &generate.TypeRef{
+			PkgPath: node.Source.PkgPath,
+			PkgName: node.Source.PkgName,
+			Name:    node.Source.Name,
+			Pointer: node.Source.Pointer,
+		}
+	}
+	return p
+}
+
+// treeNodes はルート構造体ごとに重複を除いたプロバイダを、ルート構造体の定義順に並べて返す
+func treeNodes(trees analyze.RootTrees) []*analyze.FnDITreeNode {
+	nodes := make([]*analyze.FnDITreeNode, 0)
+	for _, root := range trees {
+		converter := analyze.NewConvertTreeToUniqueList()
+		for _, tree := range root.Trees {
+			converter.Execute(tree)
+		}
+		nodes = append(nodes, converter.List()...)
+	}
+	return nodes
+}
+
+// checkOutput は出力先のファイルが生成結果に対して最新かどうかを確認する。
+// 最新でない場合は、入力が変わったのか手で編集されたのかを区別してエラーを返す
+func checkOutput(status io.Writer, outputPath string, generated []byte, inputHash string, overlay map[string][]byte) error {
+	existing, err := file.ReadFile(outputPath, overlay)
+	if errors.Is(err, fs.ErrNotExist) {
+		return withExitCode(ExitStaleOutput, fmt.Errorf("%s does not exist; run cire generate", outputPath))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	merged, err := generate.Merge(existing, generated)
 	if err != nil {
 		return err
 	}
+	if bytes.Equal(generate.StripVolatileHeader(merged.Source), generate.StripVolatileHeader(existing)) {
+		fmt.Fprintf(status, "Wire file is up to date: %s\n", outputPath)
+		return nil
+	}
+	if generate.ParseHeader(existing).InputHash != inputHash {
+		return withExitCode(ExitStaleOutput, fmt.Errorf("%s is stale: inputs changed since it was generated; run cire generate", outputPath))
+	}
+	return withExitCode(ExitStaleOutput, fmt.Errorf("%s was edited by hand: generated content differs although the inputs are unchanged", outputPath))
+}
 
-	// 結果の出力
-	if err := os.WriteFile(outputPath, formatted, 0644); err != nil {
-		return fmt.Errorf("failed to write wire.go: %w", err)
+// mergeExisting は出力先に既存のファイルがある場合、利用者の宣言やプロバイダ式を保ったまま生成結果とマージする。
+// cire が所有しない内容を失う場合は、上書き前に既存ファイルのバックアップ（<出力先>.bak）を作成し、status に知らせる
+func mergeExisting(status io.Writer, outputPath string, generated []byte, overlay map[string][]byte) ([]byte, error) {
+	existing, err := file.ReadFile(outputPath, overlay)
+	if errors.Is(err, fs.ErrNotExist) {
+		return generated, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read existing file: %w", err)
 	}
 
-	fmt.Printf("Wire file generated: %s\n", outputPath)
-	return nil
+	merged, err := generate.Merge(existing, generated)
+	if err != nil {
+		return nil, err
+	}
+	if merged.NeedsBackup {
+		backupPath := outputPath + ".bak"
+		if err := os.WriteFile(backupPath, existing, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write backup file: %w", err)
+		}
+		fmt.Fprintf(status, "Existing file contains content not managed by cire; backup written: %s\n", backupPath)
+	}
+	return merged.Source, nil
 }
@@ -0,0 +1,447 @@
+package app
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/internal/diag"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// assertGolden は got を testdata/golden/<name> の内容と比較する。-update 指定時はファイルを更新する
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("output does not match %s\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
+func TestBuildGenerateResult_Golden(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		wantErr  bool
+	}{
+		{name: "basic", filePath: "../../sample/basic/cire.go"},
+		{name: "complex", filePath: "../../sample/complex/cire.go"},
+		{name: "duplicate", filePath: "../../sample/duplicate/cire.go", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 複数回実行しても同じ出力になることを確認する
+			var first *generateResult
+			for i := 0; i < 2; i++ {
+				result, err := buildGenerateResult(&GenerateInput{FilePath: tt.filePath})
+				if err != nil {
+					t.Fatalf("buildGenerateResult() error = %v", err)
+				}
+				if result.Diagnostics.HasErrors() != tt.wantErr {
+					t.Fatalf("Diagnostics = %v, wantErr %v", result.Diagnostics, tt.wantErr)
+				}
+				if first == nil {
+					first = result
+					continue
+				}
+				if string(result.Wire) != string(first.Wire) {
+					t.Fatalf("wire output differs between runs")
+				}
+			}
+
+			data, err := first.Report.Marshal()
+			if err != nil {
+				t.Fatalf("failed to marshal report: %v", err)
+			}
+			assertGolden(t, tt.name+"/dep_tree.json.golden", data)
+
+			if tt.wantErr {
+				var buf bytes.Buffer
+				if err := diag.WriteText(&buf, relativeDiagnostics(first.Diagnostics)); err != nil {
+					t.Fatalf("failed to write diagnostics: %v", err)
+				}
+				assertGolden(t, tt.name+"/errors.golden", buf.Bytes())
+				return
+			}
+			assertGolden(t, tt.name+"/wire.go.golden", first.Wire)
+		})
+	}
+}
+
+func TestBuildGenerateResult_Overlay(t *testing.T) {
+	// ルート構造体の名前を変えた保存されていない内容を、go コマンドと同じ形式のオーバーレイファイルで渡す
+	dir := t.TempDir()
+	replacement := filepath.Join(dir, "cire.go")
+	src := `package main
+
+import "github.com/rmocchy/cire/sample/basic/handler"
+
+type Server struct {
+	handler *handler.UserHandler
+}
+`
+	if err := os.WriteFile(replacement, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overlayPath := filepath.Join(dir, "overlay.json")
+	overlayJSON := `{"Replace": {"../../sample/basic/cire.go": "` + filepath.ToSlash(replacement) + `"}}`
+	if err := os.WriteFile(overlayPath, []byte(overlayJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := buildGenerateResult(&GenerateInput{FilePath: "../../sample/basic/cire.go", OverlayPath: overlayPath})
+	if err != nil {
+		t.Fatalf("buildGenerateResult() error = %v", err)
+	}
+	if result.Diagnostics.HasErrors() {
+		t.Fatalf("Diagnostics = %v", result.Diagnostics)
+	}
+	if !bytes.Contains(result.Wire, []byte("func InitializeServer(")) || bytes.Contains(result.Wire, []byte("App")) {
+		t.Errorf("wire output does not use the overlay:\n%s", result.Wire)
+	}
+
+	onDisk, err := buildGenerateResult(&GenerateInput{FilePath: "../../sample/basic/cire.go"})
+	if err != nil {
+		t.Fatalf("buildGenerateResult() error = %v", err)
+	}
+	if onDisk.InputHash == result.InputHash {
+		t.Errorf("input hash does not reflect the overlay: %s", result.InputHash)
+	}
+}
+
+func TestBuildGenerateResult_Override(t *testing.T) {
+	const input = "testdata/override/cire.go"
+	result, err := buildGenerateResult(&GenerateInput{FilePath: input})
+	if err != nil {
+		t.Fatalf("buildGenerateResult() error = %v", err)
+	}
+	if len(result.Diagnostics) > 0 {
+		t.Fatalf("Diagnostics = %v", result.Diagnostics)
+	}
+	if bytes.Contains(result.Wire, []byte("newFakeUserRepository")) {
+		t.Errorf("wire.go uses the override:\n%s", result.Wire)
+	}
+	// wire gen -tags ciretest で通常のインジェクタが一緒に読まれないようにする
+	if !bytes.Contains(result.Wire, []byte("//go:build wireinject && !ciretest\n")) {
+		t.Errorf("wire.go is not excluded from the ciretest build:\n%s", result.Wire)
+	}
+	if filepath.Base(result.TestOutputPath) != "wire_ciretest.go" {
+		t.Errorf("TestOutputPath = %s", result.TestOutputPath)
+	}
+	assertGolden(t, "override/wire_ciretest.go.golden", result.TestWire)
+
+	// 差し替える関数のファイルの内容ごとに CIRE008 の問題を確認する
+	tests := []struct {
+		name string
+		// file はオーバーレイで置き換える（または加える）ファイル。空の場合は override.go
+		file string
+		src  string
+		want string
+		// line は問題の行。0 の場合は関数の宣言の行
+		line int
+	}{
+		{
+			name: "置き換えるプロバイダと返り値の型が異なる",
+			src: `//cire:override repository.Config
+func newConfig() repository.Config { return repository.Config{} }`,
+			want: "newConfig returns github.com/rmocchy/cire/sample/basic/repository.Config, but the replaced provider repository.NewConfig returns *github.com/rmocchy/cire/sample/basic/repository.Config",
+		},
+		{
+			name: "どのルート構造体も必要としない型",
+			src: `//cire:override repository.User
+func newUser() *repository.User { return nil }`,
+			want: "newUser overrides github.com/rmocchy/cire/sample/basic/repository.User, but no root struct requires it",
+		},
+		{
+			name: "差し替える型を返さない関数",
+			src: `//cire:override repository.UserRepository
+func newConfig() *repository.Config { return nil }`,
+			want: "invalid override: override.newConfig does not return github.com/rmocchy/cire/sample/basic/repository.UserRepository",
+		},
+		{
+			name: "wire が読まないテストファイルの差し替え",
+			file: "fake_test.go",
+			src: `//cire:override repository.Config
+func newConfig() *repository.Config { return nil }`,
+			want: "//cire:override in a test file cannot be used",
+			line: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, line := tt.file, tt.line
+			if file == "" {
+				file = "override.go"
+			}
+			if line == 0 {
+				line = 6
+			}
+			dir := t.TempDir()
+			replacement := filepath.Join(dir, file)
+			src := "package override\n\nimport \"github.com/rmocchy/cire/sample/basic/repository\"\n\n" + tt.src + "\n"
+			if err := os.WriteFile(replacement, []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			overlayPath := filepath.Join(dir, "overlay.json")
+			overlayJSON := `{"Replace": {"testdata/override/` + file + `": "` + filepath.ToSlash(replacement) + `"}}`
+			if err := os.WriteFile(overlayPath, []byte(overlayJSON), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := buildGenerateResult(&GenerateInput{FilePath: input, OverlayPath: overlayPath})
+			if err != nil {
+				t.Fatalf("buildGenerateResult() error = %v", err)
+			}
+			if len(result.Diagnostics) != 1 {
+				t.Fatalf("Diagnostics = %v, want exactly one", result.Diagnostics)
+			}
+			d := result.Diagnostics[0]
+			if d.Code != diag.InvalidOverride || !strings.Contains(d.Message, tt.want) {
+				t.Errorf("diagnostic = %v, want %s containing %q", d, diag.InvalidOverride, tt.want)
+			}
+			if filepath.Base(d.Position.Filename) != file || d.Position.Line != line {
+				t.Errorf("diagnostic position = %v", d.Position)
+			}
+		})
+	}
+}
+
+func TestGenerate_OverrideRunsWire(t *testing.T) {
+	// wire はモジュール内のパッケージしか読めないため、testdata の下に一時的なパッケージを作る
+	dir, err := os.MkdirTemp("testdata", "wire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for _, name := range []string{"cire.go", "override.go"} {
+		src, err := os.ReadFile(filepath.Join("testdata", "override", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := buildGenerateResult(&GenerateInput{FilePath: filepath.Join(dir, "cire.go")})
+	if err != nil {
+		t.Fatalf("buildGenerateResult() error = %v", err)
+	}
+	if len(result.Diagnostics) > 0 {
+		t.Fatalf("Diagnostics = %v", result.Diagnostics)
+	}
+	if err := result.output(io.Discard, false); err != nil {
+		t.Fatalf("output() error = %v", err)
+	}
+
+	// 通常のインジェクタとテスト用のインジェクタを README の手順で生成し、両方の出力が一緒にビルドできることを確かめる
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	run("tool", "wire", "gen", ".")
+	run("tool", "wire", "gen", "-tags", "ciretest", "-output_file_prefix", "ciretest_", ".")
+	run("vet", ".")
+
+	gen, err := os.ReadFile(filepath.Join(dir, "wire_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGen, err := os.ReadFile(filepath.Join(dir, "ciretest_wire_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(gen, []byte("InitializeAppForTest")) || bytes.Contains(gen, []byte("newFakeUserRepository")) {
+		t.Errorf("wire_gen.go contains the test injector:\n%s", gen)
+	}
+	if !bytes.Contains(testGen, []byte("func InitializeAppForTest()")) || !bytes.Contains(testGen, []byte("newFakeUserRepository()")) {
+		t.Errorf("ciretest_wire_gen.go does not use the override:\n%s", testGen)
+	}
+	if bytes.Contains(testGen, []byte("func InitializeApp()")) {
+		t.Errorf("ciretest_wire_gen.go duplicates the injector of wire.go:\n%s", testGen)
+	}
+}
+
+func TestBuildGenerateResult_OverrideConfig(t *testing.T) {
+	// 設定ファイルでアノテーションと同じ型を差し替えると、後から見つかったアノテーションが重複として報告される
+	configPath := filepath.Join(t.TempDir(), "cire.yaml")
+	content := "overrides:\n  - type: repository.UserRepository\n    provider: override.newFakeUserRepository\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := buildGenerateResult(&GenerateInput{FilePath: "testdata/override/cire.go", ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("buildGenerateResult() error = %v", err)
+	}
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("Diagnostics = %v, want exactly one", result.Diagnostics)
+	}
+	d := result.Diagnostics[0]
+	if d.Code != diag.InvalidOverride || d.Message != "github.com/rmocchy/cire/sample/basic/repository.UserRepository is overridden more than once" {
+		t.Errorf("diagnostic = %v", d)
+	}
+	if filepath.Base(d.Position.Filename) != "override.go" {
+		t.Errorf("diagnostic position = %v", d.Position)
+	}
+}
+
+func TestBuildGenerateResults_Profiles(t *testing.T) {
+	const input = "testdata/profile/cire.go"
+	results, err := buildGenerateResults(&GenerateInput{FilePath: input, Profiles: []string{"dev", "prod", "staging", "dev"}})
+	if err != nil {
+		t.Fatalf("buildGenerateResults() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d, want 3", len(results))
+	}
+
+	dev, prod, staging := results[0], results[1], results[2]
+	if len(dev.Diagnostics) > 0 || len(prod.Diagnostics) > 0 {
+		t.Fatalf("Diagnostics = %v, %v", dev.Diagnostics, prod.Diagnostics)
+	}
+	if filepath.Base(dev.OutputPath) != "wire_dev.go" || filepath.Base(prod.OutputPath) != "wire_prod.go" {
+		t.Errorf("OutputPath = %s, %s", dev.OutputPath, prod.OutputPath)
+	}
+	assertGolden(t, "profile/wire_dev.go.golden", dev.Wire)
+	if !bytes.Contains(prod.Wire, []byte("NewSESMailer")) || !bytes.Contains(prod.Wire, []byte("NewRedisCache")) {
+		t.Errorf("prod wire does not use the prod providers:\n%s", prod.Wire)
+	}
+	// wire gen の出力はビルドタグを持たないため、プロファイルごとに宣言名を変える
+	if !bytes.Contains(prod.Wire, []byte("func InitializeAppProd()")) || !bytes.Contains(prod.Wire, []byte("var AppProdSet = ")) {
+		t.Errorf("prod wire does not use the prod names:\n%s", prod.Wire)
+	}
+
+	// staging にはキャッシュのプロバイダが無い
+	if len(staging.Diagnostics) != 1 {
+		t.Fatalf("staging Diagnostics = %v, want exactly one", staging.Diagnostics)
+	}
+	d := staging.Diagnostics[0]
+	if d.Code != diag.MissingProvider || d.Profile != "staging" || !strings.Contains(d.Error(), "profile staging: no provider found for github.com/rmocchy/cire/internal/app/testdata/profile.Cache") {
+		t.Errorf("staging diagnostic = %v", d)
+	}
+	if filepath.Base(staging.JSONPath) != "dep_tree_staging.json" {
+		t.Errorf("staging JSONPath = %s", staging.JSONPath)
+	}
+}
+
+func TestBuildGenerateResults_ProfileErrors(t *testing.T) {
+	const input = "testdata/profile/cire.go"
+	// プロファイルを指定しない場合はプロファイルに限定されたプロバイダを使わない
+	result, err := buildGenerateResult(&GenerateInput{FilePath: input})
+	if err != nil {
+		t.Fatalf("buildGenerateResult() error = %v", err)
+	}
+	if len(result.Diagnostics) != 2 || result.Diagnostics[0].Profile != "" {
+		t.Fatalf("Diagnostics = %v, want two without a profile", result.Diagnostics)
+	}
+	var buf bytes.Buffer
+	if err := diag.WriteText(&buf, result.Diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "profile.NewFakeMailer was rejected (profile: only in profile dev, staging)") {
+		t.Errorf("diagnostics do not explain the profile:\n%s", buf.String())
+	}
+
+	for _, profiles := range [][]string{{"qa"}, {"dev prod"}} {
+		if _, err := buildGenerateResults(&GenerateInput{FilePath: input, Profiles: profiles}); err == nil {
+			t.Errorf("buildGenerateResults(%q) error = nil, want error", profiles)
+		}
+	}
+
+	// プロファイルの無い wire.go が残っていると、どのプロファイルのビルドにも含まれてしまう
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "wire.go")
+	if err := os.WriteFile(stale, []byte("//go:build wireinject\n\npackage profile\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "cire.yaml")
+	if err := os.WriteFile(configPath, []byte("output:\n  wire: "+filepath.ToSlash(stale)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = buildGenerateResults(&GenerateInput{FilePath: input, ConfigPath: configPath, Profiles: []string{"dev"}})
+	if err == nil || !strings.Contains(err.Error(), stale+" must be removed") {
+		t.Errorf("buildGenerateResults() with a stale %s error = %v", stale, err)
+	}
+}
+
+func TestBuildGenerateResult_PackageSets(t *testing.T) {
+	const input = "../../sample/complex/cire.go"
+	result, err := buildGenerateResult(&GenerateInput{FilePath: input, PackageSets: true})
+	if err != nil {
+		t.Fatalf("buildGenerateResult() error = %v", err)
+	}
+	if len(result.Diagnostics) > 0 {
+		t.Fatalf("Diagnostics = %v", result.Diagnostics)
+	}
+	assertGolden(t, "package_sets/wire.go.golden", result.Wire)
+
+	// プロバイダを持つパッケージごとに、パッケージのディレクトリへ出力する
+	dir, err := filepath.Abs(filepath.Dir(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"handler", "repository", "service"}
+	if len(result.PackageSets) != len(want) {
+		t.Fatalf("len(PackageSets) = %d, want %d", len(result.PackageSets), len(want))
+	}
+	for i, set := range result.PackageSets {
+		rel, err := filepath.Rel(dir, set.OutputPath)
+		if err != nil || rel != filepath.Join(want[i], "providers_gen.go") {
+			t.Errorf("OutputPath = %s, want %s", set.OutputPath, filepath.Join(dir, want[i], "providers_gen.go"))
+		}
+		if !strings.Contains(string(set.Source), "// inputs: "+result.InputHash+"\n") {
+			t.Errorf("%s does not record the input hash of wire.go", rel)
+		}
+	}
+	assertGolden(t, "package_sets/repository/providers_gen.go.golden", result.PackageSets[1].Source)
+
+	// providers_gen.go はビルドタグを持たないため、プロファイルごとの生成とは併用できない
+	if _, err := buildGenerateResults(&GenerateInput{FilePath: "testdata/profile/cire.go", PackageSets: true, Profiles: []string{"dev"}}); err == nil {
+		t.Error("buildGenerateResults() error = nil, want error for package sets with profiles")
+	}
+}
+
+func TestWriteOutput_BackupNoticeGoesToStatus(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "wire.go")
+	if err := os.WriteFile(outputPath, []byte("not a go file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var status bytes.Buffer
+	if err := writeOutput(&status, outputPath, []byte("package main\n"), nil); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	if want := "backup written: " + outputPath + ".bak"; !strings.Contains(status.String(), want) {
+		t.Errorf("status = %q, want it to contain %q", status.String(), want)
+	}
+	if backup, err := os.ReadFile(outputPath + ".bak"); err != nil || string(backup) != "not a go file" {
+		t.Errorf("backup = %q, %v; want the existing content", backup, err)
+	}
+}
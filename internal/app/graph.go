@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rmocchy/cire/internal/graph"
+)
+
+type GraphInput struct {
+	FilePath   string
+	ConfigPath string
+	// OverlayPath は go コマンドの -overlay と同じ形式のオーバーレイファイル（"-" は標準入力）
+	OverlayPath string
+	// Format は出力形式（"dot" または "mermaid"）
+	Format string
+	// CollapseByPackage はノードをパッケージ単位に集約するかどうか
+	CollapseByPackage bool
+	// Focus は指定された場合、その型を供給するプロバイダ以下のサブグラフだけを出力する
+	Focus string
+	// OutputPath は出力先のファイル。空の場合は標準出力に出力する
+	OutputPath string
+}
+
+// RunGraph はルート構造体ごとのプロバイダグラフを DOT または Mermaid 形式で出力する
+func RunGraph(input *GraphInput) error {
+	cfg, err := LoadConfig(input.FilePath, input.ConfigPath, nil)
+	if err != nil {
+		return err
+	}
+	overlay, err := loadOverlay(input.OverlayPath)
+	if err != nil {
+		return err
+	}
+	proj, err := LoadProjectWithOverlay(input.FilePath, cfg, overlay)
+	if err != nil {
+		return err
+	}
+	trees, diags, err := proj.AnalyzeRoots()
+	if err != nil {
+		return err
+	}
+	reportDiagnostics(diags)
+
+	g := graph.Build(trees)
+	if input.Focus != "" {
+		if g, err = g.Focus(input.Focus); err != nil {
+			return err
+		}
+	}
+	if input.CollapseByPackage {
+		g = g.CollapseByPackage()
+	}
+
+	var w io.Writer = os.Stdout
+	if input.OutputPath != "" {
+		f, err := os.Create(input.OutputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	return graph.Render(w, g, input.Format)
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/config"
+	"github.com/rmocchy/cire/internal/file"
+	"github.com/rmocchy/cire/internal/generate"
+	"github.com/rmocchy/cire/internal/graph"
+	"golang.org/x/tools/go/packages"
+)
+
+// InitFileName は cire init が生成するファイル名
+const InitFileName = "cire.go"
+
+type InitInput struct {
+	// Dir は cire.go を作成するディレクトリ
+	Dir        string
+	ConfigPath string
+	// RootName はルート構造体名
+	RootName string
+	// Filter は指定された場合、これらのパッケージパターンに一致するパッケージの型だけを候補にする
+	Filter []string
+	// Force が true の場合は既存の cire.go を上書きする
+	Force bool
+}
+
+// rootCandidate はルート構造体のフィールドとして提案する型
+type rootCandidate struct {
+	Field       generate.RootField
+	Constructor *analyze.Candidate
+	// Providers は同じ型を返す公開コンストラクタの数
+	Providers int
+}
+
+// RunInit はモジュールを解析してルート構造体のフィールドを提案し、cire.go を作成する
+func RunInit(input *InitInput) error {
+	return runInit(os.Stdout, input)
+}
+
+func runInit(w io.Writer, input *InitInput) error {
+	filePath := filepath.Join(input.Dir, InitFileName)
+	if _, err := os.Stat(filePath); err == nil && !input.Force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", filePath)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check %s: %w", filePath, err)
+	}
+
+	cfg, err := LoadConfig(filePath, input.ConfigPath, nil)
+	if err != nil {
+		return err
+	}
+	// 作成先のディレクトリにはまだ Go ファイルが無い場合があるため、ディレクトリ自体はロード対象に加えない
+	pkgs, err := file.LoadAllPkgsFromPath(filePath, &file.LoadOptions{
+		Patterns:     cfg.Patterns,
+		BuildFlags:   cfg.BuildFlags(),
+		OmitInputDir: true,
+	})
+	if err != nil {
+		return err
+	}
+	pkgName, pkgPath, err := targetPackage(input.Dir, pkgs)
+	if err != nil {
+		return err
+	}
+
+	modulePaths := make(map[string]string)
+	for _, pkg := range pkgs {
+		if pkg.Module != nil {
+			modulePaths[pkg.PkgPath] = pkg.Module.Path
+		}
+	}
+	candidates := proposeRootFields(analyze.NewFunctionCache(filterExcluded(pkgs, cfg), pkgPath), input.Filter, modulePaths)
+	if len(candidates) == 0 {
+		return fmt.Errorf("no root field candidates found")
+	}
+
+	rootName := input.RootName
+	if rootName == "" {
+		rootName = "App"
+	}
+	root := &generate.RootFile{
+		PackageName: pkgName,
+		PackagePath: pkgPath,
+		BuildTags:   cfg.BuildTags,
+		StructName:  rootName,
+	}
+	fmt.Fprintf(w, "Proposed fields for %s:\n", rootName)
+	for _, c := range candidates {
+		root.Fields = append(root.Fields, c.Field)
+		reason := "no other provider consumes it"
+		if c.Providers > 1 {
+			reason += fmt.Sprintf("; warning: %d providers return this type", c.Providers)
+		}
+		fmt.Fprintf(w, "  %s %s: provided by %s.%s (%s), %s\n",
+			c.Field.Name, graph.ShortType(c.Constructor.Func.Signature().Results().At(0).Type().String()), c.Constructor.Func.Pkg().Name(), c.Constructor.Func.Name(),
+			relPosition(c.Constructor), reason)
+	}
+
+	src, err := root.Generate()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filePath, src, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	fmt.Fprintf(w, "Root file generated: %s\n", filePath)
+	return nil
+}
+
+// targetPackage は dir のパッケージ名とパスを返す。dir にまだパッケージが無い場合は main パッケージとみなす
+func targetPackage(dir string, pkgs []*packages.Package) (string, string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	var module *packages.Module
+	for _, pkg := range pkgs {
+		for _, f := range pkg.GoFiles {
+			if filepath.Dir(f) == absDir {
+				return pkg.Name, pkg.PkgPath, nil
+			}
+		}
+		if module == nil && pkg.Module != nil {
+			module = pkg.Module
+		}
+	}
+	if module == nil {
+		return "", "", fmt.Errorf("failed to find the module containing %s", dir)
+	}
+	rel, err := filepath.Rel(module.Dir, absDir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", "", fmt.Errorf("%s is outside of module %s", dir, module.Path)
+	}
+	if rel == "." {
+		return "main", module.Path, nil
+	}
+	return "main", module.Path + "/" + filepath.ToSlash(rel), nil
+}
+
+// proposeRootFields はどのコンストラクタの引数にも使われていない型を、コンストラクタの順に返す。
+// modulePaths はパッケージパスからモジュールパスへの対応で、filter の照合に使う
+func proposeRootFields(functionCache analyze.FunctionCache, filter []string, modulePaths map[string]string) []rootCandidate {
+	constructors := functionCache.Constructors()
+	consumed := make(map[*types.Named]bool)
+	providers := make(map[*types.Named]int)
+	for _, c := range constructors {
+		providers[c.Type]++
+		params := c.Func.Signature().Params()
+		for i := 0; i < params.Len(); i++ {
+			if named, ok := analyze.Deref(params.At(i).Type()).(*types.Named); ok {
+				consumed[named] = true
+			}
+		}
+	}
+
+	candidates := make([]rootCandidate, 0)
+	proposed := make(map[*types.Named]bool)
+	usedNames := make(map[string]bool)
+	for _, c := range constructors {
+		if consumed[c.Type] || proposed[c.Type] {
+			continue
+		}
+		obj := c.Type.Obj()
+		if len(filter) > 0 && !config.MatchPackage(filter, obj.Pkg().Path(), modulePaths[obj.Pkg().Path()]) {
+			continue
+		}
+		proposed[c.Type] = true
+
+		name := obj.Name()
+		if usedNames[name] {
+			name = exportedName(obj.Pkg().Name()) + name
+		}
+		usedNames[name] = true
+		_, pointer := c.Func.Signature().Results().At(0).Type().(*types.Pointer)
+		candidates = append(candidates, rootCandidate{
+			Field: generate.RootField{
+				Name:     name,
+				PkgPath:  obj.Pkg().Path(),
+				PkgName:  obj.Pkg().Name(),
+				TypeName: obj.Name(),
+				Pointer:  pointer,
+			},
+			Constructor: c,
+			Providers:   providers[c.Type],
+		})
+	}
+	return candidates
+}
+
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	// 作成先はモジュール内である必要があるため testdata の下に一時ディレクトリを作る
+	dir, err := os.MkdirTemp("testdata", "init")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	var buf bytes.Buffer
+	input := &InitInput{Dir: dir, RootName: "API", Filter: []string{"./sample/complex/..."}}
+	if err := runInit(&buf, input); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, InitFileName))
+	if err != nil {
+		t.Fatalf("failed to read generated file: %v", err)
+	}
+	for _, want := range []string{
+		"//go:build cire",
+		"package main",
+		`"github.com/rmocchy/cire/sample/complex/handler"`,
+		"type API struct {",
+		"OrderHandler   *handler.OrderHandler",
+		"UserHandler    *handler.UserHandler",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("生成ファイルに %q が含まれていません\n出力:\n%s", want, src)
+		}
+	}
+	// 他のプロバイダの引数に使われている型は提案しない
+	if strings.Contains(string(src), "service.") {
+		t.Errorf("他のプロバイダが使う型が提案されています\n出力:\n%s", src)
+	}
+	if !strings.Contains(buf.String(), "provided by handler.NewOrderHandler") {
+		t.Errorf("選んだ理由が出力されていません\n出力:\n%s", buf.String())
+	}
+
+	// 既存のファイルは --force なしでは上書きしない
+	if err := runInit(&buf, input); err == nil {
+		t.Error("既存の cire.go があるのにエラーになりませんでした")
+	}
+}
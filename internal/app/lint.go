@@ -0,0 +1,143 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/diag"
+	"github.com/rmocchy/cire/internal/file"
+)
+
+type LintInput struct {
+	// FilePaths はルート構造体を定義したファイル。最初のファイルを基準に設定ファイルとパッケージをロードする
+	FilePaths  []string
+	ConfigPath string
+	// OverlayPath は go コマンドの -overlay と同じ形式のオーバーレイファイル（"-" は標準入力）
+	OverlayPath string
+	// Format は診断の出力形式（"text", "json" または "sarif"）
+	Format string
+	// FailOn は終了コードを 0 以外にする診断の重大度（"error" または "warning"）。空の場合は "error"
+	FailOn string
+	// 以下は CLI フラグで明示的に指定された値。指定された場合は設定ファイルの値を上書きする
+	Patterns []string
+	Exclude  []string
+}
+
+// RunLint は到達しないコンストラクタと複数のプロバイダの候補がある型を標準出力に報告する。
+// 解析の問題も合わせて報告し、FailOn 以上の重大度の問題が見つかった場合はエラーを返す
+func RunLint(input *LintInput) error {
+	if err := diag.CheckFormat(input.Format); err != nil {
+		return err
+	}
+	if err := checkFailOn(input.FailOn); err != nil {
+		return err
+	}
+	diags, err := lint(input)
+	if err != nil {
+		return err
+	}
+	return reportLintDiagnostics(os.Stdout, diags, input.Format, sourceRoot(input.FilePaths[0]), input.FailOn)
+}
+
+func lint(input *LintInput) (diag.List, error) {
+	if len(input.FilePaths) == 0 {
+		return nil, fmt.Errorf("no input file specified")
+	}
+	filePath := input.FilePaths[0]
+	cfg, err := LoadConfig(filePath, input.ConfigPath, &ConfigOverrides{Patterns: input.Patterns, Exclude: input.Exclude})
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := loadOverlay(input.OverlayPath)
+	if err != nil {
+		return nil, err
+	}
+	proj, err := LoadProjectWithOverlay(filePath, cfg, overlay)
+	if err != nil {
+		return nil, err
+	}
+	// 2つ目以降のファイルのルート構造体も到達範囲に含める
+	for _, path := range input.FilePaths[1:] {
+		structs, err := file.LoadNamedStructs(path, proj.Pkgs)
+		if err != nil {
+			return nil, withExitCode(ExitLoadFailed, err)
+		}
+		proj.Structs = append(proj.Structs, structs...)
+	}
+
+	constructors, err := cfg.ConstructorRegexp()
+	if err != nil {
+		return nil, err
+	}
+	trees, diags, err := proj.AnalyzeRoots()
+	if err != nil {
+		return nil, err
+	}
+	return append(diags, analyze.Lint(proj.FunctionCache, trees, constructors)...), nil
+}
+
+func reportLintDiagnostics(w io.Writer, diags diag.List, format, root, failOn string) error {
+	if err := writeDiagnostics(w, diags, format, root); err != nil {
+		return err
+	}
+	// warning だけの場合は、--fail-on=warning が指定されたときだけ失敗にする
+	if diags.HasErrors() || (failOn == string(diag.SeverityWarning) && len(diags) > 0) {
+		return withExitCode(ExitAnalysisFailed, fmt.Errorf("found %d problem(s)", len(diags)))
+	}
+	return nil
+}
+
+// checkFailOn は --fail-on の値が対応している重大度かを確認する。空の場合は error として扱う
+func checkFailOn(failOn string) error {
+	switch failOn {
+	case string(diag.SeverityError), string(diag.SeverityWarning), "":
+		return nil
+	}
+	return fmt.Errorf("unsupported --fail-on value: %q (expected %s or %s)", failOn, diag.SeverityError, diag.SeverityWarning)
+}
+
+// writeDiagnostics は診断を指定された形式で出力する。
+// SARIF の位置は root からの相対パスにし、それ以外の形式はカレントディレクトリからの相対パスにする
+func writeDiagnostics(w io.Writer, diags diag.List, format, root string) error {
+	if format == diag.FormatSARIF {
+		return diag.WriteSARIFWithRoot(w, diags, root)
+	}
+	return diag.Write(w, relativeDiagnostics(diags), format)
+}
+
+// sourceRoot は SARIF の %SRCROOT% とする入力ファイルのモジュールルートを返す。見つからない場合はカレントディレクトリ
+func sourceRoot(inputPath string) string {
+	if dir, err := filepath.Abs(filepath.Dir(inputPath)); err == nil {
+		if root := file.FindModuleRoot(dir); root != "" {
+			return root
+		}
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// relativeDiagnostics は診断の位置をカレントディレクトリからの相対パスにした複製を返す
+func relativeDiagnostics(diags diag.List) diag.List {
+	result := make(diag.List, 0, len(diags))
+	for _, d := range diags {
+		c := *d
+		c.Position.Filename = relPathOrEmpty(c.Position.Filename)
+		c.Related = make([]diag.Related, 0, len(d.Related))
+		for _, r := range d.Related {
+			r.Position.Filename = relPathOrEmpty(r.Position.Filename)
+			c.Related = append(c.Related, r)
+		}
+		result = append(result, &c)
+	}
+	return result
+}
+
+func relPathOrEmpty(path string) string {
+	if path == "" {
+		return ""
+	}
+	return relPath(path)
+}
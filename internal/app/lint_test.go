@@ -0,0 +1,108 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/token"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/internal/diag"
+)
+
+func TestReportLintDiagnostics_SARIFFromSubdirectory(t *testing.T) {
+	moduleRoot, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir("../../sample/duplicate")
+
+	diags, err := lint(&LintInput{FilePaths: []string{"cire.go"}})
+	if err != nil {
+		t.Fatalf("lint() error = %v", err)
+	}
+	var buf bytes.Buffer
+	// 問題が見つかった場合のエラーは出力の確認に関係しない
+	_ = reportLintDiagnostics(&buf, diags, "sarif", sourceRoot("cire.go"), "")
+
+	var got struct {
+		Runs []struct {
+			OriginalURIBaseIDs map[string]struct {
+				URI string `json:"uri"`
+			} `json:"originalUriBaseIds"`
+			Results []struct {
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	run := got.Runs[0]
+	if base := run.OriginalURIBaseIDs["%SRCROOT%"].URI; base != "file://"+filepath.ToSlash(moduleRoot)+"/" {
+		t.Errorf("originalUriBaseIds[%%SRCROOT%%] = %q, want module root %s", base, moduleRoot)
+	}
+
+	uris := make([]string, 0)
+	for _, r := range run.Results {
+		for _, loc := range r.Locations {
+			a := loc.PhysicalLocation.ArtifactLocation
+			if a.URIBaseID != "%SRCROOT%" || strings.HasPrefix(a.URI, "../") {
+				t.Errorf("location %+v is not relative to the module root", a)
+			}
+			uris = append(uris, a.URI)
+		}
+	}
+	if !slices.Contains(uris, "sample/duplicate/handler/user_handler.go") {
+		t.Errorf("uris = %v, want sample/duplicate/handler/user_handler.go", uris)
+	}
+}
+
+func TestReportLintDiagnostics_FailOn(t *testing.T) {
+	warning := diag.Warningf(diag.UnusedConstructor, token.Position{}, "logger.NewLogger is not reachable from any root")
+	failure := diag.Errorf(diag.MissingProvider, token.Position{}, "no provider found for Cache")
+	tests := []struct {
+		name     string
+		diags    diag.List
+		failOn   string
+		wantFail bool
+	}{
+		{name: "warning だけ", diags: diag.List{warning}},
+		{name: "warning だけで --fail-on=warning", diags: diag.List{warning}, failOn: "warning", wantFail: true},
+		{name: "error を含む", diags: diag.List{warning, failure}, failOn: "error", wantFail: true},
+		{name: "問題なしで --fail-on=warning", diags: diag.List{}, failOn: "warning"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reportLintDiagnostics(io.Discard, tt.diags, "text", "", tt.failOn)
+			if !tt.wantFail {
+				if err != nil {
+					t.Errorf("reportLintDiagnostics() error = %v, want nil", err)
+				}
+				return
+			}
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) || exitErr.Code != ExitAnalysisFailed {
+				t.Errorf("reportLintDiagnostics() error = %v, want exit code %d", err, ExitAnalysisFailed)
+			}
+		})
+	}
+}
+
+func TestRunLint_InvalidFailOn(t *testing.T) {
+	if err := RunLint(&LintInput{FilePaths: []string{"cire.go"}, FailOn: "info"}); err == nil {
+		t.Error("RunLint() error = nil, want error")
+	}
+}
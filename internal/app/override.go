@@ -0,0 +1,201 @@
+package app
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/diag"
+	"github.com/rmocchy/cire/internal/file"
+	"golang.org/x/tools/go/packages"
+)
+
+// override は差し替えと、それを宣言した位置（アノテーションを付けた関数、または設定ファイル）
+type override struct {
+	*analyze.Override
+	Position token.Position
+}
+
+// collectOverrides は設定ファイルの overrides と入力ファイルのパッケージのアノテーションから差し替えを集める。
+// 型や関数が見つからない、同じ型を二度差し替える、関数がプロバイダの形でない、
+// アノテーションが wire の読まないテストファイルにある場合は CIRE008 として報告する
+func (p *Project) collectOverrides() ([]*override, diag.List) {
+	overrides := make([]*override, 0)
+	diags := make(diag.List, 0)
+	seen := make(map[string]bool)
+	add := func(o *override) {
+		key := o.Type.String()
+		if seen[key] {
+			diags = append(diags, diag.Errorf(diag.InvalidOverride, o.Position, "%s is overridden more than once", key))
+			return
+		}
+		if err := analyze.CheckOverride(o.Override); err != nil {
+			diags = append(diags, diag.Errorf(diag.InvalidOverride, o.Position, "invalid override: %v", err))
+			return
+		}
+		seen[key] = true
+		overrides = append(overrides, o)
+	}
+
+	if len(p.Config.Overrides) > 0 {
+		pos := token.Position{Filename: p.Config.Path}
+		if abs, err := filepath.Abs(p.Config.Path); err == nil && p.Config.Path != "" {
+			pos.Filename = abs
+		}
+		for i, entry := range p.Config.Overrides {
+			// explain と同じく、入力ファイルのパッケージから参照できる型を優先する
+			named, err := lookupNamedType([]*packages.Package{p.RootPkg}, entry.Type)
+			if err != nil {
+				named, err = lookupNamedType(p.Pkgs, entry.Type)
+			}
+			if err != nil {
+				diags = append(diags, diag.Errorf(diag.InvalidOverride, pos, "overrides[%d]: %v", i, err))
+				continue
+			}
+			fn, err := lookupFunc(p.Pkgs, entry.Provider)
+			if err != nil {
+				diags = append(diags, diag.Errorf(diag.InvalidOverride, pos, "overrides[%d]: %v", i, err))
+				continue
+			}
+			add(&override{Override: &analyze.Override{Type: named, Func: fn}, Position: pos})
+		}
+	}
+
+	for _, a := range analyze.FindOverrideAnnotations(p.RootPkg) {
+		pos := p.RootPkg.Fset.Position(a.Func.Pos())
+		if a.TypeName == "" {
+			diags = append(diags, diag.Errorf(diag.InvalidOverride, pos, "%s %s: the overridden type is missing", analyze.OverrideDirective, a.Func.Name()))
+			continue
+		}
+		named, err := lookupNamedType([]*packages.Package{p.RootPkg}, a.TypeName)
+		if err != nil {
+			diags = append(diags, diag.Errorf(diag.InvalidOverride, pos, "%s %s: %v", analyze.OverrideDirective, a.Func.Name(), err))
+			continue
+		}
+		add(&override{Override: &analyze.Override{Type: named, Func: a.Func}, Position: pos})
+	}
+
+	// wire はテストファイルを読まないため、テスト用のインジェクタからテストファイルの関数は参照できない
+	found, err := file.FindInTestFiles(filepath.Dir(p.InputPath), analyze.OverrideDirective, p.Overlay)
+	if err != nil {
+		diags = append(diags, diag.Errorf(diag.InvalidOverride, token.Position{Filename: p.InputPath}, "%v", err))
+	}
+	for _, pos := range found {
+		diags = append(diags, diag.Errorf(diag.InvalidOverride, pos,
+			"%s in a test file cannot be used: wire does not load test files, so declare the function in a non-test file", analyze.OverrideDirective))
+	}
+	return overrides, diags
+}
+
+// lookupFunc はロードしたパッケージ（依存を含む）からパッケージレベルの関数を探す。
+// "github.com/acme/fakes.NewDB" のような完全な名前か、"fakes.NewDB" のようにパッケージ名で修飾した名前を受け付ける。
+// 同じパスのパッケージは最初に見つかったものだけを探す
+func lookupFunc(pkgs []*packages.Package, funcName string) (*types.Func, error) {
+	dot := strings.LastIndex(funcName, ".")
+	if dot < 0 {
+		return nil, fmt.Errorf("function name must be qualified with its package: %s", funcName)
+	}
+	qualifier, objName := funcName[:dot], funcName[dot+1:]
+
+	found := make([]*types.Func, 0)
+	visited := make(map[string]bool)
+	packages.Visit(pkgs, func(pkg *packages.Package) bool {
+		if visited[pkg.PkgPath] {
+			return false
+		}
+		visited[pkg.PkgPath] = true
+		if pkg.Types == nil || (pkg.PkgPath != qualifier && (strings.Contains(qualifier, "/") || pkg.Name != qualifier)) {
+			return true
+		}
+		if fn, ok := pkg.Types.Scope().Lookup(objName).(*types.Func); ok {
+			found = append(found, fn)
+		}
+		return true
+	}, nil)
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("function not found: %s", funcName)
+	case 1:
+		return found[0], nil
+	}
+	matches := make([]string, 0, len(found))
+	for _, fn := range found {
+		matches = append(matches, fn.FullName())
+	}
+	return nil, fmt.Errorf("function name %s is ambiguous: %s", funcName, strings.Join(matches, ", "))
+}
+
+// checkOverrides は差し替えが通常の解析結果のプロバイダを置き換えることを検査する。
+// どのルート構造体も必要としない型の差し替えや、置き換えるプロバイダと返り値の型が異なる差し替えを CIRE008 として報告し、
+// 残りの差し替えを返す
+func checkOverrides(overrides []*override, trees analyze.RootTrees) ([]*override, diag.List) {
+	valid := make([]*override, 0, len(overrides))
+	diags := make(diag.List, 0)
+	nodes := treeNodes(trees)
+	for _, o := range overrides {
+		result := o.Func.Signature().Results().At(0).Type().String()
+		replaced, mismatched := false, false
+		for _, node := range nodes {
+			if len(node.ReturnTypes) == 0 || strings.TrimPrefix(node.ReturnTypes[0], "*") != o.Type.String() {
+				continue
+			}
+			replaced = true
+			if node.ReturnTypes[0] != result {
+				diags = append(diags, diag.Errorf(diag.InvalidOverride, o.Position,
+					"%s.%s returns %s, but the replaced provider %s.%s returns %s", o.Func.Pkg().Name(), o.Func.Name(), result, node.PkgName, node.Name, node.ReturnTypes[0]))
+				mismatched = true
+				break
+			}
+		}
+		if !replaced {
+			diags = append(diags, diag.Errorf(diag.InvalidOverride, o.Position,
+				"%s.%s overrides %s, but no root struct requires it", o.Func.Pkg().Name(), o.Func.Name(), o.Type.String()))
+		}
+		if replaced && !mismatched {
+			valid = append(valid, o)
+		}
+	}
+	return valid, diags
+}
+
+// analyzeWithOverrides は差し替えを最優先の候補にして全てのルート構造体を解析し直す。
+// 差し替えた関数を使うルート構造体の解析結果だけを返す
+func (p *Project) analyzeWithOverrides(overrides []*override) (analyze.RootTrees, diag.List, error) {
+	list := make([]*analyze.Override, 0, len(overrides))
+	for _, o := range overrides {
+		list = append(list, o.Override)
+	}
+	resolvers := append(analyze.DefaultResolvers(p.FunctionCache), p.Resolvers...)
+	resolvers = append(resolvers, analyze.NewOverrideResolver(list))
+	trees, diags, err := p.analyzeRoots(analyze.NewAnalyze(p.FunctionCache, analyze.NewAnalysisCache(), resolvers...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	used := make(analyze.RootTrees, 0, len(trees))
+	for _, root := range trees {
+		if usesOverride(root, list) {
+			used = append(used, root)
+		}
+	}
+	return used, diags, nil
+}
+
+// usesOverride はルート構造体の解析結果が差し替えた関数のいずれかを使うかどうかを返す
+func usesOverride(root analyze.RootTree, overrides []*analyze.Override) bool {
+	for _, node := range treeNodes(analyze.RootTrees{root}) {
+		if node.Kind != analyze.ProviderFunc {
+			continue
+		}
+		for _, o := range overrides {
+			if node.PkgPath == o.Func.Pkg().Path() && node.Name == o.Func.Name() {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"go/token"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/diag"
+	"github.com/rmocchy/cire/internal/generate"
+	"golang.org/x/tools/go/packages"
+)
+
+// packageSetOutput はパッケージの公開のプロバイダセットのファイルと出力先
+type packageSetOutput struct {
+	OutputPath string
+	Source     []byte
+}
+
+// nodeOwner はプロバイダを公開のプロバイダセットに置くパッケージのパスを返す。
+// wire.Bind は実装の型、wire.FieldsOf はフィールドを持つ構造体のパッケージに置く
+func nodeOwner(node *analyze.FnDITreeNode) string {
+	if (node.Kind == analyze.ProviderBind || node.Kind == analyze.ProviderField) && node.Source != nil {
+		return node.Source.PkgPath
+	}
+	return node.PkgPath
+}
+
+// packageSetPkgs は公開のプロバイダセットを生成するパッケージをパッケージパスの昇順に返す。
+// 解析結果のプロバイダを所有するパッケージのうち、入力ファイルのパッケージとメインモジュールの外のパッケージ（書き込めない依存モジュール）は除く
+func (p *Project) packageSetPkgs(trees analyze.RootTrees) []*packages.Package {
+	byPath := make(map[string]*packages.Package)
+	packages.Visit(p.Pkgs, nil, func(pkg *packages.Package) {
+		byPath[pkg.PkgPath] = pkg
+	})
+
+	owners := make(map[string]*packages.Package)
+	for _, node := range treeNodes(trees) {
+		pkg, ok := byPath[nodeOwner(node)]
+		if !ok || pkg.PkgPath == p.RootPkg.PkgPath || pkg.Module == nil || !pkg.Module.Main || len(pkg.GoFiles) == 0 {
+			continue
+		}
+		owners[pkg.PkgPath] = pkg
+	}
+	result := make([]*packages.Package, 0, len(owners))
+	for _, pkgPath := range slices.Sorted(maps.Keys(owners)) {
+		result = append(result, owners[pkgPath])
+	}
+	return result
+}
+
+// checkPackageSets は公開のプロバイダセットを置くパッケージが、セットの参照するパッケージ（wire.Bind のインターフェースのパッケージ）から
+// import されていないかを検査する。import されている場合はセットを置くと循環参照になるため CIRE004 として報告する
+func (p *Project) checkPackageSets(trees analyze.RootTrees) diag.List {
+	diags := make(diag.List, 0)
+	nodes := treeNodes(trees)
+	for _, pkg := range p.packageSetPkgs(trees) {
+		imports := make([]string, 0)
+		for _, node := range nodes {
+			if nodeOwner(node) == pkg.PkgPath {
+				imports = append(imports, node.PkgPath)
+			}
+		}
+		if err := analyze.DetectImportCycle(p.Pkgs, pkg.PkgPath, imports); err != nil {
+			pos := token.Position{Filename: filepath.Join(filepath.Dir(pkg.GoFiles[0]), p.Config.Output.Providers)}
+			diags = append(diags, diag.Errorf(diag.ImportCycle, pos, "%v", err))
+		}
+	}
+	return diags
+}
+
+// GeneratePackageSets は package_sets が有効な場合に、パッケージごとの公開のプロバイダセットのファイルと出力先を返す。
+// パッケージのディレクトリに出力し、ヘッダーには wire.go と同じ入力のハッシュを記録する
+func (a *Analysis) GeneratePackageSets(opts *WireOptions) ([]*packageSetOutput, error) {
+	if !a.Project.Config.PackageSets {
+		return nil, nil
+	}
+	pkgs := a.Project.packageSetPkgs(a.Trees)
+	outputs := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		outputPath := filepath.Join(filepath.Dir(pkg.GoFiles[0]), a.Project.Config.Output.Providers)
+		// 利用者が書いた同名の変数を生成ファイルで上書きしない
+		if obj := pkg.Types.Scope().Lookup(generate.PackageSetName); obj != nil && pkg.Fset.Position(obj.Pos()).Filename != outputPath {
+			return nil, fmt.Errorf("cannot generate %s: package %s already declares %s at %s", outputPath, pkg.PkgPath, generate.PackageSetName, pkg.Fset.Position(obj.Pos()))
+		}
+		outputs[pkg.PkgPath] = outputPath
+	}
+
+	genConfig, err := a.generateConfig(opts, a.Trees, false)
+	if err != nil {
+		return nil, err
+	}
+	files, err := genConfig.GeneratePackageSets()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*packageSetOutput, 0, len(files))
+	for _, f := range files {
+		result = append(result, &packageSetOutput{OutputPath: outputs[f.PkgPath], Source: f.Source})
+	}
+	return result, nil
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"errors"
+	"go/types"
+	"os"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/config"
+	"github.com/rmocchy/cire/internal/diag"
+	"github.com/rmocchy/cire/internal/file"
+	"golang.org/x/tools/go/packages"
+)
+
+// Project はロード済みのパッケージと解析対象のルート構造体
+type Project struct {
+	// InputPath はルート構造体を定義したファイル
+	InputPath string
+	Config    *config.Config
+	Pkgs      []*packages.Package
+	RootPkg   *packages.Package
+	// Structs はルート構造体（ソースコード上の定義順）
+	Structs       []*types.Named
+	FunctionCache analyze.FunctionCache
+	// Resolvers は組み込みのリゾルバに加えて使うリゾルバ
+	Resolvers []analyze.ProviderResolver
+	// Overlay は保存されていないファイルの内容（キーは絶対パス）。ロードと生成の両方でディスク上の内容の代わりに使う
+	Overlay map[string][]byte
+	// Profile は解析するプロファイル（//cire:profile）。空の場合はプロファイルに限定されたプロバイダを使わない
+	Profile string
+}
+
+// LoadProject は設定に従ってパッケージをロードし、入力ファイルのルート構造体を取り出す
+func LoadProject(filePath string, cfg *config.Config) (*Project, error) {
+	return LoadProjectWithOverlay(filePath, cfg, nil)
+}
+
+// LoadProjectWithOverlay は保存されていないファイルの内容（キーは絶対パス）をディスク上の内容の代わりに使って LoadProject を実行する
+func LoadProjectWithOverlay(filePath string, cfg *config.Config, overlay map[string][]byte) (*Project, error) {
+	pkgs, err := loadPackages(filePath, cfg, overlay)
+	if err != nil {
+		return nil, withExitCode(ExitLoadFailed, err)
+	}
+
+	structs, err := file.LoadNamedStructs(filePath, pkgs)
+	if err != nil {
+		return nil, withExitCode(ExitLoadFailed, err)
+	}
+
+	rootPkg, err := file.FindPackageOfFile(filePath, pkgs)
+	if err != nil {
+		return nil, withExitCode(ExitLoadFailed, err)
+	}
+
+	return &Project{
+		InputPath:     filePath,
+		Config:        cfg,
+		Pkgs:          pkgs,
+		RootPkg:       rootPkg,
+		Structs:       structs,
+		FunctionCache: analyze.NewFunctionCache(filterExcluded(pkgs, cfg), rootPkg.PkgPath),
+		Overlay:       overlay,
+	}, nil
+}
+
+// ForProfile はロード済みのパッケージを共有し、プロバイダの候補だけをプロファイルに合わせたプロジェクトを返す
+func (p *Project) ForProfile(profile string) *Project {
+	profiled := *p
+	profiled.Profile = profile
+	profiled.FunctionCache = analyze.NewProfileFunctionCache(filterExcluded(p.Pkgs, p.Config), p.RootPkg.PkgPath, profile)
+	return &profiled
+}
+
+// NewAnalyzer は解析結果のキャッシュを共有するアナライザを作成する
+func (p *Project) NewAnalyzer() analyze.Analyze {
+	resolvers := append(analyze.DefaultResolvers(p.FunctionCache), p.Resolvers...)
+	return analyze.NewAnalyze(p.FunctionCache, analyze.NewAnalysisCache(), resolvers...)
+}
+
+// AnalyzeRoots は全てのルート構造体を定義順に解析する。
+// 解析で見つかった問題は最初の問題で止めずに全てのルート構造体について集めて返す。
+// 複数のルート構造体から同じ箇所で見つかった問題は一度だけ返す
+func (p *Project) AnalyzeRoots() (analyze.RootTrees, diag.List, error) {
+	return p.analyzeRoots(p.NewAnalyzer())
+}
+
+func (p *Project) analyzeRoots(analyzer analyze.Analyze) (analyze.RootTrees, diag.List, error) {
+	trees := make(analyze.RootTrees, 0, len(p.Structs))
+	diags := make(diag.List, 0)
+	reported := make(map[string]bool)
+	for _, s := range p.Structs {
+		nodes, err := analyzer.ExecuteFromStruct(s)
+		var list diag.List
+		if errors.As(err, &list) {
+			for _, d := range list {
+				if !reported[d.Error()] {
+					reported[d.Error()] = true
+					diags = append(diags, d)
+				}
+			}
+		} else if err != nil {
+			return nil, nil, err
+		}
+		trees = append(trees, analyze.RootTree{Name: s.Obj().Name(), Trees: nodes})
+	}
+	return trees, diags, nil
+}
+
+// FieldAnalysis はルート構造体のフィールドの解析結果
+type FieldAnalysis struct {
+	Root  *types.Named
+	Field *types.Var
+	// Nodes はフィールドの値を供給するプロバイダ（依存は Childs に持つ）
+	Nodes []*analyze.FnDITreeNode
+	// Diagnostics はフィールドの解析で見つかった問題。
+	// 問題のある型は解析結果のキャッシュに入らないため、複数のフィールドから必要な型の問題はそれぞれのフィールドに含まれる
+	Diagnostics diag.List
+}
+
+// AnalyzeFields は全てのルート構造体をフィールドごとに解析する。
+// 問題がどのフィールドから必要な型で見つかったかを区別したい場合（エディタや go/analysis での報告）に使う
+func (p *Project) AnalyzeFields() ([]*FieldAnalysis, error) {
+	analyzer := p.NewAnalyzer()
+	fields := make([]*FieldAnalysis, 0)
+	for _, root := range p.Structs {
+		st := root.Underlying().(*types.Struct)
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			single := types.NewNamed(types.NewTypeName(root.Obj().Pos(), root.Obj().Pkg(), root.Obj().Name(), nil), nil, nil)
+			single.SetUnderlying(types.NewStruct([]*types.Var{field}, nil))
+			nodes, err := analyzer.ExecuteFromStruct(single)
+			var list diag.List
+			if err != nil && !errors.As(err, &list) {
+				return nil, err
+			}
+			fields = append(fields, &FieldAnalysis{Root: root, Field: field, Nodes: nodes, Diagnostics: list})
+		}
+	}
+	return fields, nil
+}
+
+// reportDiagnostics は解析で見つかった問題を標準エラー出力に書き出す（generate 以外のコマンドで使う）
+func reportDiagnostics(diags diag.List) {
+	if len(diags) > 0 {
+		diag.WriteText(os.Stderr, diags)
+	}
+}
@@ -0,0 +1,16 @@
+package override
+
+import (
+	"github.com/rmocchy/cire/sample/basic/handler"
+	"github.com/rmocchy/cire/sample/basic/repository"
+)
+
+// App はテスト用のインジェクタで UserRepository を差し替えるルート構造体
+type App struct {
+	handler *handler.UserHandler
+}
+
+// Admin は差し替えるプロバイダに依存しないルート構造体
+type Admin struct {
+	config *repository.Config
+}
@@ -0,0 +1,14 @@
+package override
+
+import "github.com/rmocchy/cire/sample/basic/repository"
+
+type fakeUserRepository struct{}
+
+func (fakeUserRepository) FindByID(id int) (*repository.User, error) {
+	return &repository.User{ID: id, Name: "fake"}, nil
+}
+
+//cire:override repository.UserRepository
+func newFakeUserRepository() (repository.UserRepository, error) {
+	return fakeUserRepository{}, nil
+}
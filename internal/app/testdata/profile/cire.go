@@ -0,0 +1,7 @@
+package profile
+
+// App はプロファイルごとに異なるプロバイダを使うルート構造体
+type App struct {
+	mailer Mailer
+	cache  Cache
+}
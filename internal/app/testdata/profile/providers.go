@@ -0,0 +1,58 @@
+package profile
+
+// Mailer はメールを送る
+type Mailer interface {
+	Send(to string) error
+}
+
+// Cache は値をキャッシュする
+type Cache interface {
+	Get(key string) (string, bool)
+}
+
+type fakeMailer struct{}
+
+func (fakeMailer) Send(string) error { return nil }
+
+type sesMailer struct{}
+
+func (sesMailer) Send(string) error { return nil }
+
+type memoryCache map[string]string
+
+func (c memoryCache) Get(key string) (string, bool) {
+	v, ok := c[key]
+	return v, ok
+}
+
+type redisCache struct{}
+
+func (redisCache) Get(string) (string, bool) { return "", false }
+
+// NewFakeMailer は送信せずに成功するメーラーを作成する
+//
+//cire:profile dev staging
+func NewFakeMailer() Mailer {
+	return fakeMailer{}
+}
+
+// NewSESMailer は SES で送信するメーラーを作成する
+//
+//cire:profile prod
+func NewSESMailer() Mailer {
+	return sesMailer{}
+}
+
+// NewMemoryCache はメモリ上のキャッシュを作成する
+//
+//cire:profile dev
+func NewMemoryCache() Cache {
+	return memoryCache{}
+}
+
+// NewRedisCache は Redis のキャッシュを作成する
+//
+//cire:profile prod
+func NewRedisCache() Cache {
+	return redisCache{}
+}
@@ -0,0 +1,359 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName はプロジェクト設定ファイルの名前
+const FileName = "cire.yaml"
+
+// BackendWire は Google Wire 向けのコードを生成するバックエンド
+const BackendWire = "wire"
+
+// Config は cire.yaml の内容を表す
+type Config struct {
+	// Patterns はプロバイダを探索するパッケージパターン（例: "./..."）
+	Patterns []string `yaml:"patterns"`
+	// Exclude は探索対象から除外するパッケージパターン（例: "mocks/...", "...testdata..."）
+	Exclude []string `yaml:"exclude"`
+	// Output は生成物の出力先
+	Output Output `yaml:"output"`
+	// Naming は生成する宣言の命名テンプレート
+	Naming Naming `yaml:"naming"`
+	// BuildTags はパッケージのロード時に有効にするビルドタグ
+	BuildTags []string `yaml:"build_tags"`
+	// Backend はコード生成のバックエンド（現在は "wire" のみ）
+	Backend string `yaml:"backend"`
+	// Overrides はテスト用のインジェクタで差し替えるプロバイダ
+	Overrides []Override `yaml:"overrides"`
+	// SharedSets が true の場合は、複数のルート構造体が使うプロバイダをパッケージごとの共通のセットにまとめて生成する
+	SharedSets bool `yaml:"shared_sets"`
+	// PackageSets が true の場合は、プロバイダを持つモジュール内のパッケージごとに公開のプロバイダセット（ProviderSet）を生成し、
+	// ルート構造体のセットからはそれを参照する
+	PackageSets bool `yaml:"package_sets"`
+	// Lint は cire lint の設定
+	Lint Lint `yaml:"lint"`
+
+	// Path は読み込んだ設定ファイルのパス。設定ファイルが無い場合は空
+	Path string `yaml:"-"`
+}
+
+// Output は生成物の出力先を表す。相対パスは入力ファイルのディレクトリからの相対パスとして扱う
+type Output struct {
+	Wire string `yaml:"wire"`
+	JSON string `yaml:"json"`
+	// TestWire はテスト用のインジェクタの出力先。差し替えるプロバイダがある場合だけ生成する。
+	// wire はテストファイルを読まないため _test.go にはできない
+	TestWire string `yaml:"test_wire"`
+	// Providers は package_sets で各パッケージに生成するファイルの名前（パッケージのディレクトリに置く）
+	Providers string `yaml:"providers"`
+}
+
+// Naming は生成する宣言名のテンプレートを表す。テンプレート内では {{.Root}} でルート構造体名を参照できる
+type Naming struct {
+	Injector string `yaml:"injector"`
+	Set      string `yaml:"set"`
+	// TestInjector と TestSet はテスト用のインジェクタ関数とプロバイダセットの名前
+	TestInjector string `yaml:"test_injector"`
+	TestSet      string `yaml:"test_set"`
+}
+
+// Lint は cire lint の設定を表す
+type Lint struct {
+	// Constructors はコンストラクタとして扱う関数名の正規表現。一致しない関数（Load や Default などの補助関数）は報告の対象にしない
+	Constructors string `yaml:"constructors"`
+}
+
+// Override はテスト用のインジェクタで型のプロバイダを別の関数に差し替える設定
+type Override struct {
+	// Type は差し替える型（例: "github.com/acme/repository.UserRepository" または "repository.UserRepository"）
+	Type string `yaml:"type"`
+	// Provider は代わりに使う関数（例: "github.com/acme/fakes.NewUserRepository" または "fakes.NewUserRepository"）
+	Provider string `yaml:"provider"`
+}
+
+// NameData は命名テンプレートに渡すデータ
+type NameData struct {
+	Root string
+}
+
+// Default はデフォルトの設定を返す
+func Default() *Config {
+	return &Config{
+		Patterns: []string{"./..."},
+		Exclude:  []string{},
+		Output: Output{
+			Wire:      "wire.go",
+			JSON:      "dep_tree.json",
+			TestWire:  "wire_ciretest.go",
+			Providers: "providers_gen.go",
+		},
+		Naming: Naming{
+			Injector:     "Initialize{{.Root}}",
+			Set:          "{{.Root}}Set",
+			TestInjector: "Initialize{{.Root}}ForTest",
+			TestSet:      "{{.Root}}TestSet",
+		},
+		BuildTags: []string{"cire"},
+		Backend:   BackendWire,
+		Lint:      Lint{Constructors: "^New"},
+	}
+}
+
+// Find は入力ファイルのディレクトリ（inputPath がディレクトリの場合はそのディレクトリ）から
+// go.mod のあるディレクトリまで上方向に cire.yaml を探す。見つからなかった場合は空文字を返す
+func Find(inputPath string) (string, error) {
+	start := filepath.Dir(inputPath)
+	if info, err := os.Stat(inputPath); err == nil && info.IsDir() {
+		start = inputPath
+	}
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	for {
+		candidate := filepath.Join(dir, FileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		// go.mod のあるディレクトリより上は探索しない
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadForInput は入力ファイルに対応する設定ファイルを探して読み込む。
+// 設定ファイルが無い場合はデフォルトの設定を返す
+func LoadForInput(inputPath string) (*Config, error) {
+	path, err := Find(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return Default(), nil
+	}
+	return Load(path)
+}
+
+// Load は指定されたパスの設定ファイルを読み込み、未指定の項目をデフォルト値で補う
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg := Default()
+	var loaded Config
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	cfg.merge(&loaded)
+	cfg.Path = path
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// merge は other で指定された項目で c を上書きする
+func (c *Config) merge(other *Config) {
+	if other.Patterns != nil {
+		c.Patterns = other.Patterns
+	}
+	if other.Exclude != nil {
+		c.Exclude = other.Exclude
+	}
+	if other.Output.Wire != "" {
+		c.Output.Wire = other.Output.Wire
+	}
+	if other.Output.JSON != "" {
+		c.Output.JSON = other.Output.JSON
+	}
+	if other.Output.TestWire != "" {
+		c.Output.TestWire = other.Output.TestWire
+	}
+	if other.Output.Providers != "" {
+		c.Output.Providers = other.Output.Providers
+	}
+	if other.Naming.Injector != "" {
+		c.Naming.Injector = other.Naming.Injector
+	}
+	if other.Naming.Set != "" {
+		c.Naming.Set = other.Naming.Set
+	}
+	if other.Naming.TestInjector != "" {
+		c.Naming.TestInjector = other.Naming.TestInjector
+	}
+	if other.Naming.TestSet != "" {
+		c.Naming.TestSet = other.Naming.TestSet
+	}
+	if other.Overrides != nil {
+		c.Overrides = other.Overrides
+	}
+	if other.BuildTags != nil {
+		c.BuildTags = other.BuildTags
+	}
+	if other.Backend != "" {
+		c.Backend = other.Backend
+	}
+	if other.SharedSets {
+		c.SharedSets = true
+	}
+	if other.PackageSets {
+		c.PackageSets = true
+	}
+	if other.Lint.Constructors != "" {
+		c.Lint.Constructors = other.Lint.Constructors
+	}
+}
+
+// Validate は設定値の妥当性をチェックする
+func (c *Config) Validate() error {
+	if c.Backend != BackendWire {
+		return fmt.Errorf("unsupported backend: %q", c.Backend)
+	}
+	if len(c.Patterns) == 0 {
+		return errors.New("patterns must not be empty")
+	}
+	if _, err := c.InjectorName("Root"); err != nil {
+		return err
+	}
+	if _, err := c.SetName("Root"); err != nil {
+		return err
+	}
+	if _, err := c.TestInjectorName("Root"); err != nil {
+		return err
+	}
+	if _, err := c.TestSetName("Root"); err != nil {
+		return err
+	}
+	if c.SharedSets && c.PackageSets {
+		return errors.New("shared_sets and package_sets cannot be used together")
+	}
+	if c.Output.Providers != filepath.Base(c.Output.Providers) || filepath.Ext(c.Output.Providers) != ".go" || strings.HasSuffix(c.Output.Providers, "_test.go") {
+		return fmt.Errorf("output.providers must be a .go file name without a directory: %q", c.Output.Providers)
+	}
+	if strings.HasSuffix(c.Output.TestWire, "_test.go") {
+		return fmt.Errorf("output.test_wire must not be a _test.go file, which wire does not load: %q", c.Output.TestWire)
+	}
+	for i, o := range c.Overrides {
+		if o.Type == "" || o.Provider == "" {
+			return fmt.Errorf("overrides[%d]: both type and provider are required", i)
+		}
+	}
+	if _, err := c.ConstructorRegexp(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ConstructorRegexp は lint.constructors をコンパイルした正規表現を返す
+func (c *Config) ConstructorRegexp() (*regexp.Regexp, error) {
+	re, err := regexp.Compile(c.Lint.Constructors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lint.constructors pattern: %w", err)
+	}
+	return re, nil
+}
+
+// InjectorName は命名テンプレートからルート構造体のインジェクタ関数名を求める
+func (c *Config) InjectorName(root string) (string, error) {
+	return executeNameTemplate("naming.injector", c.Naming.Injector, root)
+}
+
+// SetName は命名テンプレートからルート構造体のプロバイダセット名を求める
+func (c *Config) SetName(root string) (string, error) {
+	return executeNameTemplate("naming.set", c.Naming.Set, root)
+}
+
+// TestInjectorName は命名テンプレートからルート構造体のテスト用のインジェクタ関数名を求める
+func (c *Config) TestInjectorName(root string) (string, error) {
+	return executeNameTemplate("naming.test_injector", c.Naming.TestInjector, root)
+}
+
+// TestSetName は命名テンプレートからルート構造体のテスト用のプロバイダセット名を求める
+func (c *Config) TestSetName(root string) (string, error) {
+	return executeNameTemplate("naming.test_set", c.Naming.TestSet, root)
+}
+
+var identRegexp = regexp.MustCompile(`^[\p{L}_][\p{L}\p{Nd}_]*$`)
+
+func executeNameTemplate(key, text, root string) (string, error) {
+	tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", key, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, NameData{Root: root}); err != nil {
+		return "", fmt.Errorf("failed to execute %s template: %w", key, err)
+	}
+	name := buf.String()
+	if !identRegexp.MatchString(name) {
+		return "", fmt.Errorf("%s template produced an invalid identifier: %q", key, name)
+	}
+	return name, nil
+}
+
+// ResolveOutput は出力先のパスを入力ファイルのディレクトリを基準に解決する
+func ResolveOutput(inputPath, output string) string {
+	if filepath.IsAbs(output) {
+		return output
+	}
+	return filepath.Join(filepath.Dir(inputPath), output)
+}
+
+// ProfileOutput は出力先のファイル名にプロファイル名を加える（例: "wire.go" は "wire_dev.go"、"wire_test.go" は "wire_dev_test.go"）
+func ProfileOutput(output, profile string) string {
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	if trimmed, ok := strings.CutSuffix(base, "_test"); ok {
+		return trimmed + "_" + profile + "_test" + ext
+	}
+	return base + "_" + profile + ext
+}
+
+// ProfileSuffix はプロファイルごとのファイルの宣言名に付けるプロファイル名を返す。
+// "_" と "." で区切った語の先頭を大文字にしてつなげる（例: "dev" は "Dev"、"us_east.1" は "UsEast1"）
+func ProfileSuffix(profile string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(profile, func(r rune) bool { return r == '_' || r == '.' }) {
+		r, size := utf8.DecodeRuneInString(word)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(word[size:])
+	}
+	return b.String()
+}
+
+var profileRegexp = regexp.MustCompile(`^[\p{L}\p{Nd}_.]+$`)
+
+// ValidateProfile はプロファイル名がビルドタグとして使えるか（文字・数字・"_"・"." だけからなるか）を検査する
+func ValidateProfile(profile string) error {
+	if !profileRegexp.MatchString(profile) {
+		return fmt.Errorf("invalid profile %q: profiles are used as build tags and may contain only letters, digits, '_' and '.'", profile)
+	}
+	return nil
+}
+
+// BuildFlags はパッケージのロードに渡すビルドフラグを返す
+func (c *Config) BuildFlags() []string {
+	if len(c.BuildTags) == 0 {
+		return nil
+	}
+	return []string{"-tags=" + strings.Join(c.BuildTags, ",")}
+}
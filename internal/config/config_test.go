@@ -0,0 +1,246 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile はテスト用のファイルを書き込む
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+	writeFile(t, filepath.Join(root, "cmd", "api", "cire.go"), "package main\n")
+
+	// 設定ファイルが無い場合は空
+	got, err := Find(filepath.Join(root, "cmd", "api", "cire.go"))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got != "" {
+		t.Errorf("Find() = %q, want empty", got)
+	}
+
+	// go.mod と同じ階層の設定ファイルが見つかる
+	writeFile(t, filepath.Join(root, FileName), "backend: wire\n")
+	got, err = Find(filepath.Join(root, "cmd", "api", "cire.go"))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got != filepath.Join(root, FileName) {
+		t.Errorf("Find() = %q, want %q", got, filepath.Join(root, FileName))
+	}
+
+	// より近い設定ファイルが優先される
+	writeFile(t, filepath.Join(root, "cmd", FileName), "backend: wire\n")
+	got, err = Find(filepath.Join(root, "cmd", "api", "cire.go"))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got != filepath.Join(root, "cmd", FileName) {
+		t.Errorf("Find() = %q, want %q", got, filepath.Join(root, "cmd", FileName))
+	}
+}
+
+func TestFind_Directory(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+	writeFile(t, filepath.Join(root, "cmd", FileName), "backend: wire\n")
+	writeFile(t, filepath.Join(root, "cmd", "api", FileName), "backend: wire\n")
+
+	// ディレクトリを指定した場合は、その親ではなくディレクトリ自身から探す
+	for _, dir := range []string{filepath.Join(root, "cmd", "api"), filepath.Join(root, "cmd", "api") + string(filepath.Separator)} {
+		got, err := Find(dir)
+		if err != nil {
+			t.Fatalf("Find(%q) error = %v", dir, err)
+		}
+		if want := filepath.Join(root, "cmd", "api", FileName); got != want {
+			t.Errorf("Find(%q) = %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestFind_StopsAtModuleRoot(t *testing.T) {
+	parent := t.TempDir()
+	writeFile(t, filepath.Join(parent, FileName), "backend: wire\n")
+	writeFile(t, filepath.Join(parent, "mod", "go.mod"), "module example.com/app\n")
+
+	got, err := Find(filepath.Join(parent, "mod", "cire.go"))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got != "" {
+		t.Errorf("Find() = %q, want empty (must not search above go.mod)", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeFile(t, path, `patterns: ["./internal/..."]
+exclude: ["mocks/..."]
+output:
+  wire: di/wire.go
+naming:
+  injector: "New{{.Root}}"
+build_tags: [cire, integration]
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Patterns) != 1 || cfg.Patterns[0] != "./internal/..." {
+		t.Errorf("Patterns = %v", cfg.Patterns)
+	}
+	if cfg.Output.Wire != "di/wire.go" {
+		t.Errorf("Output.Wire = %q", cfg.Output.Wire)
+	}
+	// 未指定の項目はデフォルト値
+	if cfg.Output.JSON != "dep_tree.json" {
+		t.Errorf("Output.JSON = %q, want default", cfg.Output.JSON)
+	}
+	if cfg.Backend != BackendWire {
+		t.Errorf("Backend = %q, want default", cfg.Backend)
+	}
+	if got := cfg.BuildFlags(); len(got) != 1 || got[0] != "-tags=cire,integration" {
+		t.Errorf("BuildFlags() = %v", got)
+	}
+
+	injector, err := cfg.InjectorName("App")
+	if err != nil || injector != "NewApp" {
+		t.Errorf("InjectorName() = %q, %v", injector, err)
+	}
+	set, err := cfg.SetName("App")
+	if err != nil || set != "AppSet" {
+		t.Errorf("SetName() = %q, %v", set, err)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "未対応のバックエンド", content: "backend: dig\n"},
+		{name: "識別子にならない命名テンプレート", content: "naming:\n  set: \"{{.Root}}-set\"\n"},
+		{name: "存在しないフィールドを参照する命名テンプレート", content: "naming:\n  set: \"{{.Name}}Set\"\n"},
+		{name: "差し替える関数の無い overrides", content: "overrides:\n  - type: repository.UserRepository\n"},
+		{name: "shared_sets と package_sets の併用", content: "shared_sets: true\npackage_sets: true\n"},
+		{name: "ディレクトリを含む output.providers", content: "output:\n  providers: gen/providers.go\n"},
+		{name: "テストファイルの output.test_wire", content: "output:\n  test_wire: wire_test.go\n"},
+		{name: "正規表現でない lint.constructors", content: "lint:\n  constructors: \"New(\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			writeFile(t, path, tt.content)
+			if _, err := Load(path); err == nil {
+				t.Error("Load() expected error")
+			}
+		})
+	}
+}
+
+func TestValidateFile_UnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeFile(t, path, `patterns: ["./..."]
+outputs:
+  wire: wire.go
+naming:
+  injector: "Initialize{{.Root}}"
+  sets: "{{.Root}}Set"
+overrides:
+  - type: repository.UserRepository
+    func: fakes.NewUserRepository
+`)
+
+	problems, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("ValidateFile() error = %v", err)
+	}
+	want := []string{
+		`2:1: unknown key "outputs"`,
+		`6:3: unknown key "naming.sets"`,
+		`9:5: unknown key "overrides[0].func"`,
+		`1:1: overrides[0]: both type and provider are required`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("ValidateFile() = %v, want %v", problems, want)
+	}
+	for i, p := range problems {
+		if p.String() != want[i] {
+			t.Errorf("problem[%d] = %q, want %q", i, p.String(), want[i])
+		}
+	}
+}
+
+func TestIsExcluded(t *testing.T) {
+	cfg := Default()
+	cfg.Exclude = []string{"mocks/...", "...testdata...", "github.com/other/gen"}
+
+	tests := []struct {
+		pkgPath string
+		want    bool
+	}{
+		{pkgPath: "example.com/app/mocks", want: true},
+		{pkgPath: "example.com/app/mocks/service", want: true},
+		{pkgPath: "example.com/app/internal/testdata/foo", want: true},
+		{pkgPath: "github.com/other/gen", want: true},
+		{pkgPath: "example.com/app/service", want: false},
+		{pkgPath: "example.com/app/mocksvc", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pkgPath, func(t *testing.T) {
+			if got := cfg.IsExcluded(tt.pkgPath, "example.com/app"); got != tt.want {
+				t.Errorf("IsExcluded(%q) = %v, want %v", tt.pkgPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{output: "wire.go", want: "wire_dev.go"},
+		{output: "wire_test.go", want: "wire_dev_test.go"},
+		{output: "dep_tree.json", want: "dep_tree_dev.json"},
+		{output: "gen/wire.go", want: "gen/wire_dev.go"},
+	}
+	for _, tt := range tests {
+		if got := ProfileOutput(tt.output, "dev"); got != tt.want {
+			t.Errorf("ProfileOutput(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+	for _, profile := range []string{"", "dev,prod", "-dev", "dev prod"} {
+		if err := ValidateProfile(profile); err == nil {
+			t.Errorf("ValidateProfile(%q) error = nil, want error", profile)
+		}
+	}
+}
+
+func TestProfileSuffix(t *testing.T) {
+	tests := map[string]string{
+		"dev":       "Dev",
+		"us_east.1": "UsEast1",
+		"_":         "",
+	}
+	for profile, want := range tests {
+		if got := ProfileSuffix(profile); got != want {
+			t.Errorf("ProfileSuffix(%q) = %q, want %q", profile, got, want)
+		}
+	}
+}
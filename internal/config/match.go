@@ -0,0 +1,38 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// IsExcluded は pkgPath が Exclude のいずれかのパターンに一致するかを返す
+func (c *Config) IsExcluded(pkgPath, modulePath string) bool {
+	return MatchPackage(c.Exclude, pkgPath, modulePath)
+}
+
+// MatchPackage は pkgPath が patterns のいずれかに一致するかを返す。
+// パターンはインポートパス全体、またはモジュールパスからの相対パスと照合する
+func MatchPackage(patterns []string, pkgPath, modulePath string) bool {
+	rel := ""
+	if modulePath != "" && strings.HasPrefix(pkgPath, modulePath+"/") {
+		rel = strings.TrimPrefix(pkgPath, modulePath+"/")
+	}
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(pattern, "./")
+		if MatchPattern(pattern, pkgPath) || (rel != "" && MatchPattern(pattern, rel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPattern は go コマンドと同様に "..." を任意の文字列として pkgPath と照合する
+func MatchPattern(pattern, pkgPath string) bool {
+	quoted := regexp.QuoteMeta(pattern)
+	expr := strings.ReplaceAll(quoted, `\.\.\.`, `.*`)
+	// "foo/..." は "foo" 自身にも一致する
+	if strings.HasSuffix(expr, `/.*`) {
+		expr = strings.TrimSuffix(expr, `/.*`) + `(/.*)?`
+	}
+	return regexp.MustCompile(`^` + expr + `$`).MatchString(pkgPath)
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem は設定ファイルの検証で見つかった問題を表す
+type Problem struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+}
+
+// ValidateFile は設定ファイルを検証し、未知のキーや不正な値を全て報告する
+func ValidateFile(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	problems := make([]Problem, 0)
+	if len(root.Content) > 0 {
+		problems = append(problems, unknownKeys(root.Content[0], reflect.TypeOf(Config{}), "")...)
+	}
+
+	cfg := Default()
+	var loaded Config
+	if err := root.Decode(&loaded); err != nil {
+		problems = append(problems, Problem{Line: root.Line, Column: root.Column, Message: err.Error()})
+		return problems, nil
+	}
+	cfg.merge(&loaded)
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, Problem{Line: root.Line, Column: root.Column, Message: err.Error()})
+	}
+	return problems, nil
+}
+
+// unknownKeys は構造体の yaml タグに存在しないキーを再帰的に探す
+func unknownKeys(node *yaml.Node, t reflect.Type, prefix string) []Problem {
+	// 構造体のリスト（overrides など）は要素ごとに検査する
+	if node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice {
+		problems := make([]Problem, 0)
+		for i, item := range node.Content {
+			problems = append(problems, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d].", strings.TrimSuffix(prefix, "."), i))...)
+		}
+		return problems
+	}
+	if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fields[tag] = t.Field(i).Type
+	}
+
+	problems := make([]Problem, 0)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		fieldType, ok := fields[key.Value]
+		if !ok {
+			problems = append(problems, Problem{
+				Line:    key.Line,
+				Column:  key.Column,
+				Message: fmt.Sprintf("unknown key %q", prefix+key.Value),
+			})
+			continue
+		}
+		problems = append(problems, unknownKeys(value, fieldType, prefix+key.Value+".")...)
+	}
+	return problems
+}
@@ -0,0 +1,192 @@
+package diag
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// Code は診断の種類を表すコード
+type Code string
+
+const (
+	// MissingProvider は型を返すプロバイダが見つからない
+	MissingProvider Code = "CIRE001"
+	// AmbiguousProvider は型を返すプロバイダが複数ある
+	AmbiguousProvider Code = "CIRE002"
+	// DependencyCycle はプロバイダの依存関係が循環している
+	DependencyCycle Code = "CIRE003"
+	// ImportCycle は生成ファイルがプロバイダのパッケージを import すると循環参照になる
+	ImportCycle Code = "CIRE004"
+	// UnsupportedField はルート構造体のフィールドの型がプロバイダで供給できない
+	UnsupportedField Code = "CIRE005"
+	// UnusedConstructor はどのルート構造体からも到達しないコンストラクタ
+	UnusedConstructor Code = "CIRE006"
+	// InvalidProvider は型を返すがシグネチャがプロバイダの形でない関数
+	InvalidProvider Code = "CIRE007"
+	// InvalidOverride はテスト用のインジェクタで差し替えるプロバイダの型が合わないか、差し替える対象が無い
+	InvalidOverride Code = "CIRE008"
+)
+
+// Rule は診断コードの説明（SARIF のルールのメタデータに使う）
+type Rule struct {
+	Code Code
+	// Name は PascalCase のルール名
+	Name string
+	// Description は1文の説明
+	Description string
+	// Help は問題の解決方法
+	Help string
+	// Severity は通常の重大度
+	Severity Severity
+}
+
+// Rules は全ての診断コードの説明（コード順）
+var Rules = []Rule{
+	{
+		Code:        MissingProvider,
+		Name:        "MissingProvider",
+		Description: "No provider function returns a type required by a root struct field or a provider parameter.",
+		Help:        "Add a constructor that returns the type to a package matched by patterns, or remove the field that requires it.",
+		Severity:    SeverityError,
+	},
+	{
+		Code:        AmbiguousProvider,
+		Name:        "AmbiguousProvider",
+		Description: "More than one provider function returns the same type.",
+		Help:        "Keep one constructor for the type, or mark the others with //cire:ignore.",
+		Severity:    SeverityError,
+	},
+	{
+		Code:        DependencyCycle,
+		Name:        "DependencyCycle",
+		Description: "Provider dependencies form a cycle.",
+		Help:        "Break the cycle by removing one of the constructor parameters in the cycle.",
+		Severity:    SeverityError,
+	},
+	{
+		Code:        ImportCycle,
+		Name:        "ImportCycle",
+		Description: "The generated file would import a package that imports the generated file's package.",
+		Help:        "Move the root struct file to a package that is not imported by any provider package.",
+		Severity:    SeverityError,
+	},
+	{
+		Code:        UnsupportedField,
+		Name:        "UnsupportedField",
+		Description: "A root struct field has a type that no provider can supply.",
+		Help:        "Use a named type (or a pointer to one) for root struct fields.",
+		Severity:    SeverityError,
+	},
+	{
+		Code:        UnusedConstructor,
+		Name:        "UnusedConstructor",
+		Description: "A constructor is not reachable from any root struct.",
+		Help:        "Remove the constructor, add a root field that uses it, or mark it with //cire:ignore.",
+		Severity:    SeverityWarning,
+	},
+	{
+		Code:        InvalidProvider,
+		Name:        "InvalidProvider",
+		Description: "A function returns a required type but its results are not a valid provider shape.",
+		Help:        "Return (T), (T, error), (T, func()) or (T, func(), error) with T as the first result.",
+		Severity:    SeverityError,
+	},
+	{
+		Code:        InvalidOverride,
+		Name:        "InvalidOverride",
+		Description: "A test override does not return the type of the provider it replaces, or replaces a type no root struct requires.",
+		Help:        "Make the override return exactly the replaced provider's result type, or remove the override.",
+		Severity:    SeverityError,
+	},
+}
+
+// Severity は診断の重大度
+type Severity string
+
+const (
+	// SeverityError はコード生成を妨げる問題
+	SeverityError Severity = "error"
+	// SeverityWarning はコード生成を妨げないが注意が必要な問題
+	SeverityWarning Severity = "warning"
+)
+
+// Related は診断に関連する位置
+type Related struct {
+	Position token.Position
+	Message  string
+}
+
+// Diagnostic は解析で見つかった問題
+type Diagnostic struct {
+	Code     Code
+	Severity Severity
+	Message  string
+	// Position は問題の位置。不明な場合は無効な位置（Line が 0）
+	Position token.Position
+	Related  []Related
+	// Profile は問題が見つかったプロファイル（cire generate --profile）。プロファイルを指定しない解析では空
+	Profile string
+}
+
+// Error は "file:line:col: error CIRE001: message" の形式で診断を返す
+func (d *Diagnostic) Error() string {
+	var sb strings.Builder
+	if pos := FormatPosition(d.Position); pos != "" {
+		sb.WriteString(pos + ": ")
+	}
+	fmt.Fprintf(&sb, "%s %s: %s", d.Severity, d.Code, d.Text())
+	return sb.String()
+}
+
+// Text はメッセージを返す。プロファイルの解析で見つかった問題では "profile dev: " を前置する
+func (d *Diagnostic) Text() string {
+	if d.Profile == "" {
+		return d.Message
+	}
+	return fmt.Sprintf("profile %s: %s", d.Profile, d.Message)
+}
+
+// FormatPosition は位置を "file:line:col" の形式で返す。ファイルが不明な場合は空
+func FormatPosition(pos token.Position) string {
+	switch {
+	case pos.Filename == "":
+		return ""
+	case pos.Line == 0:
+		return pos.Filename
+	case pos.Column == 0:
+		return fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+}
+
+// Errorf は重大度が error の診断を作成する
+func Errorf(code Code, pos token.Position, format string, args ...any) *Diagnostic {
+	return &Diagnostic{Code: code, Severity: SeverityError, Message: fmt.Sprintf(format, args...), Position: pos}
+}
+
+// Warningf は重大度が warning の診断を作成する
+func Warningf(code Code, pos token.Position, format string, args ...any) *Diagnostic {
+	return &Diagnostic{Code: code, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...), Position: pos}
+}
+
+// List は診断の一覧。error として返す場合は全ての診断をまとめて表す
+type List []*Diagnostic
+
+func (l List) Error() string {
+	messages := make([]string, 0, len(l))
+	for _, d := range l {
+		messages = append(messages, d.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// HasErrors は重大度が error の診断を含むかどうかを返す
+func (l List) HasErrors() bool {
+	for _, d := range l {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package diag
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testList() List {
+	d := Errorf(AmbiguousProvider, token.Position{Filename: "handler.go", Line: 12, Column: 3}, "multiple providers found for %s", "Cache")
+	d.Related = append(d.Related,
+		Related{Position: token.Position{Filename: "cache.go", Line: 5, Column: 6}, Message: "candidate cache.NewCache"},
+		Related{Message: "candidate cache.NewRedisCache"},
+	)
+	return List{d, Warningf(UnusedConstructor, token.Position{}, "logger.NewLogger is not reachable from any root")}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testList(), FormatText); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "handler.go:12:3: error CIRE002: multiple providers found for Cache\n" +
+		"\tcache.go:5:6: candidate cache.NewCache\n" +
+		"\tcandidate cache.NewRedisCache\n" +
+		"warning CIRE006: logger.NewLogger is not reachable from any root\n"
+	if buf.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testList(), FormatJSON); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var got struct {
+		Diagnostics []JSONDiagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if len(got.Diagnostics) != 2 {
+		t.Fatalf("len(diagnostics) = %d, want 2", len(got.Diagnostics))
+	}
+	first := got.Diagnostics[0]
+	if first.Code != AmbiguousProvider || first.Severity != SeverityError || first.Position == nil || first.Position.Line != 12 {
+		t.Errorf("diagnostics[0] = %+v", first)
+	}
+	if len(first.Related) != 2 || first.Related[1].Position != nil {
+		t.Errorf("diagnostics[0].related = %+v", first.Related)
+	}
+	if got.Diagnostics[1].Position != nil {
+		t.Errorf("diagnostics[1].position = %+v, want nil", got.Diagnostics[1].Position)
+	}
+}
+
+func TestDiagnostic_Profile(t *testing.T) {
+	d := Errorf(MissingProvider, token.Position{Filename: "cire.go", Line: 8}, "no provider found for Mailer")
+	d.Profile = "dev"
+	if got, want := d.Error(), "cire.go:8: error CIRE001: profile dev: no provider found for Mailer"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if j := d.ToJSON(); j.Profile != "dev" || j.Message != "no provider found for Mailer" {
+		t.Errorf("ToJSON() = %+v", j)
+	}
+}
+
+func TestWrite_UnsupportedFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, testList(), "xml"); err == nil {
+		t.Error("Write() error = nil, want error")
+	}
+}
+
+func TestList_HasErrors(t *testing.T) {
+	if !testList().HasErrors() {
+		t.Error("HasErrors() = false, want true")
+	}
+	if testList()[1:].HasErrors() {
+		t.Error("HasErrors() = true for warnings only, want false")
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testList(), FormatSARIF); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var got struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				RelatedLocations []json.RawMessage `json:"relatedLocations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if got.Version != "2.1.0" || len(got.Runs) != 1 {
+		t.Fatalf("version = %q, len(runs) = %d", got.Version, len(got.Runs))
+	}
+	run := got.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(Rules) {
+		t.Errorf("len(rules) = %d, want %d", len(run.Tool.Driver.Rules), len(Rules))
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(run.Results))
+	}
+
+	first := run.Results[0]
+	if first.RuleID != string(AmbiguousProvider) || run.Tool.Driver.Rules[first.RuleIndex].ID != first.RuleID || first.Level != "error" {
+		t.Errorf("results[0] = %+v", first)
+	}
+	if len(first.Locations) != 1 {
+		t.Fatalf("len(results[0].locations) = %d, want 1", len(first.Locations))
+	}
+	loc := first.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "handler.go" || loc.ArtifactLocation.URIBaseID != "%SRCROOT%" || loc.Region.StartLine != 12 {
+		t.Errorf("results[0].locations[0] = %+v", loc)
+	}
+	if len(first.RelatedLocations) != 2 {
+		t.Errorf("len(results[0].relatedLocations) = %d, want 2", len(first.RelatedLocations))
+	}
+
+	second := run.Results[1]
+	if second.Level != "warning" || len(second.Locations) != 0 {
+		t.Errorf("results[1] = %+v", second)
+	}
+}
+
+func TestRules_CoverAllCodes(t *testing.T) {
+	codes := []Code{MissingProvider, AmbiguousProvider, DependencyCycle, ImportCycle, UnsupportedField, UnusedConstructor, InvalidProvider, InvalidOverride}
+	seen := make(map[Code]bool)
+	for _, r := range Rules {
+		seen[r.Code] = true
+		if r.Name == "" || r.Description == "" || r.Help == "" || r.Severity == "" {
+			t.Errorf("rule %s has empty metadata: %+v", r.Code, r)
+		}
+	}
+	for _, code := range codes {
+		if !seen[code] {
+			t.Errorf("no rule for %s", code)
+		}
+	}
+}
+
+func TestWriteSARIFWithRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "repo")
+	list := List{
+		Errorf(AmbiguousProvider, token.Position{Filename: filepath.Join(root, "handler", "handler.go"), Line: 12}, "multiple providers found for Cache"),
+		Errorf(MissingProvider, token.Position{Filename: filepath.Join(filepath.Dir(root), "other", "cache.go"), Line: 5}, "no provider found for Cache"),
+	}
+	var buf bytes.Buffer
+	if err := WriteSARIFWithRoot(&buf, list, root); err != nil {
+		t.Fatalf("WriteSARIFWithRoot() error = %v", err)
+	}
+	var got struct {
+		Runs []struct {
+			OriginalURIBaseIDs map[string]struct {
+				URI string `json:"uri"`
+			} `json:"originalUriBaseIds"`
+			Results []struct {
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	run := got.Runs[0]
+	if base := run.OriginalURIBaseIDs["%SRCROOT%"].URI; base != "file://"+filepath.ToSlash(root)+"/" {
+		t.Errorf("originalUriBaseIds[%%SRCROOT%%] = %q, want file URI of %s", base, root)
+	}
+	inside := run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation
+	if inside.URI != "handler/handler.go" || inside.URIBaseID != "%SRCROOT%" {
+		t.Errorf("location under root = %+v, want handler/handler.go relative to %%SRCROOT%%", inside)
+	}
+	outside := run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation
+	if !strings.HasPrefix(outside.URI, "file://") || outside.URIBaseID != "" {
+		t.Errorf("location outside root = %+v, want absolute file URI", outside)
+	}
+}
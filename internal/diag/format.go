@@ -0,0 +1,118 @@
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+)
+
+const (
+	// FormatText は人が読むためのテキスト形式
+	FormatText = "text"
+	// FormatJSON は機械処理のための JSON 形式
+	FormatJSON = "json"
+	// FormatSARIF はコードスキャンのツールに取り込むための SARIF 2.1.0 形式
+	FormatSARIF = "sarif"
+)
+
+// CheckFormat は出力形式が対応しているかを確認する。空の場合はテキスト形式として扱う
+func CheckFormat(format string) error {
+	switch format {
+	case FormatText, FormatJSON, FormatSARIF, "":
+		return nil
+	}
+	return fmt.Errorf("unsupported diagnostics format: %q (expected %s, %s or %s)", format, FormatText, FormatJSON, FormatSARIF)
+}
+
+// Write は診断を指定された形式で出力する
+func Write(w io.Writer, list List, format string) error {
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, list)
+	case FormatSARIF:
+		return WriteSARIF(w, list)
+	}
+	return WriteText(w, list)
+}
+
+// WriteText は診断を1件ずつ出力し、関連する位置をインデントして続ける
+func WriteText(w io.Writer, list List) error {
+	for _, d := range list {
+		if _, err := fmt.Fprintln(w, d.Error()); err != nil {
+			return err
+		}
+		for _, r := range d.Related {
+			line := "\t" + r.Message
+			if pos := FormatPosition(r.Position); pos != "" {
+				line = "\t" + pos + ": " + r.Message
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// JSONPosition は JSON 出力での位置
+type JSONPosition struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// JSONRelated は JSON 出力での関連する位置
+type JSONRelated struct {
+	Position *JSONPosition `json:"position,omitempty"`
+	Message  string        `json:"message"`
+}
+
+// JSONDiagnostic は JSON 出力での診断
+type JSONDiagnostic struct {
+	Code     Code          `json:"code"`
+	Severity Severity      `json:"severity"`
+	Message  string        `json:"message"`
+	Position *JSONPosition `json:"position,omitempty"`
+	Related  []JSONRelated `json:"related,omitempty"`
+	Profile  string        `json:"profile,omitempty"`
+}
+
+// NewJSONPosition は位置を JSON 出力用に変換する。ファイルが不明な場合は nil
+func NewJSONPosition(pos token.Position) *JSONPosition {
+	if pos.Filename == "" {
+		return nil
+	}
+	return &JSONPosition{File: pos.Filename, Line: pos.Line, Column: pos.Column}
+}
+
+// ToJSON は診断を JSON 出力用に変換する
+func (d *Diagnostic) ToJSON() JSONDiagnostic {
+	j := JSONDiagnostic{
+		Code:     d.Code,
+		Severity: d.Severity,
+		Message:  d.Message,
+		Position: NewJSONPosition(d.Position),
+		Profile:  d.Profile,
+	}
+	for _, r := range d.Related {
+		j.Related = append(j.Related, JSONRelated{Position: NewJSONPosition(r.Position), Message: r.Message})
+	}
+	return j
+}
+
+// WriteJSON は診断を {"diagnostics": [...]} の形式で出力する
+func WriteJSON(w io.Writer, list List) error {
+	out := struct {
+		Diagnostics []JSONDiagnostic `json:"diagnostics"`
+	}{Diagnostics: make([]JSONDiagnostic, 0, len(list))}
+	for _, d := range list {
+		out.Diagnostics = append(out.Diagnostics, d.ToJSON())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
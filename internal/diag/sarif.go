@@ -0,0 +1,196 @@
+package diag
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/rmocchy/cire/internal/version"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifSrcRoot は相対パスの基準となるディレクトリを表す uriBaseId
+	sarifSrcRoot = "%SRCROOT%"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool sarifTool `json:"tool"`
+	// OriginalURIBaseIDs は uriBaseId が指すディレクトリの絶対 URI
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	Help                 sarifMessage       `json:"help"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string           `json:"ruleId"`
+	RuleIndex        int              `json:"ruleIndex"`
+	Level            string           `json:"level"`
+	Message          sarifMessage     `json:"message"`
+	Locations        []sarifLocation  `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation  `json:"relatedLocations,omitempty"`
+	Properties       *sarifProperties `json:"properties,omitempty"`
+}
+
+// sarifProperties は SARIF の結果に付ける cire 固有の情報
+type sarifProperties struct {
+	Profile string `json:"profile,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                   `json:"id,omitempty"`
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	Message          *sarifMessage          `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// WriteSARIF は診断を SARIF 2.1.0 の形式で出力する。
+// 相対パスの位置は %SRCROOT%（通常はリポジトリのルート）からの相対として扱われる
+func WriteSARIF(w io.Writer, list List) error {
+	return WriteSARIFWithRoot(w, list, "")
+}
+
+// WriteSARIFWithRoot は root 以下の絶対パスの位置を root からの相対パスにして SARIF 2.1.0 の形式で出力する。
+// root は %SRCROOT% の実体として originalUriBaseIds に記録するため、カレントディレクトリによらず同じ URI になる
+func WriteSARIFWithRoot(w io.Writer, list List, root string) error {
+	driver := sarifDriver{
+		Name:           "cire",
+		Version:        version.Version(),
+		InformationURI: "https://github.com/rmocchy/cire",
+		Rules:          make([]sarifRule, 0, len(Rules)),
+	}
+	ruleIndex := make(map[Code]int, len(Rules))
+	for i, r := range Rules {
+		ruleIndex[r.Code] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   string(r.Code),
+			Name:                 r.Name,
+			ShortDescription:     sarifMessage{Text: r.Description},
+			Help:                 sarifMessage{Text: r.Help},
+			DefaultConfiguration: sarifConfiguration{Level: string(r.Severity)},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(list))
+	for _, d := range list {
+		result := sarifResult{
+			RuleID:    string(d.Code),
+			RuleIndex: ruleIndex[d.Code],
+			Level:     string(d.Severity),
+			Message:   sarifMessage{Text: d.Text()},
+		}
+		if d.Profile != "" {
+			result.Properties = &sarifProperties{Profile: d.Profile}
+		}
+		if loc := sarifPhysical(d.Position, root); loc != nil {
+			result.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		for i, r := range d.Related {
+			id := i + 1
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				ID:               &id,
+				PhysicalLocation: sarifPhysical(r.Position, root),
+				Message:          &sarifMessage{Text: r.Message},
+			})
+		}
+		results = append(results, result)
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: results}
+	if root != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{
+			sarifSrcRoot: {URI: fileURI(root) + "/"},
+		}
+	}
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// sarifPhysical は位置を SARIF の物理的な位置に変換する。ファイルが不明な場合は nil。
+// root 以下の絶対パスは root からの相対パスにする
+func sarifPhysical(pos token.Position, root string) *sarifPhysicalLocation {
+	if pos.Filename == "" {
+		return nil
+	}
+	filename := pos.Filename
+	if root != "" && filepath.IsAbs(filename) {
+		if rel, err := filepath.Rel(root, filename); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			filename = rel
+		}
+	}
+	loc := &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(filename)}}
+	if !filepath.IsAbs(filename) {
+		loc.ArtifactLocation.URIBaseID = sarifSrcRoot
+	} else {
+		loc.ArtifactLocation.URI = fileURI(filename)
+	}
+	if pos.Line > 0 {
+		loc.Region = &sarifRegion{StartLine: pos.Line, StartColumn: pos.Column}
+	}
+	return loc
+}
+
+// fileURI は絶対パスを file スキームの URI に変換する
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows のドライブレター（C:/...）
+		path = "/" + path
+	}
+	return "file://" + path
+}
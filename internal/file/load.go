@@ -8,13 +8,41 @@ import (
 	"golang.org/x/tools/go/packages"
 )
 
+// LoadOptions はパッケージのロード方法を指定する
+type LoadOptions struct {
+	// Patterns はロードするパッケージパターン。空の場合は "./..." を使う
+	Patterns []string
+	// BuildFlags は go コマンドに渡すビルドフラグ（例: "-tags=cire"）
+	BuildFlags []string
+	// OmitInputDir が true の場合は入力ファイルのディレクトリをロード対象に加えない。
+	// まだ Go ファイルの無いディレクトリに入力ファイルを作る場合に使う
+	OmitInputDir bool
+	// Overlay は保存されていないファイルの内容（キーは絶対パス）。ディスク上の内容の代わりに使う
+	Overlay map[string][]byte
+}
+
 // LoadPackagesFromFile は指定されたファイルからパッケージをロードする
-func LoadAllPkgsFromPath(path string) ([]*packages.Package, error) {
+func LoadAllPkgsFromPath(path string, opts *LoadOptions) ([]*packages.Package, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	patterns := opts.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
 	// ファイルのディレクトリを取得
-	dir := filepath.Dir(path)
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	// 入力ファイルのパッケージは常にロード対象に含める
+	if !opts.OmitInputDir {
+		patterns = append(patterns[:len(patterns):len(patterns)], dir)
+	}
 
 	// go.modファイルを探してモジュールルートを見つける
-	moduleRoot := findModuleRoot(dir)
+	moduleRoot := FindModuleRoot(dir)
 	if moduleRoot == "" {
 		moduleRoot = dir
 	}
@@ -28,12 +56,15 @@ func LoadAllPkgsFromPath(path string) ([]*packages.Package, error) {
 			packages.NeedDeps |
 			packages.NeedTypes |
 			packages.NeedSyntax |
-			packages.NeedTypesInfo,
-		Dir: moduleRoot, // モジュールルートを設定
+			packages.NeedTypesInfo |
+			packages.NeedModule,
+		Dir:        moduleRoot, // モジュールルートを設定
+		BuildFlags: opts.BuildFlags,
+		Overlay:    opts.Overlay,
 	}
 
 	// ファイルが含まれるパッケージとその依存関係をロード
-	pkgs, err := packages.Load(cfg, "./...")
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load packages: %w", err)
 	}
@@ -45,8 +76,8 @@ func LoadAllPkgsFromPath(path string) ([]*packages.Package, error) {
 	return pkgs, nil
 }
 
-// findModuleRoot はgo.modファイルを探してモジュールルートを返す
-func findModuleRoot(startDir string) string {
+// FindModuleRoot はgo.modファイルを探してモジュールルートを返す。見つからない場合は空文字列
+func FindModuleRoot(startDir string) string {
 	dir := startDir
 	for {
 		goModPath := filepath.Join(dir, "go.mod")
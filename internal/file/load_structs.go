@@ -1,9 +1,11 @@
 package file
 
 import (
+	"cmp"
 	"fmt"
 	"go/types"
 	"path/filepath"
+	"slices"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -13,57 +15,52 @@ func LoadNamedStructs(path string, pkgs []*packages.Package) ([]*types.Named, er
 	if fileName == "" {
 		return nil, fmt.Errorf("invalid file path: %s", path)
 	}
-	pkgPath, err := resolvePackagePath(path)
+	p, err := FindPackageOfFile(path, pkgs)
 	if err != nil {
 		return nil, err
 	}
 
 	namedStructs := make([]*types.Named, 0)
-	for _, p := range pkgs {
-		if p.PkgPath != *pkgPath {
+	for _, name := range p.Types.Scope().Names() {
+		obj := p.Types.Scope().Lookup(name)
+		if _, ok := obj.(*types.TypeName); !ok {
 			continue
 		}
-		for _, name := range p.Types.Scope().Names() {
-			obj := p.Types.Scope().Lookup(name)
-			if _, ok := obj.(*types.TypeName); !ok {
-				continue
-			}
-			named, ok := obj.Type().(*types.Named)
-			if !ok {
-				continue
-			}
-			pos := named.Obj().Pos()
-			position := p.Fset.Position(pos)
-			defFileName := filepath.Base(position.Filename)
-			if defFileName != fileName {
-				continue
-			}
-			if _, ok := named.Underlying().(*types.Struct); ok {
-				namedStructs = append(namedStructs, named)
-			}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		pos := named.Obj().Pos()
+		position := p.Fset.Position(pos)
+		defFileName := filepath.Base(position.Filename)
+		if defFileName != fileName {
+			continue
+		}
+		if _, ok := named.Underlying().(*types.Struct); ok {
+			namedStructs = append(namedStructs, named)
 		}
 	}
 
+	// Scope().Names() は名前順なので、ソースコード上の定義順に並べ直す
+	slices.SortStableFunc(namedStructs, func(a, b *types.Named) int {
+		return cmp.Compare(a.Obj().Pos(), b.Obj().Pos())
+	})
+
 	return namedStructs, nil
 }
 
-func resolvePackagePath(filePath string) (*string, error) {
-	dir := filepath.Dir(filePath)
-
-	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedModule,
-		Dir:  dir,
-	}
-
-	pkgs, err := packages.Load(cfg, ".")
+// FindPackageOfFile はロード済みのパッケージから指定されたファイルを含むパッケージを探す
+func FindPackageOfFile(path string, pkgs []*packages.Package) (*packages.Package, error) {
+	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load package: %w", err)
+		return nil, fmt.Errorf("failed to resolve file path: %w", err)
 	}
-
-	if len(pkgs) == 0 {
-		return nil, fmt.Errorf("no package found for file: %s", filePath)
+	for _, p := range pkgs {
+		for _, f := range p.CompiledGoFiles {
+			if f == absPath {
+				return p, nil
+			}
+		}
 	}
-
-	pkgPath := pkgs[0].PkgPath
-	return &pkgPath, nil
+	return nil, fmt.Errorf("no package found for file: %s", path)
 }
@@ -0,0 +1,67 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// overlayJSON は go コマンドの -overlay と同じ形式のオーバーレイファイル
+type overlayJSON struct {
+	// Replace はファイルのパスから、代わりに読む内容のファイルのパスへの対応
+	Replace map[string]string
+}
+
+// LoadOverlay は go コマンドの -overlay と同じ形式（{"Replace": {"元のパス": "内容のファイルのパス"}}）の
+// オーバーレイファイルを読み、ファイルの絶対パスから内容への対応を返す。
+// path が "-" の場合は標準入力から読む。相対パスはカレントディレクトリを基準にする
+func LoadOverlay(path string) (map[string][]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay file: %w", err)
+	}
+
+	var parsed overlayJSON
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse overlay file: %w", err)
+	}
+	overlay := make(map[string][]byte, len(parsed.Replace))
+	for from, to := range parsed.Replace {
+		// go コマンドでは空の置換先はファイルの削除を表すが、packages.Config.Overlay では表現できない
+		if to == "" {
+			return nil, fmt.Errorf("overlay: deleting %s is not supported", from)
+		}
+		abs, err := filepath.Abs(from)
+		if err != nil {
+			return nil, fmt.Errorf("overlay: failed to resolve %s: %w", from, err)
+		}
+		content, err := os.ReadFile(to)
+		if err != nil {
+			return nil, fmt.Errorf("overlay: failed to read replacement of %s: %w", from, err)
+		}
+		overlay[abs] = content
+	}
+	return overlay, nil
+}
+
+// ReadFile はオーバーレイに内容があればそれを、無ければディスク上のファイルの内容を返す
+func ReadFile(path string, overlay map[string][]byte) ([]byte, error) {
+	if len(overlay) > 0 {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if content, ok := overlay[abs]; ok {
+			return content, nil
+		}
+	}
+	return os.ReadFile(path)
+}
@@ -4,20 +4,18 @@ import (
 	"fmt"
 	"go/parser"
 	"go/token"
-	"path"
 )
 
-func ExtractPackageName(filePath string) (*string, error) {
+// ExtractPackageName はファイルの package 句の名前を返す。オーバーレイに内容があればディスク上の内容の代わりに使う
+func ExtractPackageName(filePath string, overlay map[string][]byte) (*string, error) {
+	src, err := ReadFile(filePath, overlay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
 	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, filePath, nil, parser.PackageClauseOnly)
+	f, err := parser.ParseFile(fset, filePath, src, parser.PackageClauseOnly)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse file: %w", err)
 	}
 	return &f.Name.Name, nil
 }
-
-// PkgNameFromPath は pkgPath（例: "github.com/foo/bar/baz"）から
-// パッケージ名（例: "baz"）を返す。
-func PkgNameFromPath(pkgPath string) string {
-	return path.Base(pkgPath)
-}
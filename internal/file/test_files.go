@@ -0,0 +1,43 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FindInTestFiles は dir の _test.go ファイル（オーバーレイだけにあるファイルを含む）から、
+// 空白を除くと prefix で始まる行を探して位置を返す。ロードしないテストファイルに書かれたアノテーションを見つけるために使う
+func FindInTestFiles(dir, prefix string, overlay map[string][]byte) ([]token.Position, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(absDir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	for path := range overlay {
+		if filepath.Dir(path) == absDir && strings.HasSuffix(path, "_test.go") && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	found := make([]token.Position, 0)
+	for _, path := range paths {
+		content, err := ReadFile(path, overlay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read test file: %w", err)
+		}
+		for i, line := range bytes.Split(content, []byte("\n")) {
+			if bytes.HasPrefix(bytes.TrimSpace(line), []byte(prefix)) {
+				found = append(found, token.Position{Filename: path, Line: i + 1, Column: 1})
+			}
+		}
+	}
+	return found, nil
+}
@@ -6,3 +6,9 @@ import (
 
 //go:embed wire.go.tmpl
 var wireTemplate string
+
+//go:embed wire_ciretest.go.tmpl
+var wireTestTemplate string
+
+//go:embed providers_gen.go.tmpl
+var packageSetTemplate string
@@ -1,88 +1,212 @@
 package generate
 
 import (
-	"bytes"
 	"fmt"
-	"go/format"
-	"html/template"
 	"slices"
+	"strings"
 )
 
+// TestBuildTag はテスト用のインジェクタのファイルを有効にするビルドタグ。
+// wire はテストファイルを読まないため、テスト用のインジェクタは通常のファイルにこのタグを付けて生成し、
+// wire gen -tags ciretest で読ませる
+const TestBuildTag = "ciretest"
+
 // 生成に必要な型定義
 type GenerateConfig struct {
 	PackageName string
+	// PackagePath は生成ファイルが属するパッケージのパス。同一パッケージのプロバイダは修飾せずに参照する
+	PackagePath string
 	StructSets  []StructSet
+	// Template は WireData を受け取る text/template のテキスト。空の場合は組み込みのテンプレートを使う
+	Template string
+	// Test が true の場合は、Template が空のときにテスト用のインジェクタの組み込みテンプレートを使う
+	Test bool
+	// WithTest が true の場合は、テスト用のインジェクタのファイルも生成されるものとして、
+	// 通常の生成ファイルを TestBuildTag のビルドでは無効にする
+	WithTest bool
+	// SharedSets が true の場合は、複数のルート構造体が使うプロバイダをパッケージごとの共通のセットにまとめ、
+	// 各ルート構造体のセットから参照する
+	SharedSets bool
+	// PackageSets は公開のプロバイダセット（PackageSetName）を生成するパッケージのパス。
+	// これらのパッケージが所有するプロバイダはルート構造体のセットから除き、代わりにパッケージのセットを参照する
+	PackageSets []string
+	// Profile は生成ファイルのプロファイル。空でない場合は wireinject に加えてプロファイル名のビルドタグで有効にする
+	Profile string
+	// Generator は生成ファイルのヘッダーに記録する情報
+	Generator GeneratorData
 }
 
 type StructSet struct {
 	RootStructName string
-	Providers      []Provider
+	// SetName はプロバイダセットの変数名。空の場合は "<RootStructName>Set"
+	SetName string
+	// InjectorName はインジェクタ関数名。空の場合は "Initialize<RootStructName>"
+	InjectorName string
+	Providers    []Provider
 }
 
+// ProviderKind はプロバイダの種類
+type ProviderKind string
+
+const (
+	// KindFunc はコンストラクタ関数。空の場合も関数として扱う
+	KindFunc ProviderKind = "func"
+	// KindBind はインターフェースを実装の型に束縛する（wire.Bind）
+	KindBind ProviderKind = "bind"
+	// KindStruct は構造体のフィールドを全て注入して生成する（wire.Struct）
+	KindStruct ProviderKind = "struct"
+	// KindField は構造体のフィールドの値を使う（wire.FieldsOf）
+	KindField ProviderKind = "field"
+	// KindSet は他のパッケージの公開のプロバイダセット（GenerateConfig.PackageSets）。Name はセットの変数名
+	KindSet ProviderKind = "set"
+)
+
+// Provider は生成コードから参照するプロバイダ
 type Provider struct {
 	PkgPath string
+	// PkgName は types.Package.Name() によるパッケージ名（パスの末尾とは限らない）
+	PkgName string
+	// Name はパッケージ修飾なしの関数名。KindBind ではインターフェース名、KindStruct では構造体名
+	Name string
+	// Alias は生成コード内でパッケージを参照する名前。Generate が衝突しないよう割り当てる。
+	// 生成ファイルと同一パッケージのプロバイダでは空
+	Alias string
+	Kind  ProviderKind
+	// Source は KindBind の実装の型、KindField のフィールドを持つ構造体
+	Source *TypeRef
+	// Field は KindField のフィールド名
+	Field string
+}
+
+// TypeRef は生成コードから参照する名前付き型
+type TypeRef struct {
+	PkgPath string
+	PkgName string
 	Name    string
+	// Pointer は型をポインタとして参照するかどうか
+	Pointer bool
+	// Alias は生成コード内でパッケージを参照する名前。Generate が割り当てる
+	Alias string
 }
 
-func (c *GenerateConfig) AddStructSet(rootStructName string, providers []Provider) {
-	c.StructSets = append(c.StructSets, StructSet{
-		RootStructName: rootStructName,
-		Providers:      providers,
-	})
+// Expr は生成コード内で型を参照する式を返す（例: "*service.Config"）
+func (r TypeRef) Expr() string {
+	expr := qualifiedIdent(r.Alias, r.Name)
+	if r.Pointer {
+		return "*" + expr
+	}
+	return expr
+}
+
+// Expr は生成コード内でプロバイダを参照する式を返す
+func (p Provider) Expr() string {
+	ident := qualifiedIdent(p.Alias, p.Name)
+	switch p.Kind {
+	case KindBind:
+		return fmt.Sprintf("wire.Bind(new(%s), new(%s))", ident, p.Source.Expr())
+	case KindStruct:
+		return fmt.Sprintf("wire.Struct(new(%s), \"*\")", ident)
+	case KindField:
+		return fmt.Sprintf("wire.FieldsOf(new(%s), %q)", p.Source.Expr(), p.Field)
+	}
+	return ident
+}
+
+func qualifiedIdent(alias, name string) string {
+	if alias == "" {
+		return name
+	}
+	return alias + "." + name
+}
+
+func (c *GenerateConfig) AddStructSet(set StructSet) {
+	c.StructSets = append(c.StructSets, set)
 }
 
 func (c *GenerateConfig) SetPackageName(pkgName string) {
 	c.PackageName = pkgName
 }
 
+func (c *GenerateConfig) SetPackagePath(pkgPath string) {
+	c.PackagePath = pkgPath
+}
+
 func (c *GenerateConfig) Generate() ([]byte, error) {
-	imports := make(map[string]bool)
-	for _, set := range c.StructSets {
-		for _, provider := range set.Providers {
-			imports[provider.PkgPath] = true
-		}
+	structSets := c.StructSets
+	if len(c.PackageSets) > 0 {
+		structSets = c.usePackageSets()
 	}
-
-	importList := make([]string, 0, len(imports))
-	for imp := range imports {
-		importList = append(importList, imp)
+	all := make([]Provider, 0)
+	for _, set := range structSets {
+		all = append(all, set.Providers...)
+	}
+	aliases, err := newImportAliases(all, c.PackagePath)
+	if err != nil {
+		return nil, err
 	}
 
-	providers := make(map[string][]string)
-	for _, set := range c.StructSets {
-		providerNames := make([]string, 0, len(set.Providers))
+	// ルート構造体の順序は StructSets の順序（ソースコード上の定義順）を保つ
+	providerSet := make([]ProviderSetData, 0, len(structSets))
+	for _, set := range structSets {
+		providers := make([]Provider, 0, len(set.Providers))
 		for _, provider := range set.Providers {
-			providerNames = append(providerNames, provider.Name)
+			providers = append(providers, aliases.qualify(provider))
+		}
+		// providerをソート
+		slices.SortFunc(providers, func(a, b Provider) int {
+			return strings.Compare(a.Expr(), b.Expr())
+		})
+		setName := set.SetName
+		if setName == "" {
+			setName = set.RootStructName + "Set"
+		}
+		injectorName := set.InjectorName
+		if injectorName == "" {
+			injectorName = "Initialize" + set.RootStructName
 		}
-		providers[set.RootStructName] = providerNames
-	}
-
-	// providerをソート
-	providerSet := make([]ProviderSetData, 0, len(providers))
-	for key := range providers {
-		providerNames := providers[key]
-		slices.Sort(providerNames)
 		providerSet = append(providerSet, ProviderSetData{
-			StructName: key,
-			Providers:  providerNames,
+			StructName:   set.RootStructName,
+			SetName:      setName,
+			InjectorName: injectorName,
+			Providers:    providers,
 		})
 	}
 
+	var shared []SharedSetData
+	if c.SharedSets {
+		suffix := "SharedSet"
+		if c.Test {
+			suffix = "TestSharedSet"
+		}
+		providerSet, shared = shareProviders(providerSet, c.PackageName, suffix)
+	}
+
 	data := WireData{
+		Version:      DataVersion,
+		Generator:    c.Generator,
 		PackageName:  c.PackageName,
-		Imports:      importList,
+		PackagePath:  c.PackagePath,
+		Profile:      c.Profile,
+		TestTag:      c.testTag(),
+		Imports:      aliases.imports(),
 		ProviderSets: providerSet,
+		SharedSets:   shared,
 	}
 
-	tmpl := template.Must(template.New("wire").Parse(wireTemplate))
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return nil, fmt.Errorf("failed to execute template: %w", err)
+	text := c.Template
+	if text == "" {
+		text = wireTemplate
+		if c.Test {
+			text = wireTestTemplate
+		}
 	}
+	return executeTemplate(text, data)
+}
 
-	formatted, err := format.Source(buf.Bytes())
-	if err != nil {
-		formatted = buf.Bytes()
+// testTag はテスト用のインジェクタのファイルがある場合に TestBuildTag を返す
+func (c *GenerateConfig) testTag() string {
+	if c.Test || c.WithTest {
+		return TestBuildTag
 	}
-	return formatted, nil
+	return ""
 }
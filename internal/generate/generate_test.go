@@ -21,7 +21,7 @@ func TestGenerateConfig_Generate(t *testing.T) {
 					{
 						RootStructName: "App",
 						Providers: []Provider{
-							{PkgPath: "example.com/myapp/service", Name: "service.NewUserService"},
+							{PkgPath: "example.com/myapp/service", PkgName: "service", Name: "NewUserService"},
 						},
 					},
 				},
@@ -44,13 +44,13 @@ func TestGenerateConfig_Generate(t *testing.T) {
 					{
 						RootStructName: "Server",
 						Providers: []Provider{
-							{PkgPath: "example.com/handler", Name: "handler.NewHandler"},
+							{PkgPath: "example.com/handler", PkgName: "handler", Name: "NewHandler"},
 						},
 					},
 					{
 						RootStructName: "Client",
 						Providers: []Provider{
-							{PkgPath: "example.com/repo", Name: "repo.NewRepository"},
+							{PkgPath: "example.com/repo", PkgName: "repo", Name: "NewRepository"},
 						},
 					},
 				},
@@ -76,9 +76,9 @@ func TestGenerateConfig_Generate(t *testing.T) {
 					{
 						RootStructName: "App",
 						Providers: []Provider{
-							{PkgPath: "example.com/svc", Name: "svc.NewZService"},
-							{PkgPath: "example.com/svc", Name: "svc.NewAService"},
-							{PkgPath: "example.com/svc", Name: "svc.NewMService"},
+							{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewZService"},
+							{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewAService"},
+							{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewMService"},
 						},
 					},
 				},
@@ -98,8 +98,8 @@ func TestGenerateConfig_Generate(t *testing.T) {
 					{
 						RootStructName: "App",
 						Providers: []Provider{
-							{PkgPath: "example.com/svc", Name: "svc.NewZService"},
-							{PkgPath: "example.com/svc", Name: "svc.NewAService"},
+							{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewZService"},
+							{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewAService"},
 						},
 					},
 				},
@@ -118,8 +118,8 @@ func TestGenerateConfig_Generate(t *testing.T) {
 					{
 						RootStructName: "App",
 						Providers: []Provider{
-							{PkgPath: "example.com/svc", Name: "svc.NewFooService"},
-							{PkgPath: "example.com/svc", Name: "svc.NewBarService"},
+							{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewFooService"},
+							{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewBarService"},
 						},
 					},
 				},
@@ -134,7 +134,7 @@ func TestGenerateConfig_Generate(t *testing.T) {
 					{
 						RootStructName: "Foo",
 						Providers: []Provider{
-							{PkgPath: "example.com/foo", Name: "foo.NewFoo"},
+							{PkgPath: "example.com/foo", PkgName: "foo", Name: "NewFoo"},
 						},
 					},
 				},
@@ -171,7 +171,7 @@ func TestGenerateConfig_Generate_OutputIsFormatted(t *testing.T) {
 			{
 				RootStructName: "App",
 				Providers: []Provider{
-					{PkgPath: "example.com/svc", Name: "svc.NewService"},
+					{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewService"},
 				},
 			},
 		},
@@ -199,8 +199,8 @@ func TestGenerateConfig_Generate_DuplicateImportsDeduped(t *testing.T) {
 			{
 				RootStructName: "App",
 				Providers: []Provider{
-					{PkgPath: "example.com/svc", Name: "svc.NewFoo"},
-					{PkgPath: "example.com/svc", Name: "svc.NewBar"},
+					{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewFoo"},
+					{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewBar"},
 				},
 			},
 		},
@@ -217,3 +217,303 @@ func TestGenerateConfig_Generate_DuplicateImportsDeduped(t *testing.T) {
 		t.Errorf("同一PkgPathのimportが重複しています: %d回出現", count)
 	}
 }
+
+func TestGenerateConfig_Generate_KeepsStructSetOrder(t *testing.T) {
+	config := &GenerateConfig{
+		PackageName: "main",
+		StructSets: []StructSet{
+			{RootStructName: "Zeta", Providers: []Provider{{PkgPath: "example.com/z", PkgName: "z", Name: "NewZ"}}},
+			{RootStructName: "Alpha", Providers: []Provider{{PkgPath: "example.com/a", PkgName: "a", Name: "NewA"}}},
+			{RootStructName: "Mid", Providers: []Provider{{PkgPath: "example.com/m", PkgName: "m", Name: "NewM"}}},
+		},
+	}
+
+	for i := 0; i < 20; i++ {
+		got, err := config.Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		output := string(got)
+
+		zeta := strings.Index(output, "var ZetaSet")
+		alpha := strings.Index(output, "var AlphaSet")
+		mid := strings.Index(output, "var MidSet")
+		if !(zeta < alpha && alpha < mid) {
+			t.Fatalf("StructSets の順序が保たれていません\n出力:\n%s", output)
+		}
+
+		imports := []int{
+			strings.Index(output, `"example.com/a"`),
+			strings.Index(output, `"example.com/m"`),
+			strings.Index(output, `"example.com/z"`),
+		}
+		if !(imports[0] < imports[1] && imports[1] < imports[2]) {
+			t.Fatalf("import がソートされていません\n出力:\n%s", output)
+		}
+	}
+}
+
+func TestGenerateConfig_Generate_ImportAliases(t *testing.T) {
+	tests := []struct {
+		name        string
+		providers   []Provider
+		wantContain []string
+		wantMissing []string
+	}{
+		{
+			name: "メジャーバージョンのサフィックスを持つパッケージ",
+			providers: []Provider{
+				{PkgPath: "github.com/foo/bar/v2", PkgName: "bar", Name: "NewBar"},
+			},
+			wantContain: []string{
+				`bar "github.com/foo/bar/v2"`,
+				"bar.NewBar,",
+			},
+			wantMissing: []string{"v2.NewBar"},
+		},
+		{
+			name: "ディレクトリ名とパッケージ名が異なる",
+			providers: []Provider{
+				{PkgPath: "github.com/redis/go-redis", PkgName: "redis", Name: "NewClient"},
+			},
+			wantContain: []string{
+				`redis "github.com/redis/go-redis"`,
+				"redis.NewClient,",
+			},
+		},
+		{
+			name: "同名パッケージの衝突",
+			providers: []Provider{
+				{PkgPath: "example.com/b/service", PkgName: "service", Name: "NewB"},
+				{PkgPath: "example.com/a/service", PkgName: "service", Name: "NewA"},
+			},
+			wantContain: []string{
+				`"example.com/a/service"`,
+				`service2 "example.com/b/service"`,
+				"service.NewA,",
+				"service2.NewB,",
+			},
+		},
+		{
+			name: "wire と同名のパッケージ",
+			providers: []Provider{
+				{PkgPath: "example.com/internal/wire", PkgName: "wire", Name: "NewWire"},
+			},
+			wantContain: []string{
+				`wire2 "example.com/internal/wire"`,
+				"wire2.NewWire,",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &GenerateConfig{
+				PackageName: "main",
+				StructSets:  []StructSet{{RootStructName: "App", Providers: tt.providers}},
+			}
+			got, err := config.Generate()
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			output := string(got)
+			for _, want := range tt.wantContain {
+				if !strings.Contains(output, want) {
+					t.Errorf("Generate() 出力に %q が含まれていません\n出力:\n%s", want, output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(output, missing) {
+					t.Errorf("Generate() 出力に %q が含まれています\n出力:\n%s", missing, output)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateConfig_Generate_SamePackageProvider(t *testing.T) {
+	config := &GenerateConfig{
+		PackageName: "main",
+		PackagePath: "example.com/app",
+		StructSets: []StructSet{
+			{
+				RootStructName: "App",
+				Providers: []Provider{
+					{PkgPath: "example.com/app", PkgName: "main", Name: "NewConfig"},
+					{PkgPath: "example.com/app/service", PkgName: "service", Name: "NewService"},
+				},
+			},
+		},
+	}
+
+	got, err := config.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	output := string(got)
+
+	if !strings.Contains(output, "\tNewConfig,") {
+		t.Errorf("同一パッケージのプロバイダが修飾なしで参照されていません\n出力:\n%s", output)
+	}
+	if strings.Contains(output, "main.NewConfig") {
+		t.Errorf("同一パッケージのプロバイダがパッケージ名で修飾されています\n出力:\n%s", output)
+	}
+	if strings.Contains(output, `"example.com/app"`) {
+		t.Errorf("生成ファイル自身のパッケージが import されています\n出力:\n%s", output)
+	}
+	if !strings.Contains(output, "service.NewService,") {
+		t.Errorf("他パッケージのプロバイダが修飾されていません\n出力:\n%s", output)
+	}
+}
+
+func TestGenerateConfig_Generate_ProviderKinds(t *testing.T) {
+	config := &GenerateConfig{
+		PackageName: "main",
+		PackagePath: "example.com/app",
+		StructSets: []StructSet{
+			{
+				RootStructName: "App",
+				Providers: []Provider{
+					{
+						PkgPath: "example.com/app/service", PkgName: "service", Name: "Store", Kind: KindBind,
+						Source: &TypeRef{PkgPath: "example.com/app/store", PkgName: "store", Name: "MemoryStore", Pointer: true},
+					},
+					{PkgPath: "example.com/app/store", PkgName: "store", Name: "NewMemoryStore"},
+					{PkgPath: "example.com/app/config", PkgName: "config", Name: "Config", Kind: KindStruct},
+					{
+						PkgPath: "example.com/app", PkgName: "main", Name: "Env", Kind: KindField, Field: "Region",
+						Source: &TypeRef{PkgPath: "example.com/app", PkgName: "main", Name: "Env", Pointer: true},
+					},
+				},
+			},
+		},
+	}
+
+	got, err := config.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	output := string(got)
+
+	for _, want := range []string{
+		`"example.com/app/store"`,
+		"wire.Bind(new(service.Store), new(*store.MemoryStore)),",
+		`wire.Struct(new(config.Config), "*"),`,
+		`wire.FieldsOf(new(*Env), "Region"),`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("出力に %q が含まれていません\n出力:\n%s", want, output)
+		}
+	}
+}
+
+func TestGenerateConfig_Generate_SharedSets(t *testing.T) {
+	userRepo := Provider{PkgPath: "example.com/app/repository", PkgName: "repository", Name: "NewUserRepository"}
+	productRepo := Provider{PkgPath: "example.com/app/repository", PkgName: "repository", Name: "NewProductRepository"}
+	config := &GenerateConfig{
+		PackageName: "main",
+		PackagePath: "example.com/app",
+		SharedSets:  true,
+		StructSets: []StructSet{
+			{RootStructName: "UserApp", Providers: []Provider{
+				{PkgPath: "example.com/app/handler", PkgName: "handler", Name: "NewUserHandler"},
+				userRepo,
+				{PkgPath: "example.com/app", PkgName: "main", Name: "NewConfig"},
+			}},
+			{RootStructName: "OrderApp", Providers: []Provider{
+				{PkgPath: "example.com/app/handler", PkgName: "handler", Name: "NewOrderHandler"},
+				userRepo,
+				productRepo,
+				{PkgPath: "example.com/app", PkgName: "main", Name: "NewConfig"},
+			}},
+			{RootStructName: "ProductApp", Providers: []Provider{productRepo}},
+		},
+	}
+
+	got, err := config.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	output := string(got)
+	for _, want := range []string{
+		"// MainSharedSet is the Wire provider set shared by UserApp, OrderApp\nvar MainSharedSet = wire.NewSet(\n\tNewConfig,\n",
+		"// RepositorySharedSet is the Wire provider set shared by UserApp, OrderApp, ProductApp\nvar RepositorySharedSet = wire.NewSet(\n\trepository.NewProductRepository,\n\trepository.NewUserRepository,\n",
+		"var UserAppSet = wire.NewSet(\n\tMainSharedSet,\n\tRepositorySharedSet,\n\thandler.NewUserHandler,\n",
+		"var ProductAppSet = wire.NewSet(\n\tRepositorySharedSet,\n\twire.Struct(new(ProductApp), \"*\"),\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("出力に %q が含まれていません\n出力:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "repository.NewUserRepository") != 1 {
+		t.Errorf("共有するプロバイダが複数のセットに出力されています\n出力:\n%s", output)
+	}
+	if strings.Index(output, "var RepositorySharedSet") > strings.Index(output, "var UserAppSet") {
+		t.Errorf("共通のセットがルート構造体のセットより後に出力されています\n出力:\n%s", output)
+	}
+}
+
+func TestGenerateConfig_GeneratePackageSets(t *testing.T) {
+	config := &GenerateConfig{
+		PackageName: "main",
+		PackagePath: "example.com/app",
+		PackageSets: []string{"example.com/app/service", "example.com/app/store"},
+		StructSets: []StructSet{
+			{RootStructName: "App", Providers: []Provider{
+				{PkgPath: "example.com/app/service", PkgName: "service", Name: "NewUserService"},
+				{
+					PkgPath: "example.com/app/service", PkgName: "service", Name: "Store", Kind: KindBind,
+					Source: &TypeRef{PkgPath: "example.com/app/store", PkgName: "store", Name: "MemoryStore", Pointer: true},
+				},
+				{PkgPath: "example.com/app/store", PkgName: "store", Name: "NewMemoryStore"},
+				{PkgPath: "example.com/lib/clock", PkgName: "clock", Name: "New"},
+			}},
+			{RootStructName: "Worker", Providers: []Provider{
+				{PkgPath: "example.com/app/store", PkgName: "store", Name: "NewMemoryStore"},
+			}},
+		},
+	}
+
+	got, err := config.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	output := string(got)
+	for _, want := range []string{
+		"var AppSet = wire.NewSet(\n\tclock.New,\n\tservice.ProviderSet,\n\tstore.ProviderSet,\n",
+		"var WorkerSet = wire.NewSet(\n\tstore.ProviderSet,\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("出力に %q が含まれていません\n出力:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "wire.Bind") || strings.Contains(output, "NewMemoryStore") {
+		t.Errorf("パッケージのセットに移したプロバイダが出力されています\n出力:\n%s", output)
+	}
+
+	files, err := config.GeneratePackageSets()
+	if err != nil {
+		t.Fatalf("GeneratePackageSets() error = %v", err)
+	}
+	if len(files) != 2 || files[0].PkgPath != "example.com/app/service" || files[1].PkgPath != "example.com/app/store" {
+		t.Fatalf("files = %v", files)
+	}
+	service, store := string(files[0].Source), string(files[1].Source)
+	if !strings.Contains(service, "package service") || !strings.Contains(service, "var ProviderSet = wire.NewSet(\n\tNewUserService,\n") {
+		t.Errorf("service のセットが正しくありません\n出力:\n%s", service)
+	}
+	// インターフェースの束縛は実装の型を持つパッケージに置く
+	for _, want := range []string{
+		"package store",
+		`"example.com/app/service"`,
+		"// ProviderSet is the Wire provider set of this package used by App, Worker\n",
+		"var ProviderSet = wire.NewSet(\n\tNewMemoryStore,\n\twire.Bind(new(service.Store), new(*MemoryStore)),\n",
+	} {
+		if !strings.Contains(store, want) {
+			t.Errorf("出力に %q が含まれていません\n出力:\n%s", want, store)
+		}
+	}
+	if strings.Contains(store, "go:build") {
+		t.Errorf("パッケージのセットにビルドタグが付いています\n出力:\n%s", store)
+	}
+}
@@ -0,0 +1,31 @@
+package generate
+
+import "regexp"
+
+var (
+	headerVersionRegexp = regexp.MustCompile(`(?m)^// cire version: (.+)$`)
+	headerCommandRegexp = regexp.MustCompile(`(?m)^// command: (.+)$`)
+	headerInputsRegexp  = regexp.MustCompile(`(?m)^// inputs: (\S+)$`)
+)
+
+// ParseHeader は生成ファイルのヘッダーから生成元の情報を読み取る。見つからない項目は空になる
+func ParseHeader(src []byte) GeneratorData {
+	var data GeneratorData
+	if m := headerVersionRegexp.FindSubmatch(src); m != nil {
+		data.Version = string(m[1])
+	}
+	if m := headerCommandRegexp.FindSubmatch(src); m != nil {
+		data.Command = string(m[1])
+	}
+	if m := headerInputsRegexp.FindSubmatch(src); m != nil {
+		data.InputHash = string(m[1])
+	}
+	return data
+}
+
+// StripVolatileHeader はヘッダーのうち入力に依存しない行（cire のバージョンとコマンドライン）を取り除く。
+// 生成結果を比較する際に、生成した環境の違いを無視するために使う
+func StripVolatileHeader(src []byte) []byte {
+	src = headerVersionRegexp.ReplaceAll(src, nil)
+	return headerCommandRegexp.ReplaceAll(src, nil)
+}
@@ -0,0 +1,69 @@
+package generate
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerate_Header(t *testing.T) {
+	config := &GenerateConfig{
+		PackageName: "main",
+		StructSets: []StructSet{
+			{RootStructName: "App", Providers: []Provider{{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewService"}}},
+		},
+		Generator: GeneratorData{
+			Version:   "v1.2.3",
+			Command:   "cire generate -f ./cire.go",
+			InputHash: "sha256:abc",
+		},
+	}
+
+	got, err := config.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), "wire.go", got, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if !ast.IsGenerated(f) {
+		t.Errorf("生成ファイルとして認識されません\n出力:\n%s", got)
+	}
+
+	// ヘッダーの後でもビルド制約が有効であること
+	found := false
+	for _, line := range strings.Split(string(got), "\n") {
+		if constraint.IsGoBuild(line) {
+			found = true
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	if !found {
+		t.Errorf("package 句より前に //go:build がありません\n出力:\n%s", got)
+	}
+
+	header := ParseHeader(got)
+	if header != config.Generator {
+		t.Errorf("ParseHeader() = %+v, want %+v", header, config.Generator)
+	}
+}
+
+func TestStripVolatileHeader(t *testing.T) {
+	a := []byte("// Code generated by cire. DO NOT EDIT.\n// cire version: v1\n// command: cire generate -f a.go\n// inputs: sha256:abc\n")
+	b := []byte("// Code generated by cire. DO NOT EDIT.\n// cire version: v2\n// command: cire generate -f a.go -j\n// inputs: sha256:abc\n")
+	if string(StripVolatileHeader(a)) != string(StripVolatileHeader(b)) {
+		t.Errorf("バージョンとコマンドラインの違いが無視されません")
+	}
+
+	c := []byte("// Code generated by cire. DO NOT EDIT.\n// cire version: v1\n// inputs: sha256:def\n")
+	if string(StripVolatileHeader(a)) == string(StripVolatileHeader(c)) {
+		t.Errorf("入力のハッシュの違いが無視されています")
+	}
+}
@@ -0,0 +1,92 @@
+package generate
+
+import (
+	"fmt"
+	"go/token"
+	"maps"
+	"path"
+	"slices"
+)
+
+// ImportData は生成コードの import 宣言
+type ImportData struct {
+	// Alias は import のエイリアス。パッケージ名とパスの末尾が一致する場合は空
+	Alias string
+	Path  string
+}
+
+// importAliases はパッケージパスごとに生成コード内で一意な参照名を割り当てる
+type importAliases struct {
+	byPath map[string]string
+	used   map[string]bool
+}
+
+// newImportAliases は providers が参照するパッケージに参照名を割り当てる。
+// 同名パッケージが複数ある場合はパスの昇順に name, name2, name3... を割り当てる。
+// localPkgPath（生成ファイル自身のパッケージ）は import せず、参照名も割り当てない
+func newImportAliases(providers []Provider, localPkgPath string) (*importAliases, error) {
+	names := make(map[string]string)
+	for _, p := range providers {
+		refs := []TypeRef{{PkgPath: p.PkgPath, PkgName: p.PkgName, Name: p.Name}}
+		if p.Source != nil {
+			refs = append(refs, *p.Source)
+		}
+		for _, ref := range refs {
+			if localPkgPath != "" && ref.PkgPath == localPkgPath {
+				continue
+			}
+			if ref.PkgName == "" {
+				return nil, fmt.Errorf("package name is empty for provider %s", ref.Name)
+			}
+			if existing, ok := names[ref.PkgPath]; ok && existing != ref.PkgName {
+				return nil, fmt.Errorf("package %s has conflicting names: %s and %s", ref.PkgPath, existing, ref.PkgName)
+			}
+			names[ref.PkgPath] = ref.PkgName
+		}
+	}
+
+	a := &importAliases{
+		byPath: make(map[string]string),
+		used:   map[string]bool{"wire": true},
+	}
+	for _, pkgPath := range slices.Sorted(maps.Keys(names)) {
+		name := names[pkgPath]
+		alias := name
+		for i := 2; a.used[alias] || token.IsKeyword(alias); i++ {
+			alias = fmt.Sprintf("%s%d", name, i)
+		}
+		a.used[alias] = true
+		a.byPath[pkgPath] = alias
+	}
+	return a, nil
+}
+
+// alias はパッケージパスに割り当てた参照名を返す。生成ファイル自身のパッケージでは空
+func (a *importAliases) alias(pkgPath string) string {
+	return a.byPath[pkgPath]
+}
+
+// qualify はプロバイダと実装の型に参照名を設定したコピーを返す
+func (a *importAliases) qualify(p Provider) Provider {
+	p.Alias = a.alias(p.PkgPath)
+	if p.Source != nil {
+		source := *p.Source
+		source.Alias = a.alias(source.PkgPath)
+		p.Source = &source
+	}
+	return p
+}
+
+// imports は import 宣言の一覧をパスの昇順で返す
+func (a *importAliases) imports() []ImportData {
+	result := make([]ImportData, 0, len(a.byPath))
+	for _, pkgPath := range slices.Sorted(maps.Keys(a.byPath)) {
+		alias := a.byPath[pkgPath]
+		// パス末尾から参照名が推測できない場合（/v2 や go-redis など）は明示的にエイリアスを付ける
+		if path.Base(pkgPath) == alias {
+			alias = ""
+		}
+		result = append(result, ImportData{Alias: alias, Path: pkgPath})
+	}
+	return result
+}
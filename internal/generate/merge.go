@@ -0,0 +1,470 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// UserMarker はプロバイダセット内で cire が管理する要素と利用者が追加した要素の境界を示すコメント。
+// このコメントより後ろの要素は再生成時にそのまま引き継がれる
+const UserMarker = "cire:user"
+
+// MergeResult は既存ファイルと生成結果をマージした結果
+type MergeResult struct {
+	Source []byte
+	// NeedsBackup は cire が所有しない内容を上書きしたため、既存ファイルのバックアップが必要かどうか
+	NeedsBackup bool
+}
+
+// Merge は既存の wire.go に含まれる利用者の宣言・import・プロバイダ式を保ったまま、
+// cire が所有する宣言を generated の内容で置き換える。
+//
+// cire が所有する宣言は以下のいずれか:
+//   - generated に同名の宣言があり、wire.NewSet / wire.Build を使う宣言
+//   - UserMarker を含む wire.NewSet の変数宣言（ルート構造体が削除された場合の古いセット）
+//   - cire が所有するセットだけを wire.Build するインジェクタ関数
+func Merge(existing, generated []byte) (*MergeResult, error) {
+	fset := token.NewFileSet()
+	oldFile, err := parser.ParseFile(fset, "existing.go", existing, parser.ParseComments)
+	if err != nil {
+		// 解析できないファイルは丸ごと置き換えるしかない
+		return &MergeResult{Source: generated, NeedsBackup: true}, nil
+	}
+	genFile, err := parser.ParseFile(fset, "generated.go", generated, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse generated source: %w", err)
+	}
+
+	old := newSourceFile(fset, oldFile, existing)
+	gen := newSourceFile(fset, genFile, generated)
+	imports := newImportRenamer(oldFile, genFile)
+
+	genDecls := gen.declsByName()
+	ownedSets := make(map[string]bool)
+	for name, decl := range old.declsByName() {
+		if _, ok := genDecls[name]; ok && isNewSetDecl(decl) {
+			ownedSets[name] = true
+		} else if isNewSetDecl(decl) && old.hasMarker(newSetCall(decl)) {
+			ownedSets[name] = true
+		}
+	}
+
+	result := &MergeResult{}
+	userDecls := make([]ast.Decl, 0)
+	for _, decl := range oldFile.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			continue
+		}
+		name := declName(decl)
+		switch {
+		case ownedSets[name]:
+			continue
+		case isInjectorOf(decl, ownedSets):
+			continue
+		case name != "" && genDecls[name] != nil:
+			// 同名の宣言を cire が生成するが、cire の形ではないため利用者の内容を失う
+			result.NeedsBackup = true
+			continue
+		}
+		userDecls = append(userDecls, decl)
+	}
+
+	// 既存のセットに利用者が追加したプロバイダ式を、再生成したセットへ引き継ぐ
+	insertions := make([]insertion, 0)
+	for name, decl := range genDecls {
+		if !isNewSetDecl(decl) || !ownedSets[name] {
+			continue
+		}
+		extras, dropped := old.userArgs(newSetCall(old.declsByName()[name]), gen.argTexts(newSetCall(decl)), imports)
+		if dropped {
+			result.NeedsBackup = true
+		}
+		if len(extras) == 0 {
+			continue
+		}
+		insertions = append(insertions, gen.appendArgs(newSetCall(decl), extras))
+	}
+	slices.SortFunc(insertions, func(a, b insertion) int { return b.offset - a.offset })
+	merged := append([]byte(nil), generated...)
+	for _, ins := range insertions {
+		merged = slices.Insert(merged, ins.offset, []byte(ins.text)...)
+	}
+
+	// 利用者の宣言を末尾に追加する
+	var buf bytes.Buffer
+	buf.Write(merged)
+	for _, decl := range userDecls {
+		buf.WriteString("\n")
+		buf.WriteString(imports.rewrite(old, decl, declStart(decl)))
+		buf.WriteString("\n")
+	}
+
+	// 利用者のコードが参照する import のうち、生成結果に無いものを引き継ぐ
+	mergedFset := token.NewFileSet()
+	mergedFile, err := parser.ParseFile(mergedFset, "merged.go", buf.Bytes(), parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse merged source: %w", err)
+	}
+	imported := make(map[string]bool)
+	for _, imp := range mergedFile.Imports {
+		_, importPath := importNameAndPath(imp)
+		imported[importPath] = true
+	}
+	for _, imp := range oldFile.Imports {
+		name, importPath := importNameAndPath(imp)
+		if imported[importPath] {
+			continue
+		}
+		if name == "_" || name == "." {
+			astutil.AddNamedImport(mergedFset, mergedFile, name, importPath)
+			continue
+		}
+		if alias := imports.names[importPath]; usesName(mergedFile, alias) {
+			explicit := alias
+			if imp.Name == nil && alias == name {
+				explicit = ""
+			}
+			astutil.AddNamedImport(mergedFset, mergedFile, explicit, importPath)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := format.Node(&out, mergedFset, mergedFile); err != nil {
+		return nil, fmt.Errorf("failed to print merged source: %w", err)
+	}
+	result.Source, err = format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format merged source: %w", err)
+	}
+	return result, nil
+}
+
+type insertion struct {
+	offset int
+	text   string
+}
+
+// sourceFile はパース済みのファイルとその元のソースコード
+type sourceFile struct {
+	fset *token.FileSet
+	file *ast.File
+	src  []byte
+}
+
+func newSourceFile(fset *token.FileSet, file *ast.File, src []byte) *sourceFile {
+	return &sourceFile{fset: fset, file: file, src: src}
+}
+
+func (s *sourceFile) offset(pos token.Pos) int {
+	return s.fset.Position(pos).Offset
+}
+
+func (s *sourceFile) text(node ast.Node) string {
+	return string(s.src[s.offset(node.Pos()):s.offset(node.End())])
+}
+
+// declsByName はトップレベルの宣言を名前で引けるようにする。import や複数の名前を持つ宣言は含まない
+func (s *sourceFile) declsByName() map[string]ast.Decl {
+	decls := make(map[string]ast.Decl)
+	for _, decl := range s.file.Decls {
+		if name := declName(decl); name != "" {
+			decls[name] = decl
+		}
+	}
+	return decls
+}
+
+// declStart はドキュメントコメントを含む宣言の開始位置を返す
+func declStart(decl ast.Decl) token.Pos {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	}
+	return decl.Pos()
+}
+
+// hasMarker は呼び出しの括弧内に UserMarker のコメントがあるかを返す
+func (s *sourceFile) hasMarker(call *ast.CallExpr) bool {
+	return s.markerPos(call).IsValid()
+}
+
+func (s *sourceFile) markerPos(call *ast.CallExpr) token.Pos {
+	if call == nil {
+		return token.NoPos
+	}
+	for _, group := range s.file.Comments {
+		if group.Pos() < call.Lparen || group.End() > call.Rparen {
+			continue
+		}
+		for _, c := range group.List {
+			if strings.Contains(c.Text, UserMarker) {
+				return c.Pos()
+			}
+		}
+	}
+	return token.NoPos
+}
+
+// appendArgs は呼び出しの末尾に引数を追加する挿入を返す
+func (s *sourceFile) appendArgs(call *ast.CallExpr, args []string) insertion {
+	offset := s.offset(call.Rparen)
+	prefix := ""
+	// 最後の引数の後ろにカンマが無い場合（1行で書かれた呼び出しなど）は補う
+	if n := len(call.Args); n > 0 && !strings.Contains(string(s.src[s.offset(call.Args[n-1].End()):offset]), ",") {
+		prefix = ","
+	}
+	if offset > 0 && s.src[offset-1] != '\n' {
+		prefix += "\n"
+	}
+	return insertion{offset: offset, text: prefix + strings.Join(args, ",\n") + ",\n"}
+}
+
+// argTexts は呼び出しの引数のソースコードを返す
+func (s *sourceFile) argTexts(call *ast.CallExpr) map[string]bool {
+	texts := make(map[string]bool)
+	for _, arg := range call.Args {
+		texts[normalizeExpr(s.text(arg))] = true
+	}
+	return texts
+}
+
+// userArgs は既存のセットのうち利用者が追加した引数を返す。
+// UserMarker がある場合はその後ろの引数を利用者のものとみなす。
+// 無い場合（UserMarker を出力する前の cire が生成したファイル）は、当時の cire が生成しえない引数だけを利用者のものとみなし、
+// 生成しうる引数（関数の参照と wire.Struct）のうち生成結果に含まれないものは捨てる。
+// 引き継ぐ引数の import の参照名は imports で生成結果に合わせる。
+// dropped は利用者が追加した可能性のある引数を捨てたかどうか（バックアップが必要かどうか）
+func (s *sourceFile) userArgs(call *ast.CallExpr, generated map[string]bool, imports *importRenamer) (extras []string, dropped bool) {
+	if call == nil {
+		return nil, false
+	}
+	marker := s.markerPos(call)
+	extras = make([]string, 0)
+	for _, arg := range call.Args {
+		if marker.IsValid() && arg.Pos() < marker {
+			continue
+		}
+		text := imports.rewrite(s, arg, arg.Pos())
+		if generated[normalizeExpr(text)] {
+			continue
+		}
+		// 依存関係が変わって使われなくなったプロバイダを残すと、wire が未使用や重複として報告する
+		if !marker.IsValid() && legacyGenerated(arg) {
+			dropped = true
+			continue
+		}
+		extras = append(extras, text)
+	}
+	return extras, dropped
+}
+
+// legacyGenerated は UserMarker を出力する前の cire が生成しえた形の引数（関数の参照と wire.Struct）かどうかを返す
+func legacyGenerated(arg ast.Expr) bool {
+	switch a := arg.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		_, ok := a.X.(*ast.Ident)
+		return ok
+	case *ast.CallExpr:
+		return isWireCall(a, "Struct")
+	}
+	return false
+}
+
+var spaceRegexp = regexp.MustCompile(`\s+`)
+
+func normalizeExpr(text string) string {
+	return spaceRegexp.ReplaceAllString(text, "")
+}
+
+func declName(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil {
+			return ""
+		}
+		return d.Name.Name
+	case *ast.GenDecl:
+		if d.Tok == token.IMPORT || len(d.Specs) != 1 {
+			return ""
+		}
+		switch spec := d.Specs[0].(type) {
+		case *ast.ValueSpec:
+			if len(spec.Names) == 1 {
+				return spec.Names[0].Name
+			}
+		case *ast.TypeSpec:
+			return spec.Name.Name
+		}
+	}
+	return ""
+}
+
+// newSetCall は `var X = wire.NewSet(...)` の呼び出し部分を返す
+func newSetCall(decl ast.Decl) *ast.CallExpr {
+	gd, ok := decl.(*ast.GenDecl)
+	if !ok || gd.Tok != token.VAR || len(gd.Specs) != 1 {
+		return nil
+	}
+	spec, ok := gd.Specs[0].(*ast.ValueSpec)
+	if !ok || len(spec.Values) != 1 {
+		return nil
+	}
+	call, ok := spec.Values[0].(*ast.CallExpr)
+	if !ok || !isWireCall(call, "NewSet") {
+		return nil
+	}
+	return call
+}
+
+func isNewSetDecl(decl ast.Decl) bool {
+	return newSetCall(decl) != nil
+}
+
+// isInjectorOf は decl が sets のいずれかを wire.Build するだけのインジェクタ関数かを返す
+func isInjectorOf(decl ast.Decl, sets map[string]bool) bool {
+	fd, ok := decl.(*ast.FuncDecl)
+	if !ok || fd.Recv != nil || fd.Body == nil || len(fd.Body.List) == 0 {
+		return false
+	}
+	stmt, ok := fd.Body.List[0].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := stmt.X.(*ast.CallExpr)
+	if !ok || !isWireCall(call, "Build") || len(call.Args) != 1 {
+		return false
+	}
+	ident, ok := call.Args[0].(*ast.Ident)
+	return ok && sets[ident.Name]
+}
+
+func isWireCall(call *ast.CallExpr, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	return ok && x.Name == "wire"
+}
+
+// importRenamer は既存ファイルから引き継ぐ利用者のコードの import の参照名を、マージ後のファイルの参照名に書き換える。
+// 生成結果が同じパスを import している場合はその参照名を使い、別名の振り直し（service と service2 の入れ替わりなど）で
+// 利用者のコードが別のパッケージを指さないようにする。生成結果に無いパスは既存の参照名を保ち、
+// 生成結果の参照名と衝突する場合だけ番号を付けた別名にする
+type importRenamer struct {
+	// oldPaths は既存ファイルの参照名からパスへの対応
+	oldPaths map[string]string
+	// names はパスからマージ後の参照名への対応
+	names map[string]string
+}
+
+func newImportRenamer(oldFile, genFile *ast.File) *importRenamer {
+	r := &importRenamer{oldPaths: make(map[string]string), names: make(map[string]string)}
+	used := make(map[string]bool)
+	for _, imp := range genFile.Imports {
+		name, importPath := importNameAndPath(imp)
+		r.names[importPath] = name
+		used[name] = true
+	}
+	// 生成結果に無いパスの参照名は既存ファイルの import の順に決め、実行ごとに変わらないようにする
+	for _, imp := range oldFile.Imports {
+		name, importPath := importNameAndPath(imp)
+		if name == "_" || name == "." {
+			continue
+		}
+		r.oldPaths[name] = importPath
+		if _, ok := r.names[importPath]; ok {
+			continue
+		}
+		alias := name
+		for i := 2; used[alias]; i++ {
+			alias = fmt.Sprintf("%s%d", name, i)
+		}
+		r.names[importPath] = alias
+		used[alias] = true
+	}
+	return r
+}
+
+// rewrite は start から node の終わりまでのソースコードを、import の参照名を書き換えて返す。
+// ファイル内で宣言された名前（import と同名のローカル変数など）は書き換えない
+func (r *importRenamer) rewrite(s *sourceFile, node ast.Node, start token.Pos) string {
+	base := s.offset(start)
+	text := []byte(s.src[base:s.offset(node.End())])
+	idents := make([]*ast.Ident, 0)
+	ast.Inspect(node, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+			if importPath, ok := r.oldPaths[x.Name]; ok && r.names[importPath] != x.Name {
+				idents = append(idents, x)
+			}
+		}
+		return true
+	})
+	// 後ろから置き換えて、前の識別子のオフセットがずれないようにする
+	for i := len(idents) - 1; i >= 0; i-- {
+		x := idents[i]
+		offset := s.offset(x.Pos()) - base
+		text = slices.Replace(text, offset, offset+len(x.Name), []byte(r.names[r.oldPaths[x.Name]])...)
+	}
+	return string(text)
+}
+
+// importNameAndPath は import 宣言の参照名とパスを返す。名前が省略されている場合はパスから推測する
+func importNameAndPath(imp *ast.ImportSpec) (string, string) {
+	importPath, _ := strconv.Unquote(imp.Path.Value)
+	if imp.Name != nil {
+		return imp.Name.Name, importPath
+	}
+	name := path.Base(importPath)
+	if isMajorVersion(name) {
+		name = path.Base(path.Dir(importPath))
+	}
+	return name, importPath
+}
+
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+// usesName はファイル内で name.X の形の参照があるかを返す
+func usesName(f *ast.File, name string) bool {
+	used := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return !used
+		}
+		if x, ok := sel.X.(*ast.Ident); ok && x.Name == name {
+			used = true
+		}
+		return !used
+	})
+	return used
+}
@@ -0,0 +1,365 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+)
+
+// generateForMerge はマージのテストに使う生成結果を返す
+func generateForMerge(t *testing.T, roots ...string) []byte {
+	t.Helper()
+
+	config := &GenerateConfig{PackageName: "main"}
+	for _, root := range roots {
+		config.AddStructSet(StructSet{
+			RootStructName: root,
+			Providers: []Provider{
+				{PkgPath: "example.com/svc", PkgName: "svc", Name: "New" + root + "Service"},
+			},
+		})
+	}
+	got, err := config.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	return got
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name            string
+		existing        string
+		wantContain     []string
+		wantMissing     []string
+		wantNeedsBackup bool
+	}{
+		{
+			name: "利用者の宣言と import が保たれる",
+			existing: `//go:build wireinject
+
+package main
+
+import (
+	"github.com/google/wire"
+	"example.com/svc"
+	"example.com/version"
+)
+
+var AppSet = wire.NewSet(
+	svc.NewAppService,
+	wire.Struct(new(App), "*"),
+	// cire:user - entries below this line are kept when cire regenerates this file
+)
+
+func InitializeApp() (*App, error) {
+	wire.Build(AppSet)
+	return nil, nil
+}
+
+// BuildVersion は利用者が追加した宣言
+var BuildVersion = wire.Value(version.Version)
+`,
+			wantContain: []string{
+				`"example.com/version"`,
+				"// BuildVersion は利用者が追加した宣言",
+				"var BuildVersion = wire.Value(version.Version)",
+				"svc.NewAppService,",
+			},
+		},
+		{
+			name: "セットに追加したプロバイダ式が引き継がれる",
+			existing: `//go:build wireinject
+
+package main
+
+import (
+	"github.com/google/wire"
+	"example.com/legacy"
+	"example.com/svc"
+)
+
+var AppSet = wire.NewSet(
+	svc.NewAppService,
+	wire.Struct(new(App), "*"),
+	// cire:user - entries below this line are kept when cire regenerates this file
+	wire.Bind(new(legacy.Store), new(*legacy.DBStore)),
+	legacy.NewDBStore,
+)
+
+func InitializeApp() (*App, error) {
+	wire.Build(AppSet)
+	return nil, nil
+}
+`,
+			wantContain: []string{
+				`"example.com/legacy"`,
+				"wire.Bind(new(legacy.Store), new(*legacy.DBStore)),",
+				"legacy.NewDBStore,",
+			},
+		},
+		{
+			name: "マーカーの無いセットでは生成しうる引数を捨てて利用者の式だけを引き継ぐ",
+			existing: `//go:build wireinject
+
+package main
+
+import (
+	"github.com/google/wire"
+	"example.com/legacy"
+	"example.com/svc"
+)
+
+var AppSet = wire.NewSet(
+	svc.NewAppService,
+	svc.NewRemovedService,
+	wire.Bind(new(legacy.Store), new(*legacy.DBStore)),
+	wire.Struct(new(App), "*"),
+)
+
+func InitializeApp() (*App, error) {
+	wire.Build(AppSet)
+	return nil, nil
+}
+`,
+			wantContain: []string{
+				"svc.NewAppService,",
+				"wire.Bind(new(legacy.Store), new(*legacy.DBStore)),",
+			},
+			wantMissing:     []string{"svc.NewRemovedService"},
+			wantNeedsBackup: true,
+		},
+		{
+			name: "削除されたルート構造体のセットとインジェクタは取り除かれる",
+			existing: `//go:build wireinject
+
+package main
+
+import (
+	"github.com/google/wire"
+	"example.com/old"
+)
+
+var OldSet = wire.NewSet(
+	old.NewOld,
+	wire.Struct(new(Old), "*"),
+	// cire:user - entries below this line are kept when cire regenerates this file
+)
+
+func InitializeOld() (*Old, error) {
+	wire.Build(OldSet)
+	return nil, nil
+}
+`,
+			wantMissing: []string{"OldSet", "InitializeOld", `"example.com/old"`},
+		},
+		{
+			name: "cire の形ではない同名の宣言はバックアップ対象",
+			existing: `package main
+
+func InitializeApp() (*App, error) {
+	return &App{}, nil
+}
+`,
+			wantContain:     []string{"wire.Build(AppSet)"},
+			wantNeedsBackup: true,
+		},
+		{
+			name:            "パースできないファイルはバックアップ対象",
+			existing:        `package main func {`,
+			wantContain:     []string{"wire.Build(AppSet)"},
+			wantNeedsBackup: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Merge([]byte(tt.existing), generateForMerge(t, "App"))
+			if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			if result.NeedsBackup != tt.wantNeedsBackup {
+				t.Errorf("NeedsBackup = %v, want %v", result.NeedsBackup, tt.wantNeedsBackup)
+			}
+
+			output := string(result.Source)
+			for _, want := range tt.wantContain {
+				if !strings.Contains(output, want) {
+					t.Errorf("Merge() 出力に %q が含まれていません\n出力:\n%s", want, output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(output, missing) {
+					t.Errorf("Merge() 出力に %q が含まれています\n出力:\n%s", missing, output)
+				}
+			}
+		})
+	}
+}
+
+func TestMerge_Idempotent(t *testing.T) {
+	generated := generateForMerge(t, "App", "Admin")
+
+	result, err := Merge(generated, generated)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if string(result.Source) != string(generated) {
+		t.Errorf("生成結果同士のマージで内容が変わりました\n--- got ---\n%s\n--- want ---\n%s", result.Source, generated)
+	}
+	if result.NeedsBackup {
+		t.Error("生成結果同士のマージでバックアップが要求されました")
+	}
+}
+
+func TestMerge_SingleLineSet(t *testing.T) {
+	generated := []byte(`package main
+
+import "github.com/google/wire"
+
+var AppSet = wire.NewSet(NewA)
+`)
+	existing := []byte(`package main
+
+import "github.com/google/wire"
+
+var AppSet = wire.NewSet(NewA, wire.Value(1))
+`)
+
+	result, err := Merge(existing, generated)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if !strings.Contains(string(result.Source), "wire.Value(1)") {
+		t.Errorf("Merge() 出力に利用者のプロバイダ式が含まれていません\n出力:\n%s", result.Source)
+	}
+}
+
+func TestMerge_RenumberedImportAliases(t *testing.T) {
+	// 前回の生成では a/service が service、b/service が service2 だったが、今回は入れ替わった
+	existing := []byte(`package main
+
+import (
+	"github.com/google/wire"
+	service "example.com/a/service"
+	service2 "example.com/b/service"
+)
+
+var AppSet = wire.NewSet(
+	service.NewUserService,
+	service2.NewOrderService,
+	// cire:user - entries below this line are kept when cire regenerates this file
+	service2.NewAuditService,
+)
+
+// Version は利用者が追加した宣言
+var Version = wire.Value(service.Version)
+`)
+	generated := []byte(`package main
+
+import (
+	"github.com/google/wire"
+	service "example.com/b/service"
+	service2 "example.com/a/service"
+)
+
+var AppSet = wire.NewSet(
+	service.NewOrderService,
+	service2.NewUserService,
+	// cire:user - entries below this line are kept when cire regenerates this file
+)
+`)
+
+	result, err := Merge(existing, generated)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	got := string(result.Source)
+	for _, want := range []string{
+		"\tservice.NewAuditService,\n",
+		"var Version = wire.Value(service2.Version)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Merge() 出力に %q が含まれていません\n出力:\n%s", want, got)
+		}
+	}
+	for _, missing := range []string{"service2.NewAuditService", "wire.Value(service.Version)"} {
+		if strings.Contains(got, missing) {
+			t.Errorf("Merge() 出力が別のパッケージを指す %q を含んでいます\n出力:\n%s", missing, got)
+		}
+	}
+}
+
+func TestMerge_UserAliasOfGeneratedImport(t *testing.T) {
+	existing := []byte(`package main
+
+import (
+	"github.com/google/wire"
+	s "example.com/svc"
+	"example.com/svc/legacy"
+)
+
+var Extra = wire.NewSet(s.NewCache, legacy.NewStore)
+
+func helper(s int) int {
+	return s
+}
+`)
+	generated := generateForMerge(t, "App")
+
+	result, err := Merge(existing, generated)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	got := string(result.Source)
+	// 生成結果が同じパスを別の名前で import しているため、利用者のコードはその名前で参照する
+	if !strings.Contains(got, "var Extra = wire.NewSet(svc.NewCache, legacy.NewStore)") {
+		t.Errorf("Merge() 出力の利用者の宣言が生成結果の import を参照していません\n出力:\n%s", got)
+	}
+	// import と同名のローカル変数は書き換えない
+	if !strings.Contains(got, "func helper(s int) int {\n\treturn s\n}") {
+		t.Errorf("Merge() 出力のローカル変数が書き換えられました\n出力:\n%s", got)
+	}
+	if !strings.Contains(got, "\"example.com/svc/legacy\"") || strings.Contains(got, "s \"example.com/svc\"") {
+		t.Errorf("Merge() 出力の import が正しくありません\n出力:\n%s", got)
+	}
+}
+
+func TestMerge_UserImportCollidesWithGeneratedAlias(t *testing.T) {
+	// 利用者だけが使う a/service の参照名が、生成結果では b/service に使われている
+	existing := []byte(`package main
+
+import (
+	"github.com/google/wire"
+	"example.com/a/service"
+)
+
+var Extra = wire.Value(service.Version)
+`)
+	generated := []byte(`package main
+
+import (
+	"github.com/google/wire"
+	"example.com/b/service"
+)
+
+var AppSet = wire.NewSet(
+	service.NewOrderService,
+	// cire:user - entries below this line are kept when cire regenerates this file
+)
+`)
+
+	result, err := Merge(existing, generated)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	got := string(result.Source)
+	for _, want := range []string{
+		"service2 \"example.com/a/service\"",
+		"\"example.com/b/service\"",
+		"var Extra = wire.Value(service2.Version)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Merge() 出力に %q が含まれていません\n出力:\n%s", want, got)
+		}
+	}
+}
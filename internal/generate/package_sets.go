@@ -0,0 +1,129 @@
+package generate
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// PackageSetName は GenerateConfig.PackageSets のパッケージに生成する公開のプロバイダセットの変数名
+const PackageSetName = "ProviderSet"
+
+// PackageSetData は公開のプロバイダセットのファイル（providers_gen.go）のテンプレートに渡すデータ
+type PackageSetData struct {
+	// Version はデータモデルのバージョン（DataVersion）
+	Version int
+	// Generator は生成ファイルのヘッダーに記録する生成元の情報
+	Generator GeneratorData
+	// PackageName と PackagePath はセットを置くパッケージの名前とパス
+	PackageName string
+	PackagePath string
+	// Imports はプロバイダが参照する他のパッケージの import 宣言（パスの昇順）。github.com/google/wire は含まない
+	Imports []ImportData
+	// Roots はセットのプロバイダを使うルート構造体名（ルート構造体の定義順）
+	Roots []string
+	// Providers はセットに含めるプロバイダ（参照式の昇順）
+	Providers []Provider
+}
+
+// PackageSetFile は生成した公開のプロバイダセットのファイル
+type PackageSetFile struct {
+	// PkgPath はファイルを置くパッケージのパス
+	PkgPath string
+	Source  []byte
+}
+
+// owner はプロバイダを公開のプロバイダセットに置くパッケージを返す。
+// wire.Bind は実装の型、wire.FieldsOf はフィールドを持つ構造体のパッケージに置く
+func (p Provider) owner() (pkgPath, pkgName string) {
+	if (p.Kind == KindBind || p.Kind == KindField) && p.Source != nil {
+		return p.Source.PkgPath, p.Source.PkgName
+	}
+	return p.PkgPath, p.PkgName
+}
+
+// usePackageSets は PackageSets のパッケージが所有するプロバイダを、そのパッケージのセットの参照に置き換えた StructSets を返す
+func (c *GenerateConfig) usePackageSets() []StructSet {
+	result := make([]StructSet, 0, len(c.StructSets))
+	for _, set := range c.StructSets {
+		providers := make([]Provider, 0, len(set.Providers))
+		referenced := make(map[string]bool)
+		for _, p := range set.Providers {
+			pkgPath, pkgName := p.owner()
+			if !slices.Contains(c.PackageSets, pkgPath) {
+				providers = append(providers, p)
+				continue
+			}
+			if !referenced[pkgPath] {
+				referenced[pkgPath] = true
+				providers = append(providers, Provider{PkgPath: pkgPath, PkgName: pkgName, Name: PackageSetName, Kind: KindSet})
+			}
+		}
+		set.Providers = providers
+		result = append(result, set)
+	}
+	return result
+}
+
+// GeneratePackageSets は PackageSets のパッケージごとに、いずれかのルート構造体が使うそのパッケージのプロバイダを
+// 公開のプロバイダセットにまとめたファイルを生成する（パッケージパスの昇順）。
+// ファイルには wireinject のビルドタグを付けず、他のモジュールからも wire でそのまま使えるようにする
+func (c *GenerateConfig) GeneratePackageSets() ([]*PackageSetFile, error) {
+	type packageSet struct {
+		name      string
+		roots     []string
+		providers []Provider
+	}
+	sets := make(map[string]*packageSet)
+	for _, set := range c.StructSets {
+		for _, p := range set.Providers {
+			pkgPath, pkgName := p.owner()
+			if !slices.Contains(c.PackageSets, pkgPath) {
+				continue
+			}
+			ps, ok := sets[pkgPath]
+			if !ok {
+				ps = &packageSet{name: pkgName}
+				sets[pkgPath] = ps
+			}
+			if !slices.Contains(ps.roots, set.RootStructName) {
+				ps.roots = append(ps.roots, set.RootStructName)
+			}
+			ps.providers = append(ps.providers, p)
+		}
+	}
+
+	files := make([]*PackageSetFile, 0, len(sets))
+	for _, pkgPath := range slices.Sorted(maps.Keys(sets)) {
+		ps := sets[pkgPath]
+		aliases, err := newImportAliases(ps.providers, pkgPath)
+		if err != nil {
+			return nil, err
+		}
+		// 複数のルート構造体が使うプロバイダは一度だけ並べる
+		providers := make([]Provider, 0, len(ps.providers))
+		for _, p := range ps.providers {
+			p = aliases.qualify(p)
+			if !slices.ContainsFunc(providers, func(q Provider) bool { return q.Expr() == p.Expr() }) {
+				providers = append(providers, p)
+			}
+		}
+		slices.SortFunc(providers, func(a, b Provider) int { return cmp.Compare(a.Expr(), b.Expr()) })
+
+		src, err := executeTemplate(packageSetTemplate, PackageSetData{
+			Version:     DataVersion,
+			Generator:   c.Generator,
+			PackageName: ps.name,
+			PackagePath: pkgPath,
+			Imports:     aliases.imports(),
+			Roots:       ps.roots,
+			Providers:   providers,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate the provider set of %s: %w", pkgPath, err)
+		}
+		files = append(files, &PackageSetFile{PkgPath: pkgPath, Source: src})
+	}
+	return files, nil
+}
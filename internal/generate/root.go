@@ -0,0 +1,77 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// RootFile は cire init が生成するルート構造体のファイル
+type RootFile struct {
+	PackageName string
+	// PackagePath はファイルが属するパッケージのパス。同一パッケージの型は修飾せずに参照する
+	PackagePath string
+	// BuildTags はファイルに付けるビルドタグ（&& で結合する）
+	BuildTags  []string
+	StructName string
+	Fields     []RootField
+}
+
+// RootField はルート構造体のフィールド
+type RootField struct {
+	Name    string
+	PkgPath string
+	PkgName string
+	// TypeName はパッケージ修飾なしの型名
+	TypeName string
+	Pointer  bool
+}
+
+// Generate はルート構造体のファイルを生成する
+func (r *RootFile) Generate() ([]byte, error) {
+	refs := make([]Provider, 0, len(r.Fields))
+	for _, f := range r.Fields {
+		refs = append(refs, Provider{PkgPath: f.PkgPath, PkgName: f.PkgName, Name: f.TypeName})
+	}
+	aliases, err := newImportAliases(refs, r.PackagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if len(r.BuildTags) > 0 {
+		fmt.Fprintf(&buf, "//go:build %s\n\n", strings.Join(r.BuildTags, " && "))
+	}
+	fmt.Fprintf(&buf, "package %s\n\n", r.PackageName)
+	if imports := aliases.imports(); len(imports) > 0 {
+		buf.WriteString("import (\n")
+		for _, imp := range imports {
+			if imp.Alias != "" {
+				fmt.Fprintf(&buf, "\t%s %q\n", imp.Alias, imp.Path)
+			} else {
+				fmt.Fprintf(&buf, "\t%q\n", imp.Path)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+	fmt.Fprintf(&buf, "// %s は依存関係の解析対象となるルート構造体\n", r.StructName)
+	fmt.Fprintf(&buf, "type %s struct {\n", r.StructName)
+	for _, f := range r.Fields {
+		typ := f.TypeName
+		if alias := aliases.alias(f.PkgPath); alias != "" {
+			typ = alias + "." + typ
+		}
+		if f.Pointer {
+			typ = "*" + typ
+		}
+		fmt.Fprintf(&buf, "\t%s %s\n", f.Name, typ)
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format root file: %w", err)
+	}
+	return src, nil
+}
@@ -0,0 +1,68 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRootFile_Generate(t *testing.T) {
+	tests := []struct {
+		name        string
+		root        RootFile
+		wantContain []string
+		wantMissing []string
+	}{
+		{
+			name: "ビルドタグと import",
+			root: RootFile{
+				PackageName: "main",
+				BuildTags:   []string{"cire"},
+				StructName:  "App",
+				Fields: []RootField{
+					{Name: "UserHandler", PkgPath: "example.com/app/handler", PkgName: "handler", TypeName: "UserHandler", Pointer: true},
+					{Name: "Store", PkgPath: "example.com/kv/v2", PkgName: "kv", TypeName: "Store"},
+				},
+			},
+			wantContain: []string{
+				"//go:build cire\n\npackage main",
+				`"example.com/app/handler"`,
+				`kv "example.com/kv/v2"`,
+				"UserHandler *handler.UserHandler",
+				"Store       kv.Store",
+			},
+		},
+		{
+			name: "同一パッケージの型は修飾しない",
+			root: RootFile{
+				PackageName: "app",
+				PackagePath: "example.com/app",
+				StructName:  "App",
+				Fields: []RootField{
+					{Name: "Server", PkgPath: "example.com/app", PkgName: "app", TypeName: "Server", Pointer: true},
+				},
+			},
+			wantContain: []string{"Server *Server"},
+			wantMissing: []string{"import", "//go:build"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.root.Generate()
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			output := string(got)
+			for _, want := range tt.wantContain {
+				if !strings.Contains(output, want) {
+					t.Errorf("Generate() 出力に %q が含まれていません\n出力:\n%s", want, output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(output, missing) {
+					t.Errorf("Generate() 出力に %q が含まれています\n出力:\n%s", missing, output)
+				}
+			}
+		})
+	}
+}
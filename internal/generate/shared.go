@@ -0,0 +1,112 @@
+package generate
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"unicode"
+)
+
+// shareProviders は複数のルート構造体が使うプロバイダをパッケージごとの共通のセットにまとめる。
+// 各ルート構造体のセットからは共通のセットに移したプロバイダを除き、代わりに共通のセットを参照させる。
+// 共通のセットにはそのルート構造体が使わないプロバイダも含まれうるが、wire は wire.Build に直接渡した要素だけを未使用として検査する。
+// suffix は共通のセットの名前の末尾（テスト用のインジェクタでは通常の生成ファイルと名前が重ならないよう "TestSharedSet" にする）
+func shareProviders(sets []ProviderSetData, packageName, suffix string) ([]ProviderSetData, []SharedSetData) {
+	// プロバイダを使うルート構造体を数える
+	users := make(map[string][]string)
+	for _, set := range sets {
+		for _, p := range set.Providers {
+			users[p.Expr()] = append(users[p.Expr()], set.StructName)
+		}
+	}
+
+	// sharedGroup は共通のセットと、その名前を決めるパッケージ
+	type sharedGroup struct {
+		pkgPath string
+		alias   string
+		set     SharedSetData
+	}
+	byPkg := make(map[string]*sharedGroup)
+	for _, set := range sets {
+		for _, p := range set.Providers {
+			if len(users[p.Expr()]) < 2 {
+				continue
+			}
+			group, ok := byPkg[p.PkgPath]
+			if !ok {
+				group = &sharedGroup{pkgPath: p.PkgPath, alias: p.Alias}
+				byPkg[p.PkgPath] = group
+			}
+			if !slices.ContainsFunc(group.set.Providers, func(q Provider) bool { return q.Expr() == p.Expr() }) {
+				group.set.Providers = append(group.set.Providers, p)
+			}
+			for _, root := range users[p.Expr()] {
+				if !slices.Contains(group.set.Roots, root) {
+					group.set.Roots = append(group.set.Roots, root)
+				}
+			}
+		}
+	}
+	if len(byPkg) == 0 {
+		return sets, nil
+	}
+
+	// 共通のセットはパッケージパスの昇順に並べ、パッケージの参照名から名前を付ける
+	groups := slices.SortedFunc(maps.Values(byPkg), func(a, b *sharedGroup) int { return cmp.Compare(a.pkgPath, b.pkgPath) })
+	taken := make(map[string]bool)
+	// order はルート構造体の定義順（Roots を定義順に並べるために使う）
+	order := make(map[string]int)
+	for i, set := range sets {
+		taken[set.SetName] = true
+		order[set.StructName] = i
+	}
+	shared := make([]SharedSetData, 0, len(groups))
+	setOf := make(map[string]string)
+	for _, group := range groups {
+		base := group.alias
+		if base == "" {
+			base = packageName
+		}
+		name := upperFirst(base) + suffix
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s%s%d", upperFirst(base), suffix, n)
+		}
+		taken[name] = true
+		group.set.Name = name
+		slices.SortFunc(group.set.Providers, func(a, b Provider) int { return cmp.Compare(a.Expr(), b.Expr()) })
+		slices.SortFunc(group.set.Roots, func(a, b string) int { return cmp.Compare(order[a], order[b]) })
+		for _, p := range group.set.Providers {
+			setOf[p.Expr()] = name
+		}
+		shared = append(shared, group.set)
+	}
+
+	result := make([]ProviderSetData, 0, len(sets))
+	for _, set := range sets {
+		own := make([]Provider, 0, len(set.Providers))
+		for _, p := range set.Providers {
+			name, ok := setOf[p.Expr()]
+			if !ok {
+				own = append(own, p)
+				continue
+			}
+			if !slices.Contains(set.SharedSets, name) {
+				set.SharedSets = append(set.SharedSets, name)
+			}
+		}
+		slices.Sort(set.SharedSets)
+		set.Providers = own
+		result = append(result, set)
+	}
+	return result, shared
+}
+
+func upperFirst(s string) string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return s
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
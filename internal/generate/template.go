@@ -0,0 +1,96 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// TemplateFuncs はテンプレートから利用できるヘルパー関数
+//
+//   - qualify: Provider を生成コード内の参照式（例: "service.NewUserService"）に変換する
+//   - lowerCamel: 識別子を lowerCamelCase に変換する（例: "HTTPServer" → "httpServer"）
+//   - sortedImports: import 宣言に追加のパスを加え、重複を除いてパスの昇順に並べる
+var TemplateFuncs = template.FuncMap{
+	"qualify":       qualify,
+	"lowerCamel":    lowerCamel,
+	"sortedImports": sortedImports,
+}
+
+func qualify(p Provider) string {
+	return p.Expr()
+}
+
+func lowerCamel(s string) string {
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		// 先頭の大文字の連続を小文字にする。ただし次が小文字の場合、その直前の文字は単語の先頭として残す
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+func sortedImports(imports []ImportData, extra ...string) []ImportData {
+	seen := make(map[string]bool)
+	result := make([]ImportData, 0, len(imports)+len(extra))
+	for _, imp := range imports {
+		if seen[imp.Path] {
+			continue
+		}
+		seen[imp.Path] = true
+		result = append(result, imp)
+	}
+	for _, path := range extra {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		result = append(result, ImportData{Path: path})
+	}
+	slices.SortFunc(result, func(a, b ImportData) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return result
+}
+
+// ParseTemplate はテンプレートをヘルパー関数付きでパースする
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("wire").Funcs(TemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// executeTemplate はテンプレートを実行し、出力が Go のソースコードとして正しいことを確認して整形する
+func executeTemplate(text string, data any) ([]byte, error) {
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "wire.go", buf.Bytes(), parser.ParseComments); err != nil {
+		return nil, fmt.Errorf("template output is not valid Go source: %w", err)
+	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format template output: %w", err)
+	}
+	return formatted, nil
+}
@@ -0,0 +1,144 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLowerCamel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "UserApp", want: "userApp"},
+		{in: "HTTPServer", want: "httpServer"},
+		{in: "ID", want: "id"},
+		{in: "app", want: "app"},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := lowerCamel(tt.in); got != tt.want {
+				t.Errorf("lowerCamel(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortedImports(t *testing.T) {
+	imports := []ImportData{
+		{Path: "example.com/svc"},
+		{Alias: "redis", Path: "github.com/redis/go-redis"},
+	}
+
+	got := sortedImports(imports, "context", "example.com/svc")
+	want := []string{"context", "example.com/svc", "github.com/redis/go-redis"}
+	if len(got) != len(want) {
+		t.Fatalf("sortedImports() = %v, want paths %v", got, want)
+	}
+	for i, imp := range got {
+		if imp.Path != want[i] {
+			t.Errorf("sortedImports()[%d].Path = %q, want %q", i, imp.Path, want[i])
+		}
+	}
+	if got[2].Alias != "redis" {
+		t.Errorf("alias が保持されていません: %v", got[2])
+	}
+}
+
+func TestGenerateConfig_Generate_CustomTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		wantErr     bool
+		wantContain []string
+	}{
+		{
+			name: "context を受け取るインジェクタ",
+			template: `// Code generated for v{{.Version}}.
+
+//go:build wireinject
+
+//go:generate wire
+package {{.PackageName}}
+
+import (
+	"github.com/google/wire"
+{{- range sortedImports .Imports "context"}}
+	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{- end}}
+)
+{{range .ProviderSets}}
+var {{lowerCamel .SetName}} = wire.NewSet(
+{{- range .Providers}}
+	{{qualify .}},
+{{- end}}
+)
+
+func {{.InjectorName}}(ctx context.Context) (*{{.StructName}}, error) {
+	wire.Build({{lowerCamel .SetName}})
+	return nil, nil
+}
+{{end}}`,
+			wantContain: []string{
+				"// Code generated for v1.",
+				"//go:generate wire",
+				`"context"`,
+				"var appSet = wire.NewSet(",
+				"svc.NewService,",
+				"func InitializeApp(ctx context.Context) (*App, error) {",
+			},
+		},
+		{
+			name:     "Go として不正な出力",
+			template: `package {{.PackageName}} func {`,
+			wantErr:  true,
+		},
+		{
+			name:     "存在しないフィールドの参照",
+			template: `package {{.PackageName}}{{.Unknown}}`,
+			wantErr:  true,
+		},
+		{
+			name: "HTML エスケープされない",
+			template: `package {{.PackageName}}
+
+var _ = "{{range .ProviderSets}}{{.StructName}}<&>{{end}}"
+`,
+			wantContain: []string{`"App<&>"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &GenerateConfig{
+				PackageName: "main",
+				StructSets: []StructSet{
+					{
+						RootStructName: "App",
+						Providers: []Provider{
+							{PkgPath: "example.com/svc", PkgName: "svc", Name: "NewService"},
+						},
+					},
+				},
+				Template: tt.template,
+			}
+
+			got, err := config.Generate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			output := string(got)
+			for _, want := range tt.wantContain {
+				if !strings.Contains(output, want) {
+					t.Errorf("Generate() 出力に %q が含まれていません\n出力:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
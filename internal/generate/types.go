@@ -1,14 +1,66 @@
 package generate
 
-// WireData は wire.go テンプレートに渡すデータ
+// DataVersion は WireData のデータモデルのバージョン。
+// フィールドの削除や意味の変更など、既存のテンプレートが壊れうる変更を行った場合に上げる
+const DataVersion = 1
+
+// WireData は wire.go テンプレートに渡すデータ。
+// --template で指定するユーザー定義テンプレートもこの構造体を受け取る
 type WireData struct {
-	PackageName  string
-	Imports      []string
+	// Version はデータモデルのバージョン（DataVersion）
+	Version int
+	// Generator は生成ファイルのヘッダーに記録する生成元の情報
+	Generator GeneratorData
+	// PackageName は生成ファイルのパッケージ名
+	PackageName string
+	// PackagePath は生成ファイルのパッケージのインポートパス
+	PackagePath string
+	// Profile は生成ファイルのプロファイル（cire generate --profile）。空でない場合はビルドタグとして使う
+	Profile string
+	// TestTag はテスト用のインジェクタのファイルを有効にするビルドタグ（TestBuildTag）。
+	// 通常の生成ファイルでは、wire gen -tags ciretest で一緒に読まれないよう否定して使う。テスト用のインジェクタが無い場合は空
+	TestTag string
+	// Imports はプロバイダが参照するパッケージの import 宣言（パスの昇順）。
+	// github.com/google/wire と生成ファイル自身のパッケージは含まない
+	Imports []ImportData
+	// ProviderSets はルート構造体ごとのプロバイダセット（ルート構造体の定義順）
 	ProviderSets []ProviderSetData
+	// SharedSets は複数のルート構造体が使うプロバイダをパッケージごとにまとめたセット（パッケージパスの昇順）。
+	// GenerateConfig.SharedSets が false の場合は空
+	SharedSets []SharedSetData
+}
+
+// SharedSetData は複数のルート構造体で共有するプロバイダセットのデータ
+type SharedSetData struct {
+	// Name はプロバイダセットの変数名（例: "RepositorySharedSet"）
+	Name string
+	// Roots はセットのプロバイダを使うルート構造体名
+	Roots []string
+	// Providers はセットに含めるプロバイダ
+	Providers []Provider
 }
 
 // ProviderSetData は各 Provider セットのデータ
 type ProviderSetData struct {
+	// StructName はルート構造体名
 	StructName string
-	Providers  []string
+	// SetName はプロバイダセットの変数名
+	SetName string
+	// InjectorName はインジェクタ関数名
+	InjectorName string
+	// Providers はルート構造体の生成に必要なプロバイダ（参照式の昇順）。
+	// テンプレートでは {{qualify .}} で参照式に変換する。SharedSets に含まれるプロバイダは除く
+	Providers []Provider
+	// SharedSets は参照する共通のプロバイダセットの変数名（名前の昇順）
+	SharedSets []string
+}
+
+// GeneratorData は生成ファイルを生成した cire と入力の情報
+type GeneratorData struct {
+	// Version は cire のバージョン
+	Version string
+	// Command は生成に使ったコマンドライン。空の場合はヘッダーに出力しない
+	Command string
+	// InputHash は解析した入力のハッシュ（例: "sha256:..."）
+	InputHash string
 }
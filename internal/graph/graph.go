@@ -0,0 +1,263 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rmocchy/cire/internal/analyze"
+)
+
+// NodeKind はグラフのノードの種類
+type NodeKind string
+
+const (
+	// KindRoot はルート構造体
+	KindRoot NodeKind = "root"
+	// KindProvider は具象型を返すプロバイダ
+	KindProvider NodeKind = "provider"
+	// KindBinding はインターフェースを返すプロバイダ（実装をインターフェースに束縛する）
+	KindBinding NodeKind = "binding"
+	// KindInput はどのプロバイダからも供給されない外部からの入力
+	KindInput NodeKind = "input"
+	// KindPackage はパッケージ単位に集約したノード
+	KindPackage NodeKind = "package"
+)
+
+// Node はグラフのノード
+type Node struct {
+	ID      string
+	Kind    NodeKind
+	Name    string
+	PkgPath string
+	PkgName string
+	// Type はノードが供給する型（ルート構造体の場合は構造体名）
+	Type string
+}
+
+// Label はノードの表示名（コンストラクタ名・パッケージ・返り値の型）を返す
+func (n *Node) Label() string {
+	switch n.Kind {
+	case KindRoot, KindInput:
+		return n.Name
+	case KindPackage:
+		return n.PkgPath
+	}
+	return fmt.Sprintf("%s\n%s\n%s", n.Name, n.PkgName, ShortType(n.Type))
+}
+
+// Edge は依存関係。From が To に依存する（To が From に値を供給する）
+type Edge struct {
+	From string
+	To   string
+}
+
+// RootGraph はルート構造体ごとのプロバイダグラフ
+type RootGraph struct {
+	Name  string
+	Nodes []*Node
+	Edges []Edge
+}
+
+// Graph はルート構造体ごとのプロバイダグラフの一覧（ルート構造体の定義順）
+type Graph struct {
+	Roots []*RootGraph
+}
+
+// Build は解析結果からグラフを構築する
+func Build(trees analyze.RootTrees) *Graph {
+	g := &Graph{Roots: make([]*RootGraph, 0, len(trees))}
+	for _, root := range trees {
+		b := newRootBuilder(root.Name)
+		rootID := "root:" + root.Name
+		b.addNode(&Node{ID: rootID, Kind: KindRoot, Name: root.Name, Type: root.Name})
+		for _, tree := range root.Trees {
+			b.addEdge(rootID, b.visit(tree))
+		}
+		g.Roots = append(g.Roots, b.graph)
+	}
+	return g
+}
+
+type rootBuilder struct {
+	graph *RootGraph
+	nodes map[string]*Node
+	edges map[Edge]bool
+}
+
+func newRootBuilder(name string) *rootBuilder {
+	return &rootBuilder{
+		graph: &RootGraph{Name: name},
+		nodes: make(map[string]*Node),
+		edges: make(map[Edge]bool),
+	}
+}
+
+func (b *rootBuilder) addNode(n *Node) {
+	if _, ok := b.nodes[n.ID]; ok {
+		return
+	}
+	b.nodes[n.ID] = n
+	b.graph.Nodes = append(b.graph.Nodes, n)
+}
+
+func (b *rootBuilder) addEdge(from, to string) {
+	e := Edge{From: from, To: to}
+	if b.edges[e] {
+		return
+	}
+	b.edges[e] = true
+	b.graph.Edges = append(b.graph.Edges, e)
+}
+
+// visit はプロバイダのノードを追加し、そのノードの ID を返す
+func (b *rootBuilder) visit(tree *analyze.FnDITreeNode) string {
+	id := tree.PkgPath + "." + tree.Name
+	if _, ok := b.nodes[id]; ok {
+		return id
+	}
+	kind := KindProvider
+	if tree.ReturnsInterface {
+		kind = KindBinding
+	}
+	typ := ""
+	if len(tree.ReturnTypes) > 0 {
+		typ = tree.ReturnTypes[0]
+	}
+	b.addNode(&Node{ID: id, Kind: kind, Name: tree.Name, PkgPath: tree.PkgPath, PkgName: tree.PkgName, Type: typ})
+
+	for _, child := range tree.Childs {
+		b.addEdge(id, b.visit(child))
+	}
+	// 子のプロバイダで解決されない引数は外部からの入力
+	for _, param := range tree.Params {
+		if providedBy(param, tree.Childs) {
+			continue
+		}
+		inputID := "input:" + param
+		b.addNode(&Node{ID: inputID, Kind: KindInput, Name: ShortType(param), Type: param})
+		b.addEdge(id, inputID)
+	}
+	return id
+}
+
+// providedBy は param の型を供給する子のプロバイダがあるかを返す
+func providedBy(param string, childs []*analyze.FnDITreeNode) bool {
+	want := strings.TrimPrefix(param, "*")
+	for _, child := range childs {
+		for _, ret := range child.ReturnTypes {
+			if strings.TrimPrefix(ret, "*") == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ShortType は型の文字列のパッケージパスをパッケージ名に縮める（例: "*github.com/foo/service.User" → "*service.User"）
+func ShortType(t string) string {
+	var sb strings.Builder
+	start := 0
+	for i := 0; i <= len(t); i++ {
+		if i < len(t) && !isDelimiter(t[i]) {
+			continue
+		}
+		word := t[start:i]
+		if slash := strings.LastIndex(word, "/"); slash >= 0 {
+			word = word[slash+1:]
+		}
+		sb.WriteString(word)
+		if i < len(t) {
+			sb.WriteByte(t[i])
+		}
+		start = i + 1
+	}
+	return sb.String()
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte("*[](){},; ", c) >= 0
+}
+
+// CollapseByPackage はノードをパッケージ単位に集約したグラフを返す。ルート構造体と外部からの入力はそのまま残す
+func (g *Graph) CollapseByPackage() *Graph {
+	collapsed := &Graph{Roots: make([]*RootGraph, 0, len(g.Roots))}
+	for _, root := range g.Roots {
+		b := newRootBuilder(root.Name)
+		idMap := make(map[string]string)
+		for _, n := range root.Nodes {
+			if n.Kind == KindRoot || n.Kind == KindInput {
+				idMap[n.ID] = n.ID
+				b.addNode(n)
+				continue
+			}
+			pkgID := "pkg:" + n.PkgPath
+			idMap[n.ID] = pkgID
+			b.addNode(&Node{ID: pkgID, Kind: KindPackage, Name: n.PkgName, PkgPath: n.PkgPath, PkgName: n.PkgName})
+		}
+		for _, e := range root.Edges {
+			from, to := idMap[e.From], idMap[e.To]
+			if from == to {
+				continue
+			}
+			b.addEdge(from, to)
+		}
+		collapsed.Roots = append(collapsed.Roots, b.graph)
+	}
+	return collapsed
+}
+
+// Focus は指定された型を供給するノードとその依存先だけを残したグラフを返す。
+// typeName は "github.com/foo/service.UserService" のような完全な型名か、"service.UserService" のような短縮形で指定する
+func (g *Graph) Focus(typeName string) (*Graph, error) {
+	want := strings.TrimPrefix(typeName, "*")
+	focused := &Graph{Roots: make([]*RootGraph, 0)}
+	for _, root := range g.Roots {
+		starts := make([]string, 0)
+		for _, n := range root.Nodes {
+			if n.Kind == KindRoot {
+				continue
+			}
+			typ := strings.TrimPrefix(n.Type, "*")
+			if typ == want || strings.TrimPrefix(ShortType(n.Type), "*") == want {
+				starts = append(starts, n.ID)
+			}
+		}
+		if len(starts) == 0 {
+			continue
+		}
+
+		reachable := make(map[string]bool)
+		var walk func(id string)
+		walk = func(id string) {
+			if reachable[id] {
+				return
+			}
+			reachable[id] = true
+			for _, e := range root.Edges {
+				if e.From == id {
+					walk(e.To)
+				}
+			}
+		}
+		for _, id := range starts {
+			walk(id)
+		}
+
+		b := newRootBuilder(root.Name)
+		for _, n := range root.Nodes {
+			if reachable[n.ID] {
+				b.addNode(n)
+			}
+		}
+		for _, e := range root.Edges {
+			if reachable[e.From] && reachable[e.To] {
+				b.addEdge(e.From, e.To)
+			}
+		}
+		focused.Roots = append(focused.Roots, b.graph)
+	}
+	if len(focused.Roots) == 0 {
+		return nil, fmt.Errorf("no provider found for type %s", typeName)
+	}
+	return focused, nil
+}
@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/internal/analyze"
+)
+
+// testTrees は App -> NewHandler -> NewService(interface) -> NewRepository(*sql.DB を外部から受け取る) の解析結果
+func testTrees() analyze.RootTrees {
+	repo := &analyze.FnDITreeNode{
+		Name:        "NewRepository",
+		PkgPath:     "example.com/app/repository",
+		PkgName:     "repository",
+		ReturnTypes: []string{"*example.com/app/repository.Repository"},
+		Params:      []string{"*database/sql.DB"},
+	}
+	svc := &analyze.FnDITreeNode{
+		Name:             "NewService",
+		PkgPath:          "example.com/app/service",
+		PkgName:          "service",
+		Childs:           []*analyze.FnDITreeNode{repo},
+		ReturnTypes:      []string{"example.com/app/service.Service"},
+		Params:           []string{"*example.com/app/repository.Repository"},
+		ReturnsInterface: true,
+	}
+	handler := &analyze.FnDITreeNode{
+		Name:        "NewHandler",
+		PkgPath:     "example.com/app/handler",
+		PkgName:     "handler",
+		Childs:      []*analyze.FnDITreeNode{svc},
+		ReturnTypes: []string{"*example.com/app/handler.Handler"},
+		Params:      []string{"example.com/app/service.Service"},
+	}
+	return analyze.RootTrees{{Name: "App", Trees: []*analyze.FnDITreeNode{handler}}}
+}
+
+func nodeKinds(root *RootGraph) map[string]NodeKind {
+	kinds := make(map[string]NodeKind)
+	for _, n := range root.Nodes {
+		kinds[n.ID] = n.Kind
+	}
+	return kinds
+}
+
+func TestBuild(t *testing.T) {
+	g := Build(testTrees())
+	if len(g.Roots) != 1 {
+		t.Fatalf("len(Roots) = %d, want 1", len(g.Roots))
+	}
+
+	kinds := nodeKinds(g.Roots[0])
+	want := map[string]NodeKind{
+		"root:App":                                 KindRoot,
+		"example.com/app/handler.NewHandler":       KindProvider,
+		"example.com/app/service.NewService":       KindBinding,
+		"example.com/app/repository.NewRepository": KindProvider,
+		"input:*database/sql.DB":                   KindInput,
+	}
+	if len(kinds) != len(want) {
+		t.Errorf("ノード数 = %d, want %d: %v", len(kinds), len(want), kinds)
+	}
+	for id, kind := range want {
+		if kinds[id] != kind {
+			t.Errorf("ノード %q の種類 = %q, want %q", id, kinds[id], kind)
+		}
+	}
+	if got := len(g.Roots[0].Edges); got != 4 {
+		t.Errorf("エッジ数 = %d, want 4: %v", got, g.Roots[0].Edges)
+	}
+}
+
+func TestShortType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "*github.com/foo/service.User", want: "*service.User"},
+		{in: "[]example.com/a.B", want: "[]a.B"},
+		{in: "func(context.Context) error", want: "func(context.Context) error"},
+		{in: "App", want: "App"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := ShortType(tt.in); got != tt.want {
+				t.Errorf("ShortType(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraph_CollapseByPackage(t *testing.T) {
+	g := Build(testTrees()).CollapseByPackage()
+
+	kinds := nodeKinds(g.Roots[0])
+	for _, id := range []string{"pkg:example.com/app/handler", "pkg:example.com/app/service", "pkg:example.com/app/repository"} {
+		if kinds[id] != KindPackage {
+			t.Errorf("ノード %q の種類 = %q, want %q", id, kinds[id], KindPackage)
+		}
+	}
+	if kinds["root:App"] != KindRoot || kinds["input:*database/sql.DB"] != KindInput {
+		t.Errorf("ルート構造体または外部からの入力が残っていません: %v", kinds)
+	}
+}
+
+func TestGraph_Focus(t *testing.T) {
+	tests := []struct {
+		name      string
+		typeName  string
+		wantNodes []string
+		wantErr   bool
+	}{
+		{
+			name:      "短縮形の型名",
+			typeName:  "service.Service",
+			wantNodes: []string{"example.com/app/service.NewService", "example.com/app/repository.NewRepository", "input:*database/sql.DB"},
+		},
+		{
+			name:      "完全な型名とポインタ",
+			typeName:  "*example.com/app/repository.Repository",
+			wantNodes: []string{"example.com/app/repository.NewRepository", "input:*database/sql.DB"},
+		},
+		{
+			name:     "存在しない型",
+			typeName: "service.Unknown",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Build(testTrees()).Focus(tt.typeName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Focus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			kinds := nodeKinds(g.Roots[0])
+			if len(kinds) != len(tt.wantNodes) {
+				t.Errorf("ノード = %v, want %v", kinds, tt.wantNodes)
+			}
+			for _, id := range tt.wantNodes {
+				if _, ok := kinds[id]; !ok {
+					t.Errorf("ノード %q が含まれていません", id)
+				}
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format      string
+		wantErr     bool
+		wantContain []string
+	}{
+		{
+			format: FormatDOT,
+			wantContain: []string{
+				"digraph cire {",
+				`label="App";`,
+				`"App/example.com/app/service.NewService" [label="NewService\nservice\nservice.Service", shape=box, style="rounded,dashed"];`,
+				`"App/root:App" -> "App/example.com/app/handler.NewHandler";`,
+			},
+		},
+		{
+			format: FormatMermaid,
+			wantContain: []string{
+				"flowchart LR",
+				`subgraph r0 ["App"]`,
+				`"NewService<br/>service<br/>service.Service"`,
+				"classDef binding",
+			},
+		},
+		{
+			format:  "svg",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Render(&buf, Build(testTrees()), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			output := buf.String()
+			for _, want := range tt.wantContain {
+				if !strings.Contains(output, want) {
+					t.Errorf("Render() 出力に %q が含まれていません\n出力:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FormatDOT は Graphviz の DOT 形式
+	FormatDOT = "dot"
+	// FormatMermaid は Mermaid の flowchart 形式
+	FormatMermaid = "mermaid"
+)
+
+// Render はグラフを指定された形式で出力する
+func Render(w io.Writer, g *Graph, format string) error {
+	switch format {
+	case FormatDOT:
+		return RenderDOT(w, g)
+	case FormatMermaid:
+		return RenderMermaid(w, g)
+	default:
+		return fmt.Errorf("unsupported graph format: %q (expected %s or %s)", format, FormatDOT, FormatMermaid)
+	}
+}
+
+// dotStyles はノードの種類ごとの DOT の属性
+var dotStyles = map[NodeKind]string{
+	KindRoot:     `shape=box, style="bold,filled", fillcolor="#dbeafe"`,
+	KindProvider: `shape=box`,
+	KindBinding:  `shape=box, style="rounded,dashed"`,
+	KindInput:    `shape=ellipse, style=filled, fillcolor="#e5e7eb"`,
+	KindPackage:  `shape=folder`,
+}
+
+// RenderDOT はグラフを Graphviz の DOT 形式で出力する。ルート構造体ごとにクラスタを作る
+func RenderDOT(w io.Writer, g *Graph) error {
+	var sb strings.Builder
+	sb.WriteString("digraph cire {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [fontname=\"Helvetica\", fontsize=10];\n")
+	for i, root := range g.Roots {
+		fmt.Fprintf(&sb, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&sb, "    label=%s;\n", strconv.Quote(root.Name))
+		// 複数のルート構造体で同じプロバイダを使う場合があるため、ノードの ID にはルート構造体名を前置する
+		for _, n := range root.Nodes {
+			fmt.Fprintf(&sb, "    %s [label=%s, %s];\n", strconv.Quote(root.Name+"/"+n.ID), strconv.Quote(n.Label()), dotStyles[n.Kind])
+		}
+		for _, e := range root.Edges {
+			fmt.Fprintf(&sb, "    %s -> %s;\n", strconv.Quote(root.Name+"/"+e.From), strconv.Quote(root.Name+"/"+e.To))
+		}
+		sb.WriteString("  }\n")
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// mermaidClasses はノードの種類ごとの Mermaid のクラス定義
+var mermaidClasses = []struct {
+	kind NodeKind
+	def  string
+}{
+	{kind: KindRoot, def: "fill:#dbeafe,stroke:#1d4ed8,stroke-width:2px"},
+	{kind: KindBinding, def: "stroke-dasharray:5 5"},
+	{kind: KindInput, def: "fill:#e5e7eb,stroke:#6b7280"},
+}
+
+// RenderMermaid はグラフを Mermaid の flowchart 形式で出力する。ルート構造体ごとに subgraph を作る
+func RenderMermaid(w io.Writer, g *Graph) error {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	for i, root := range g.Roots {
+		// Mermaid の ID に使える文字は限られるため連番の ID を割り当てる
+		ids := make(map[string]string)
+		for j, n := range root.Nodes {
+			ids[n.ID] = fmt.Sprintf("r%dn%d", i, j)
+		}
+
+		fmt.Fprintf(&sb, "  subgraph r%d [%s]\n", i, mermaidText(root.Name))
+		for _, n := range root.Nodes {
+			fmt.Fprintf(&sb, "    %s%s\n", ids[n.ID], mermaidShape(n))
+		}
+		for _, e := range root.Edges {
+			fmt.Fprintf(&sb, "    %s --> %s\n", ids[e.From], ids[e.To])
+		}
+		sb.WriteString("  end\n")
+		for _, n := range root.Nodes {
+			if n.Kind == KindRoot || n.Kind == KindBinding || n.Kind == KindInput {
+				fmt.Fprintf(&sb, "  class %s %s\n", ids[n.ID], n.Kind)
+			}
+		}
+	}
+	for _, c := range mermaidClasses {
+		fmt.Fprintf(&sb, "  classDef %s %s\n", c.kind, c.def)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// mermaidShape はノードの種類に応じた Mermaid のノードの形を返す
+func mermaidShape(n *Node) string {
+	label := mermaidText(strings.ReplaceAll(n.Label(), "\n", "<br/>"))
+	switch n.Kind {
+	case KindRoot:
+		return "[[" + label + "]]"
+	case KindBinding:
+		return "{{" + label + "}}"
+	case KindInput:
+		return "([" + label + "])"
+	case KindPackage:
+		return "[/" + label + "/]"
+	default:
+		return "[" + label + "]"
+	}
+}
+
+// mermaidText は Mermaid のラベルとして安全な文字列に変換する
+func mermaidText(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
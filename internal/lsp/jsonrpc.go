@@ -0,0 +1,123 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC 2.0 のエラーコード
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+	// codeInvalidRequest は shutdown の後に受け取ったリクエストに返す
+	codeInvalidRequest = -32600
+)
+
+// message は JSON-RPC のリクエスト・通知・レスポンスのいずれか
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// conn は Content-Length ヘッダーで区切られた JSON-RPC のメッセージを読み書きする
+type conn struct {
+	r *bufio.Reader
+	// mu は複数の goroutine からの書き込みを直列化する
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: bufio.NewReader(r), w: w}
+}
+
+// read は次のメッセージを読む。入力が終わった場合は io.EOF を返す
+func (c *conn) read() (*message, error) {
+	header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, &rpcError{Code: codeParseError, Message: err.Error()}
+	}
+	return msg, nil
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// reply はリクエストにレスポンスを返す。err が nil でない場合はエラーのレスポンスを返す
+func (c *conn) reply(id *json.RawMessage, result any, err error) error {
+	if err == nil {
+		if result == nil {
+			// result は省略できないため、結果の無いレスポンスでは null を返す
+			result = json.RawMessage("null")
+		}
+		return c.write(&message{ID: id, Result: result})
+	}
+	rerr, ok := err.(*rpcError)
+	if !ok {
+		rerr = &rpcError{Code: codeInternalError, Message: err.Error()}
+	}
+	return c.write(&message{ID: id, Error: rerr})
+}
+
+// notify はサーバーからクライアントへ通知を送る
+func (c *conn) notify(method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{Method: method, Params: body})
+}
+
+// request はサーバーからクライアントへリクエストを送る。クライアントのレスポンスは読み捨てる
+func (c *conn) request(id int, method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(strconv.Itoa(id))
+	return c.write(&message{ID: &raw, Method: method, Params: body})
+}
@@ -0,0 +1,509 @@
+// Package lsp は cire のルート構造体を定義したファイル（cire.go）を編集するための Language Server を提供する。
+//
+// 開いているルートファイルの保存されていない内容を packages.Config.Overlay でロードして診断を配信し、
+// コードレンズ（wire.go の再生成と依存グラフの表示）、ルート構造体のフィールドのホバー（プロバイダの連鎖）と
+// 定義へのジャンプ（選ばれたコンストラクタ）に応答する
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/rmocchy/cire/internal/config"
+	"github.com/rmocchy/cire/internal/generate"
+	"github.com/rmocchy/cire/internal/graph"
+	"github.com/rmocchy/cire/internal/version"
+)
+
+// workspace/executeCommand で実行できるコマンド
+const (
+	// CommandGenerate は引数 [uri] のルートファイルから wire.go を再生成する
+	CommandGenerate = "cire.generate"
+	// CommandGraph は引数 [uri, ルート構造体名] の依存グラフを Mermaid で表示する
+	CommandGraph = "cire.graph"
+)
+
+// newCommand は文字列の引数を持つコマンドを作成する
+func newCommand(title, command string, args ...string) *Command {
+	c := &Command{Title: title, Command: command}
+	for _, arg := range args {
+		raw, _ := json.Marshal(arg)
+		c.Arguments = append(c.Arguments, raw)
+	}
+	return c
+}
+
+// defaultDelay は最後の変更から再解析を始めるまでの待ち時間
+const defaultDelay = 300 * time.Millisecond
+
+// document はクライアントが開いているドキュメント
+type document struct {
+	version int
+	text    []byte
+}
+
+// Server は 1 つのクライアントとの接続を扱う Language Server
+type Server struct {
+	conn *conn
+	// files はルート構造体を定義したファイル名
+	files []string
+	// delay は最後の変更から再解析を始めるまでの待ち時間
+	delay time.Duration
+
+	// mu は以下のフィールドを保護する
+	mu        sync.Mutex
+	docs      map[string]*document
+	snapshots map[string]*snapshot
+	timer     *time.Timer
+	shutdown  bool
+	requestID int
+	// generation はドキュメントの変更や保存のたびに増える。解析結果が最新かどうかの判定に使う
+	generation int
+
+	// analyzing は解析を直列化する（後から始めた解析の結果が常に新しい内容になるようにする）
+	analyzing sync.Mutex
+}
+
+// NewServer は r からリクエストを読み、w にレスポンスと通知を書き込むサーバーを作成する
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		conn:      newConn(r, w),
+		files:     []string{app.InitFileName},
+		delay:     defaultDelay,
+		docs:      make(map[string]*document),
+		snapshots: make(map[string]*snapshot),
+	}
+}
+
+// Serve は標準入出力などのストリームで Language Server を実行する。
+// 入力が終わるか exit 通知を受け取ると終了する
+func Serve(r io.Reader, w io.Writer) error {
+	return NewServer(r, w).Run()
+}
+
+// Run はメッセージを読み終えるまで処理する。shutdown を受け取らずに exit した場合はエラーを返す
+func (s *Server) Run() error {
+	for {
+		msg, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rerr *rpcError
+		if errors.As(err, &rerr) {
+			s.conn.reply(nil, nil, rerr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if !s.shutdown {
+				return errors.New("exit notification received before shutdown")
+			}
+			return nil
+		}
+		if msg.Method == "" {
+			// サーバーから送ったリクエスト（window/showDocument など）へのレスポンスは使わない
+			continue
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) (any, error) {
+	s.mu.Lock()
+	shutdown := s.shutdown
+	s.mu.Unlock()
+	if shutdown {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(&params)
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.shutdown = true
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		s.setDocument(params.TextDocument.URI, &document{version: params.TextDocument.Version, text: []byte(params.TextDocument.Text)})
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// Full 同期のため最後の変更がドキュメント全体になる
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		s.setDocument(params.TextDocument.URI, &document{version: params.TextDocument.Version, text: []byte(text)})
+		return nil, nil
+	case "textDocument/didSave":
+		// 開いていないファイルはディスクから読むため、保存のたびに解析し直す
+		s.scheduleAnalysis()
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.closeDocument(params.TextDocument.URI)
+	case "textDocument/codeLens":
+		var params CodeLensParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		snap := s.snapshot(params.TextDocument.URI)
+		if snap == nil {
+			return []CodeLens{}, nil
+		}
+		return snap.codeLenses(), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(&params), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(&params), nil
+	case "workspace/executeCommand":
+		var params ExecuteCommandParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.executeCommand(&params)
+	}
+	if msg.ID == nil {
+		// 未対応の通知（$/cancelRequest など）は無視する
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func unmarshalParams(msg *message, v any) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) initialize(params *InitializeParams) (*InitializeResult, error) {
+	if len(params.InitializationOptions) > 0 {
+		var opts InitializationOptions
+		if err := json.Unmarshal(params.InitializationOptions, &opts); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid initializationOptions: " + err.Error()}
+		}
+		if len(opts.Files) > 0 {
+			s.files = opts.Files
+		}
+	}
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:       1,
+			HoverProvider:          true,
+			DefinitionProvider:     true,
+			CodeLensProvider:       &CodeLensOptions{},
+			ExecuteCommandProvider: &ExecuteCommandOptions{Commands: []string{CommandGenerate, CommandGraph}},
+		},
+		ServerInfo: ServerInfo{Name: "cire", Version: version.Version()},
+	}, nil
+}
+
+// isRootFile はルート構造体を定義したファイルかどうかを返す
+func (s *Server) isRootFile(path string) bool {
+	return slices.Contains(s.files, filepath.Base(path))
+}
+
+// setDocument は開いているドキュメントの内容を更新し、再解析を予約する。
+// ルートファイル以外の Go ファイルの変更もルートファイルの解析結果に影響するため、全ての変更で再解析する
+func (s *Server) setDocument(uri string, doc *document) {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.docs[path] = doc
+	s.mu.Unlock()
+	s.scheduleAnalysis()
+}
+
+// closeDocument はドキュメントを閉じる。ルートファイルの場合は表示している診断を消す
+func (s *Server) closeDocument(uri string) error {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	delete(s.docs, path)
+	delete(s.snapshots, path)
+	s.mu.Unlock()
+	s.scheduleAnalysis()
+	if !s.isRootFile(path) {
+		return nil
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}})
+}
+
+// scheduleAnalysis は最後の変更から delay だけ待って、開いている全てのルートファイルを解析し直す
+func (s *Server) scheduleAnalysis() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return
+	}
+	s.generation++
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.timer = time.AfterFunc(s.delay, s.analyzeOpenFiles)
+}
+
+// analyzeOpenFiles は開いている全てのルートファイルを解析し、診断を配信する
+func (s *Server) analyzeOpenFiles() {
+	s.mu.Lock()
+	paths := make([]string, 0)
+	for path := range s.docs {
+		if s.isRootFile(path) {
+			paths = append(paths, path)
+		}
+	}
+	s.mu.Unlock()
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		snap := s.analyze(path)
+		if snap == nil {
+			continue
+		}
+		s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+			URI:         pathToURI(path),
+			Version:     snap.version,
+			Diagnostics: snap.diagnostics(),
+		})
+	}
+}
+
+// analyze は開いているドキュメントの内容でルートファイルを解析し、結果を保存する。
+// ルートファイルが開かれていない場合は nil
+func (s *Server) analyze(path string) *snapshot {
+	s.analyzing.Lock()
+	defer s.analyzing.Unlock()
+
+	s.mu.Lock()
+	doc, ok := s.docs[path]
+	generation := s.generation
+	overlay := make(map[string][]byte, len(s.docs))
+	for p, d := range s.docs {
+		overlay[p] = d.text
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	snap := analyzeFile(path, doc.version, overlay)
+	snap.generation = generation
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.docs[path]; ok {
+		s.snapshots[path] = snap
+	}
+	return snap
+}
+
+// snapshot は開いているルートファイルの最新の内容の解析結果を返す。
+// 予約した再解析がまだ終わっていない場合はその場で解析する
+func (s *Server) snapshot(uri string) *snapshot {
+	path, err := uriToPath(uri)
+	if err != nil || !s.isRootFile(path) {
+		return nil
+	}
+	s.mu.Lock()
+	_, ok := s.docs[path]
+	snap := s.snapshots[path]
+	generation := s.generation
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if snap != nil && snap.generation == generation {
+		return snap
+	}
+	return s.analyze(path)
+}
+
+func (s *Server) hover(params *TextDocumentPositionParams) *Hover {
+	snap := s.snapshot(params.TextDocument.URI)
+	if snap == nil {
+		return nil
+	}
+	f := snap.fieldAt(params.Position)
+	if f == nil {
+		return nil
+	}
+	r := snap.identRange(snap.proj.RootPkg.Fset.Position(f.Field.Pos()))
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: snap.hover(f)}, Range: &r}
+}
+
+func (s *Server) definition(params *TextDocumentPositionParams) *Location {
+	snap := s.snapshot(params.TextDocument.URI)
+	if snap == nil {
+		return nil
+	}
+	f := snap.fieldAt(params.Position)
+	if f == nil {
+		return nil
+	}
+	pos, ok := snap.definition(f)
+	if !ok {
+		return nil
+	}
+	loc := snap.location(pos)
+	return &loc
+}
+
+func (s *Server) executeCommand(params *ExecuteCommandParams) error {
+	args := make([]string, len(params.Arguments))
+	for i, raw := range params.Arguments {
+		if err := json.Unmarshal(raw, &args[i]); err != nil {
+			return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("argument %d of %s must be a string", i, params.Command)}
+		}
+	}
+	switch params.Command {
+	case CommandGenerate:
+		if len(args) != 1 {
+			return &rpcError{Code: codeInvalidParams, Message: CommandGenerate + " expects [uri]"}
+		}
+		return s.generate(args[0])
+	case CommandGraph:
+		if len(args) != 2 {
+			return &rpcError{Code: codeInvalidParams, Message: CommandGraph + " expects [uri, root]"}
+		}
+		return s.graph(args[0], args[1])
+	}
+	return &rpcError{Code: codeInvalidParams, Message: "unknown command: " + params.Command}
+}
+
+// generate は cire generate と同じ手順で wire.go を再生成し、結果をメッセージで表示する。
+// 生成は保存済みのファイルから行う（保存していない変更は生成結果に含まれない）
+func (s *Server) generate(uri string) error {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	var out bytes.Buffer
+	err = app.RunGenerate(&app.GenerateInput{
+		FilePath: path,
+		Command:  generateCommand(path),
+		Format:   "text",
+		Stdout:   &out,
+		Stderr:   &out,
+	})
+	if err != nil {
+		return s.conn.notify("window/showMessage", &ShowMessageParams{Type: MessageError, Message: fmt.Sprintf("cire generate failed: %v\n%s", err, out.String())})
+	}
+	return s.conn.notify("window/showMessage", &ShowMessageParams{Type: MessageInfo, Message: out.String()})
+}
+
+// generateCommand は生成ファイルのヘッダーに記録するコマンドラインを返す。
+// 既存の生成ファイルに記録されたコマンドがあればそれを引き継ぐ
+func generateCommand(path string) string {
+	if cfg, err := app.LoadConfig(path, "", nil); err == nil {
+		if src, err := os.ReadFile(config.ResolveOutput(path, cfg.Output.Wire)); err == nil {
+			if command := generate.ParseHeader(src).Command; command != "" {
+				return command
+			}
+		}
+	}
+	return "cire generate -f " + filepath.Base(path)
+}
+
+// graph はルート構造体の依存グラフを Mermaid の Markdown ファイルに書き出し、クライアントに表示させる
+func (s *Server) graph(uri, root string) error {
+	snap := s.snapshot(uri)
+	if snap == nil || snap.proj == nil {
+		return &rpcError{Code: codeInvalidParams, Message: "not an open cire file: " + uri}
+	}
+	trees, _, err := snap.proj.AnalyzeRoots()
+	if err != nil {
+		return err
+	}
+	trees = slices.DeleteFunc(trees, func(tree analyze.RootTree) bool { return tree.Name != root })
+	if len(trees) == 0 {
+		return &rpcError{Code: codeInvalidParams, Message: "root struct not found: " + root}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\n```mermaid\n", root)
+	if err := graph.Render(&buf, graph.Build(trees), "mermaid"); err != nil {
+		return err
+	}
+	buf.WriteString("```\n")
+
+	out := filepath.Join(os.TempDir(), fmt.Sprintf("cire-graph-%s.md", root))
+	if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+
+	s.mu.Lock()
+	s.requestID++
+	id := s.requestID
+	s.mu.Unlock()
+	return s.conn.request(id, "window/showDocument", &ShowDocumentParams{URI: pathToURI(out), TakeFocus: true})
+}
+
+// pathToURI はファイルの絶対パスを file スキームの URI に変換する
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// uriToPath は file スキームの URI をファイルの絶対パスに変換する
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme: %s", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
@@ -0,0 +1,204 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unsavedRoot は sample/basic/cire.go に、プロバイダの無いフィールドを追加した保存されていない内容
+const unsavedRoot = `package main
+
+import (
+	"github.com/rmocchy/cire/sample/basic/handler"
+)
+
+// App は依存関係の解析対象となるルート構造体
+type App struct {
+	handler *handler.UserHandler
+	clock   Clock
+}
+
+type Clock interface {
+	Now() int64
+}
+`
+
+// client はテストからサーバーにリクエストを送り、レスポンスと通知を受け取る
+type client struct {
+	t    *testing.T
+	conn *conn
+	id   int
+	// notifications はレスポンスを待つ間に受け取ったサーバーからの通知とリクエスト
+	notifications []*message
+}
+
+func startServer(t *testing.T) *client {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	s := NewServer(serverR, serverW)
+	s.delay = 10 * time.Millisecond
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run()
+		serverW.Close()
+	}()
+	t.Cleanup(func() {
+		clientW.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	})
+	return &client{t: t, conn: newConn(clientR, clientW)}
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatalf("notify(%s) error = %v", method, err)
+	}
+}
+
+// call はリクエストを送り、レスポンスの result を v に読み込む
+func (c *client) call(method string, params any, v any) {
+	c.t.Helper()
+	c.id++
+	if err := c.conn.request(c.id, method, params); err != nil {
+		c.t.Fatalf("request(%s) error = %v", method, err)
+	}
+	for {
+		msg := c.read()
+		if msg.Method != "" {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		if msg.Error != nil {
+			c.t.Fatalf("%s error = %v", method, msg.Error)
+		}
+		raw, _ := json.Marshal(msg.Result)
+		if err := json.Unmarshal(raw, v); err != nil {
+			c.t.Fatalf("%s result = %s: %v", method, raw, err)
+		}
+		return
+	}
+}
+
+// waitNotification は指定したメソッドの通知を受け取るまで待つ
+func (c *client) waitNotification(method string) *message {
+	c.t.Helper()
+	for i, msg := range c.notifications {
+		if msg.Method == method {
+			c.notifications = append(c.notifications[:i], c.notifications[i+1:]...)
+			return msg
+		}
+	}
+	for {
+		msg := c.read()
+		if msg.Method == method {
+			return msg
+		}
+		c.notifications = append(c.notifications, msg)
+	}
+}
+
+func (c *client) read() *message {
+	c.t.Helper()
+	msg, err := c.conn.read()
+	if err != nil {
+		c.t.Fatalf("read() error = %v", err)
+	}
+	return msg
+}
+
+func TestServer(t *testing.T) {
+	path, err := filepath.Abs("../../sample/basic/cire.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(path)
+	c := startServer(t)
+
+	var init InitializeResult
+	c.call("initialize", &InitializeParams{}, &init)
+	if !init.Capabilities.HoverProvider || !init.Capabilities.DefinitionProvider || init.Capabilities.CodeLensProvider == nil {
+		t.Errorf("capabilities = %+v", init.Capabilities)
+	}
+	c.notify("initialized", struct{}{})
+	c.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "go", Version: 1, Text: unsavedRoot},
+	})
+
+	t.Run("保存されていない内容の診断", func(t *testing.T) {
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(c.waitNotification("textDocument/publishDiagnostics").Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		if params.URI != uri || params.Version != 1 || len(params.Diagnostics) != 1 {
+			t.Fatalf("publishDiagnostics = %+v", params)
+		}
+		d := params.Diagnostics[0]
+		if d.Code != "CIRE001" || !strings.Contains(d.Message, "Clock") {
+			t.Errorf("diagnostic = %+v", d)
+		}
+		// clock フィールドの名前の範囲
+		want := Range{Start: Position{Line: 9, Character: 1}, End: Position{Line: 9, Character: 6}}
+		if d.Range != want {
+			t.Errorf("diagnostic range = %+v, want %+v", d.Range, want)
+		}
+	})
+
+	t.Run("コードレンズ", func(t *testing.T) {
+		var lenses []CodeLens
+		c.call("textDocument/codeLens", &CodeLensParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &lenses)
+		if len(lenses) != 2 {
+			t.Fatalf("codeLens = %+v", lenses)
+		}
+		if lenses[0].Command.Command != CommandGenerate || lenses[1].Command.Command != CommandGraph {
+			t.Errorf("commands = %s, %s", lenses[0].Command.Command, lenses[1].Command.Command)
+		}
+		if lenses[0].Range.Start != (Position{Line: 7, Character: 5}) {
+			t.Errorf("codeLens range = %+v", lenses[0].Range)
+		}
+	})
+
+	t.Run("ホバー", func(t *testing.T) {
+		var hover Hover
+		c.call("textDocument/hover", &TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 8, Character: 3}}, &hover)
+		for _, want := range []string{"**App.handler**", "`handler.NewUserHandler` — handler/user_handler.go:15", "  - `service.NewUserService`"} {
+			if !strings.Contains(hover.Contents.Value, want) {
+				t.Errorf("hover = %q, want to contain %q", hover.Contents.Value, want)
+			}
+		}
+	})
+
+	t.Run("定義へのジャンプ", func(t *testing.T) {
+		var loc Location
+		c.call("textDocument/definition", &TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 8, Character: 12}}, &loc)
+		if !strings.HasSuffix(loc.URI, "/sample/basic/handler/user_handler.go") {
+			t.Errorf("definition uri = %s", loc.URI)
+		}
+		want := Range{Start: Position{Line: 14, Character: 5}, End: Position{Line: 14, Character: 19}}
+		if loc.Range != want {
+			t.Errorf("definition range = %+v, want %+v", loc.Range, want)
+		}
+	})
+
+	t.Run("閉じたファイルの診断を消す", func(t *testing.T) {
+		c.notify("textDocument/didClose", &DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(c.waitNotification("textDocument/publishDiagnostics").Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		if len(params.Diagnostics) != 0 {
+			t.Errorf("diagnostics after close = %+v", params.Diagnostics)
+		}
+	})
+
+	var result any
+	c.call("shutdown", nil, &result)
+	c.notify("exit", nil)
+}
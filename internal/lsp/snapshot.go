@@ -0,0 +1,301 @@
+package lsp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/app"
+	"github.com/rmocchy/cire/internal/diag"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// snapshot はルートファイルのある時点の内容（保存されていないバッファを含む）の解析結果
+type snapshot struct {
+	path    string
+	version int
+	// generation は解析したときのサーバーの変更の世代
+	generation int
+	proj       *app.Project
+	fields     []*app.FieldAnalysis
+	// err はパッケージのロードや解析に失敗した場合のエラー
+	err error
+	// positions は「パッケージパス.名前」からパッケージレベルの関数と型の位置を引く
+	positions map[string]token.Position
+	// content はファイルの内容を返す。開いているドキュメントはバッファの内容を使う
+	content func(path string) []byte
+}
+
+// analyzeFile はルートファイルを cire generate と同じ設定でロードし、フィールドごとに解析する
+func analyzeFile(path string, version int, overlay map[string][]byte) *snapshot {
+	s := &snapshot{path: path, version: version, positions: make(map[string]token.Position)}
+	s.content = func(p string) []byte {
+		if text, ok := overlay[p]; ok {
+			return text
+		}
+		text, _ := os.ReadFile(p)
+		return text
+	}
+
+	cfg, err := app.LoadConfig(path, "", nil)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	if s.proj, err = app.LoadProjectWithOverlay(path, cfg, overlay); err != nil {
+		s.err = err
+		return s
+	}
+	if s.fields, err = s.proj.AnalyzeFields(); err != nil {
+		s.err = err
+		return s
+	}
+	packages.Visit(s.proj.Pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil {
+			return
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			s.positions[pkg.PkgPath+"."+name] = pkg.Fset.Position(scope.Lookup(name).Pos())
+		}
+	})
+	return s
+}
+
+// diagnostics はルートファイルに表示する診断を返す。
+// ルートファイルの外で見つかった問題は、原因になったルート構造体のフィールドに表示し、元の位置を関連情報に示す
+func (s *snapshot) diagnostics() []Diagnostic {
+	result := make([]Diagnostic, 0)
+	if s.err != nil {
+		return append(result, Diagnostic{Severity: SeverityError, Source: "cire", Message: s.err.Error()})
+	}
+	for _, f := range s.fields {
+		fieldRange := s.identRange(s.proj.RootPkg.Fset.Position(f.Field.Pos()))
+		for _, d := range f.Diagnostics {
+			ld := Diagnostic{
+				Range:    fieldRange,
+				Severity: SeverityError,
+				Code:     string(d.Code),
+				Source:   "cire",
+				Message:  d.Message,
+			}
+			if d.Severity == diag.SeverityWarning {
+				ld.Severity = SeverityWarning
+			}
+			if d.Position.Filename == s.path {
+				ld.Range = s.identRange(d.Position)
+			} else if d.Position.Filename != "" {
+				ld.RelatedInformation = append(ld.RelatedInformation, DiagnosticRelatedInformation{Location: s.location(d.Position), Message: "reported here"})
+			}
+			for _, r := range d.Related {
+				if r.Position.Filename != "" {
+					ld.RelatedInformation = append(ld.RelatedInformation, DiagnosticRelatedInformation{Location: s.location(r.Position), Message: r.Message})
+				}
+			}
+			result = append(result, ld)
+		}
+	}
+	return result
+}
+
+// codeLenses はルート構造体ごとに再生成とグラフ表示のコードレンズを返す
+func (s *snapshot) codeLenses() []CodeLens {
+	lenses := make([]CodeLens, 0)
+	if s.proj == nil {
+		return lenses
+	}
+	uri := pathToURI(s.path)
+	for _, root := range s.proj.Structs {
+		r := s.identRange(s.proj.RootPkg.Fset.Position(root.Obj().Pos()))
+		lenses = append(lenses,
+			CodeLens{Range: r, Command: newCommand("Regenerate wire.go", CommandGenerate, uri)},
+			CodeLens{Range: r, Command: newCommand("Show dependency graph", CommandGraph, uri, root.Obj().Name())},
+		)
+	}
+	return lenses
+}
+
+// fieldAt はルートファイルの位置にあるルート構造体のフィールドの解析結果を返す。見つからない場合は nil
+func (s *snapshot) fieldAt(pos Position) *app.FieldAnalysis {
+	if s.proj == nil {
+		return nil
+	}
+	fset := s.proj.RootPkg.Fset
+	for _, f := range s.proj.RootPkg.Syntax {
+		tf := fset.File(f.Pos())
+		if tf.Name() != s.path {
+			continue
+		}
+		offset := byteOffset(s.content(s.path), pos)
+		if offset < 0 || offset > tf.Size() {
+			return nil
+		}
+		path, _ := astutil.PathEnclosingInterval(f, tf.Pos(offset), tf.Pos(offset))
+		for _, n := range path {
+			field, ok := n.(*ast.Field)
+			if !ok {
+				continue
+			}
+			for _, fa := range s.fields {
+				if field.Pos() <= fa.Field.Pos() && fa.Field.Pos() < field.End() {
+					return fa
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hover はフィールドの値を供給するプロバイダの連鎖を Markdown で返す
+func (s *snapshot) hover(f *app.FieldAnalysis) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s.%s** `%s`\n\n", f.Root.Obj().Name(), f.Field.Name(), types.TypeString(f.Field.Type(), packageName))
+	if len(f.Nodes) == 0 {
+		sb.WriteString("No provider found.\n")
+		for _, d := range f.Diagnostics {
+			fmt.Fprintf(&sb, "\n- %s: %s", d.Code, d.Message)
+		}
+		return sb.String()
+	}
+	sb.WriteString("Provider chain:\n\n")
+	visited := make(map[*analyze.FnDITreeNode]bool)
+	var walk func(nodes []*analyze.FnDITreeNode, depth int)
+	walk = func(nodes []*analyze.FnDITreeNode, depth int) {
+		for _, node := range nodes {
+			fmt.Fprintf(&sb, "%s- %s", strings.Repeat("  ", depth), nodeLabel(node))
+			if pos, ok := s.nodePosition(node); ok {
+				fmt.Fprintf(&sb, " — %s:%d", s.relPath(pos.Filename), pos.Line)
+			}
+			if visited[node] && len(node.Childs) > 0 {
+				sb.WriteString(" (see above)\n")
+				continue
+			}
+			sb.WriteString("\n")
+			visited[node] = true
+			walk(node.Childs, depth+1)
+		}
+	}
+	walk(f.Nodes, 0)
+	return sb.String()
+}
+
+// definition はフィールドの値を供給するコンストラクタの位置を返す。
+// インターフェースの束縛では実装のコンストラクタ、構造体やフィールドのプロバイダでは構造体の宣言を返す
+func (s *snapshot) definition(f *app.FieldAnalysis) (token.Position, bool) {
+	if len(f.Nodes) == 0 {
+		return token.Position{}, false
+	}
+	node := f.Nodes[0]
+	if node.Kind == analyze.ProviderBind && len(node.Childs) > 0 {
+		node = node.Childs[0]
+	}
+	return s.nodePosition(node)
+}
+
+// nodePosition はプロバイダの関数または型の宣言の位置を返す
+func (s *snapshot) nodePosition(node *analyze.FnDITreeNode) (token.Position, bool) {
+	key := node.PkgPath + "." + node.Name
+	if node.Source != nil {
+		key = node.Source.PkgPath + "." + node.Source.Name
+	}
+	pos, ok := s.positions[key]
+	return pos, ok && pos.IsValid()
+}
+
+// nodeLabel はプロバイダの種類ごとの説明を返す（例: "`service.NewService`", "struct `config.Config`"）
+func nodeLabel(node *analyze.FnDITreeNode) string {
+	name := node.PkgName + "." + node.Name
+	switch node.Kind {
+	case analyze.ProviderBind:
+		return fmt.Sprintf("`%s` bound to `%s`", name, node.Source.String())
+	case analyze.ProviderStruct:
+		return fmt.Sprintf("struct `%s`", name)
+	case analyze.ProviderField:
+		return fmt.Sprintf("field `%s`", name)
+	}
+	return "`" + name + "`"
+}
+
+func packageName(pkg *types.Package) string {
+	return pkg.Name()
+}
+
+// relPath はルートファイルのディレクトリからの相対パスを返す
+func (s *snapshot) relPath(path string) string {
+	rel, err := filepath.Rel(filepath.Dir(s.path), path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// location は解決済みの位置を、その位置にある識別子の範囲に変換する
+func (s *snapshot) location(p token.Position) Location {
+	return Location{URI: pathToURI(p.Filename), Range: s.identRange(p)}
+}
+
+// identRange は位置から始まる識別子の範囲を返す。識別子が無い場合は長さ 0 の範囲
+func (s *snapshot) identRange(p token.Position) Range {
+	if p.Line == 0 {
+		return Range{}
+	}
+	content := s.content(p.Filename)
+	start := lspPosition(content, p)
+	end := start
+	if p.Offset >= 0 && p.Offset <= len(content) {
+		rest := content[p.Offset:]
+		n := 0
+		for n < len(rest) {
+			r, size := utf8.DecodeRune(rest[n:])
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+				break
+			}
+			end.Character += len(utf16.Encode([]rune{r}))
+			n += size
+		}
+	}
+	return Range{Start: start, End: end}
+}
+
+// lspPosition は token.Position（列はバイト単位）を LSP の位置（列は UTF-16 単位）に変換する
+func lspPosition(content []byte, p token.Position) Position {
+	if p.Line == 0 {
+		return Position{}
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if p.Line > len(lines) {
+		return Position{Line: p.Line - 1}
+	}
+	line := lines[p.Line-1]
+	col := min(max(p.Column-1, 0), len(line))
+	return Position{Line: p.Line - 1, Character: len(utf16.Encode([]rune(line[:col])))}
+}
+
+// byteOffset は LSP の位置をファイル先頭からのバイト数に変換する。位置がファイルの外の場合は -1
+func byteOffset(content []byte, pos Position) int {
+	lines := strings.SplitAfter(string(content), "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return -1
+	}
+	offset := 0
+	for _, line := range lines[:pos.Line] {
+		offset += len(line)
+	}
+	units := 0
+	for i, r := range lines[pos.Line] {
+		if units >= pos.Character {
+			return offset + i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return offset + len(lines[pos.Line])
+}
@@ -0,0 +1,324 @@
+package report
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rmocchy/cire/internal/analyze"
+	"github.com/rmocchy/cire/internal/diag"
+	"golang.org/x/tools/go/packages"
+)
+
+// Source はレポートの元になる解析結果
+type Source struct {
+	// InputPath はルート構造体を定義したファイル
+	InputPath string
+	// BaseDir はレポート内のファイルパスの基準となるディレクトリ（通常はレポートの出力先）
+	BaseDir     string
+	PackageName string
+	PackagePath string
+	// Structs はルート構造体（Trees と同じ順序）
+	Structs     []*types.Named
+	Trees       analyze.RootTrees
+	Pkgs        []*packages.Package
+	Diagnostics diag.List
+	Version     string
+}
+
+// Build は解析結果からレポートを作成する
+func Build(src *Source) *Report {
+	b := newBuilder(src)
+	r := &Report{
+		SchemaVersion: SchemaVersion,
+		Generator:     Generator{Name: "cire", Version: src.Version},
+		Input:         b.relPath(src.InputPath),
+		Package:       Package{Name: src.PackageName, Path: src.PackagePath},
+		Roots:         make([]Root, 0, len(src.Trees)),
+		Diagnostics:   make([]Diagnostic, 0, len(src.Diagnostics)),
+	}
+	for i, tree := range src.Trees {
+		var structure *types.Named
+		if i < len(src.Structs) {
+			structure = src.Structs[i]
+		}
+		r.Roots = append(r.Roots, b.root(tree, structure))
+	}
+	for _, d := range src.Diagnostics {
+		r.Diagnostics = append(r.Diagnostics, b.diagnostic(d))
+	}
+	return r
+}
+
+type builder struct {
+	src  *Source
+	fset *token.FileSet
+	// funcs は「パッケージパス.関数名」からプロバイダ関数を引く
+	funcs map[string]*types.Func
+	// decls はプロバイダ関数の宣言と型情報
+	decls map[*types.Func]funcDecl
+	// types は「パッケージパス.型名」から型を引く
+	types map[string]*types.TypeName
+}
+
+type funcDecl struct {
+	decl *ast.FuncDecl
+	info *types.Info
+}
+
+func newBuilder(src *Source) *builder {
+	b := &builder{
+		src:   src,
+		funcs: make(map[string]*types.Func),
+		decls: make(map[*types.Func]funcDecl),
+		types: make(map[string]*types.TypeName),
+	}
+	packages.Visit(src.Pkgs, nil, func(pkg *packages.Package) {
+		if b.fset == nil {
+			b.fset = pkg.Fset
+		}
+		if pkg.Types != nil {
+			scope := pkg.Types.Scope()
+			for _, name := range scope.Names() {
+				if tn, ok := scope.Lookup(name).(*types.TypeName); ok {
+					b.types[pkg.PkgPath+"."+name] = tn
+				}
+			}
+		}
+		if pkg.TypesInfo == nil {
+			return
+		}
+		for _, f := range pkg.Syntax {
+			for _, decl := range f.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Recv != nil {
+					continue
+				}
+				if fn, ok := pkg.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+					b.funcs[pkg.PkgPath+"."+fn.Name()] = fn
+					b.decls[fn] = funcDecl{decl: fd, info: pkg.TypesInfo}
+				}
+			}
+		}
+	})
+	return b
+}
+
+func (b *builder) relPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	base, err := filepath.Abs(b.src.BaseDir)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (b *builder) position(pos token.Pos) Position {
+	if b.fset == nil || !pos.IsValid() {
+		return Position{}
+	}
+	p := b.fset.Position(pos)
+	return Position{File: b.relPath(p.Filename), Line: p.Line, Column: p.Column}
+}
+
+func (b *builder) diagnostic(d *diag.Diagnostic) Diagnostic {
+	result := Diagnostic{
+		Code:     string(d.Code),
+		Severity: string(d.Severity),
+		Message:  d.Message,
+		Position: b.sourcePosition(d.Position),
+	}
+	for _, r := range d.Related {
+		result.Related = append(result.Related, Related{Position: b.sourcePosition(r.Position), Message: r.Message})
+	}
+	return result
+}
+
+// sourcePosition は解決済みの位置をレポート用に変換する。ファイルが不明な場合は nil
+func (b *builder) sourcePosition(pos token.Position) *Position {
+	if pos.Filename == "" {
+		return nil
+	}
+	return &Position{File: b.relPath(pos.Filename), Line: pos.Line, Column: pos.Column}
+}
+
+func (b *builder) root(tree analyze.RootTree, structure *types.Named) Root {
+	root := Root{
+		Name:      tree.Name,
+		Fields:    make([]Field, 0),
+		Providers: make([]Provider, 0),
+		Inputs:    make([]Input, 0),
+	}
+
+	converter := analyze.NewConvertTreeToUniqueList()
+	for _, node := range tree.Trees {
+		converter.Execute(node)
+	}
+	inputs := make(map[string][]string)
+	for _, node := range converter.List() {
+		provider := b.provider(node)
+		for _, param := range provider.Params {
+			if param.Input {
+				inputs[param.Type] = append(inputs[param.Type], provider.ID)
+			}
+		}
+		root.Providers = append(root.Providers, provider)
+	}
+	for _, typ := range slices.Sorted(maps.Keys(inputs)) {
+		root.Inputs = append(root.Inputs, Input{Type: typ, ConsumedBy: inputs[typ]})
+	}
+
+	if structure == nil {
+		return root
+	}
+	root.Position = b.position(structure.Obj().Pos())
+	if st, ok := structure.Underlying().(*types.Struct); ok {
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			typ := field.Type().String()
+			root.Fields = append(root.Fields, Field{
+				Name:     field.Name(),
+				Type:     typ,
+				Provider: providerOf(typ, tree.Trees),
+			})
+		}
+	}
+	return root
+}
+
+func (b *builder) provider(node *analyze.FnDITreeNode) Provider {
+	p := Provider{
+		ID:      node.PkgPath + "." + node.Name,
+		Name:    node.Name,
+		PkgPath: node.PkgPath,
+		PkgName: node.PkgName,
+		Kind:    string(node.Kind),
+		Params:  make([]Param, 0),
+		Results: node.ReturnTypes,
+	}
+	if len(node.ReturnTypes) > 0 {
+		p.Provides = node.ReturnTypes[0]
+	}
+	if node.ReturnsInterface {
+		p.Binding = &Binding{Interface: p.Provides}
+	}
+	if node.Kind != "" && node.Kind != analyze.ProviderFunc {
+		// 関数以外のプロバイダは宣言された型の位置を使い、束縛する実装は解析結果から分かる
+		if node.Source != nil {
+			p.Position = b.typePosition(node.Source.PkgPath, node.Source.Name)
+		} else {
+			p.Position = b.typePosition(node.PkgPath, node.Name)
+		}
+		if p.Binding != nil && node.Source != nil {
+			p.Binding.Implementation = node.Source.String()
+		}
+		for i, typ := range node.Params {
+			p.Params = append(p.Params, newParam(i, "", typ, node.Childs))
+		}
+		return p
+	}
+
+	fn, ok := b.funcs[p.ID]
+	if !ok {
+		// 型情報が無い場合は解析結果から分かる範囲で埋める
+		for i, typ := range node.Params {
+			p.Params = append(p.Params, newParam(i, "", typ, node.Childs))
+		}
+		return p
+	}
+
+	p.Position = b.position(fn.Pos())
+	p.Signature = "func " + fn.Name() + strings.TrimPrefix(types.TypeString(fn.Type(), packageNameQualifier), "func")
+	params := fn.Signature().Params()
+	for i := 0; i < params.Len(); i++ {
+		p.Params = append(p.Params, newParam(i, params.At(i).Name(), params.At(i).Type().String(), node.Childs))
+	}
+	p.HasCleanup, p.ReturnsError = analyze.ResultFlags(fn)
+	if p.Binding != nil {
+		p.Binding.Implementation = b.implementation(fn)
+	}
+	return p
+}
+
+// typePosition はパッケージレベルで宣言された型の位置を返す
+func (b *builder) typePosition(pkgPath, name string) Position {
+	if obj, ok := b.types[pkgPath+"."+name]; ok {
+		return b.position(obj.Pos())
+	}
+	return Position{}
+}
+
+func newParam(index int, name, typ string, childs []*analyze.FnDITreeNode) Param {
+	provider := providerOf(typ, childs)
+	return Param{Index: index, Name: name, Type: typ, Provider: provider, Input: provider == ""}
+}
+
+// providerOf は typ を返すプロバイダの ID を返す。ポインタの有無は区別しない
+func providerOf(typ string, nodes []*analyze.FnDITreeNode) string {
+	want := strings.TrimPrefix(typ, "*")
+	for _, node := range nodes {
+		if len(node.ReturnTypes) > 0 && strings.TrimPrefix(node.ReturnTypes[0], "*") == want {
+			return node.PkgPath + "." + node.Name
+		}
+	}
+	return ""
+}
+
+// implementation はインターフェースを返すプロバイダの return 文が全て同じ具象型を返す場合、その型を返す
+func (b *builder) implementation(fn *types.Func) string {
+	d, ok := b.decls[fn]
+	if !ok || d.decl.Body == nil {
+		return ""
+	}
+	impl := ""
+	consistent := true
+	ast.Inspect(d.decl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// 関数リテラルの return 文はプロバイダの返り値ではない
+			return false
+		case *ast.ReturnStmt:
+			if len(n.Results) == 0 {
+				consistent = false
+				return false
+			}
+			t := d.info.TypeOf(n.Results[0])
+			if basic, ok := t.(*types.Basic); ok && basic.Kind() == types.UntypedNil {
+				// エラー時の return nil, err は実装の型に関係しない
+				return false
+			}
+			if t == nil || types.IsInterface(t) {
+				consistent = false
+				return false
+			}
+			if impl != "" && impl != t.String() {
+				consistent = false
+			}
+			impl = t.String()
+		}
+		return consistent
+	})
+	if !consistent {
+		return ""
+	}
+	return impl
+}
+
+// packageNameQualifier は型をパッケージ名で修飾する（シグネチャを読みやすくするため）
+func packageNameQualifier(pkg *types.Package) string {
+	return pkg.Name()
+}
@@ -0,0 +1,146 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SchemaVersion はレポートのスキーマのバージョン。
+// フィールドの削除や意味の変更など、既存の利用者が壊れうる変更を行った場合に上げる（フィールドの追加では上げない）
+const SchemaVersion = 1
+
+// Report は cire generate -j が出力する解析レポート。スキーマは report.schema.json で公開している
+type Report struct {
+	SchemaVersion int       `json:"schema_version"`
+	Generator     Generator `json:"generator"`
+	// Input はルート構造体を定義したファイル（レポートのディレクトリからの相対パス）
+	Input   string  `json:"input"`
+	Package Package `json:"package"`
+	// Roots はルート構造体ごとの解析結果（ルート構造体の定義順）
+	Roots       []Root       `json:"roots"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Generator はレポートを出力した cire の情報
+type Generator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Package は生成ファイルのパッケージ
+type Package struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Position はソースコード上の位置。File はレポートのディレクトリからの相対パス
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Root はルート構造体の解析結果
+type Root struct {
+	Name     string   `json:"name"`
+	Position Position `json:"position"`
+	Fields   []Field  `json:"fields"`
+	// Providers はルート構造体の生成に必要なプロバイダ（ルート構造体のフィールドから深さ優先で辿った順）
+	Providers []Provider `json:"providers"`
+	// Inputs はどのプロバイダからも供給されない引数の型（型名の昇順）
+	Inputs []Input `json:"inputs"`
+}
+
+// Field はルート構造体のフィールド
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Provider はフィールドの値を供給するプロバイダの ID。プロバイダが無い場合は空
+	Provider string `json:"provider,omitempty"`
+}
+
+// Provider はプロバイダ
+type Provider struct {
+	// ID は「パッケージパス.関数名」。関数以外のプロバイダでは「パッケージパス.型名」（フィールドは「パッケージパス.構造体名.フィールド名」）
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	PkgPath string `json:"pkg_path"`
+	PkgName string `json:"pkg_name"`
+	// Kind はプロバイダの種類（"func", "bind", "struct" または "field"）
+	Kind     string   `json:"kind"`
+	Position Position `json:"position"`
+	// Signature は関数のシグネチャ。関数以外のプロバイダでは空
+	Signature string   `json:"signature"`
+	Params    []Param  `json:"params"`
+	Results   []string `json:"results"`
+	// Provides はプロバイダが供給する型（最初の返り値の型）
+	Provides     string `json:"provides"`
+	ReturnsError bool   `json:"returns_error"`
+	HasCleanup   bool   `json:"has_cleanup"`
+	// Binding はインターフェースを返すプロバイダとインターフェースの束縛の場合のみ設定される
+	Binding *Binding `json:"binding,omitempty"`
+}
+
+// Param はプロバイダの引数と、その値を供給するプロバイダ
+type Param struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	// Provider は引数の値を供給するプロバイダの ID。Input の場合は空
+	Provider string `json:"provider,omitempty"`
+	// Input はどのプロバイダからも供給されない（外部から与える）引数かどうか
+	Input bool `json:"input"`
+}
+
+// Binding はインターフェースを返すプロバイダが束縛するインターフェースと実装
+type Binding struct {
+	Interface string `json:"interface"`
+	// Implementation は関数本体の return 文から特定できた実装の型。特定できない場合は空
+	Implementation string `json:"implementation,omitempty"`
+}
+
+// Input はどのプロバイダからも供給されない引数の型
+type Input struct {
+	Type string `json:"type"`
+	// ConsumedBy はこの型を引数に取るプロバイダの ID
+	ConsumedBy []string `json:"consumed_by"`
+}
+
+// Diagnostic は解析で見つかった問題
+type Diagnostic struct {
+	// Code は問題の種類を表すコード（例: CIRE001）
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Position は問題の位置。位置が不明な場合は省略する
+	Position *Position `json:"position,omitempty"`
+	Related  []Related `json:"related,omitempty"`
+}
+
+// Related は診断に関連する位置（例: 曖昧なプロバイダの候補）
+type Related struct {
+	Position *Position `json:"position,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// Marshal はレポートをインデント付きの JSON に変換する
+func (r *Report) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Write はレポートを JSON ファイルに書き出す
+func Write(path string, r *Report) error {
+	data, err := r.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write JSON file: %w", err)
+	}
+	return nil
+}
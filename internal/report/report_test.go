@@ -0,0 +1,120 @@
+package report
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rmocchy/cire/internal/analyze"
+)
+
+// schemaObject は JSON Schema のうちテストで参照する部分
+type schemaObject struct {
+	Required   []string                  `json:"required"`
+	Properties map[string]schemaProperty `json:"properties"`
+	Defs       map[string]schemaObject   `json:"$defs"`
+}
+
+type schemaProperty struct {
+	Ref   string `json:"$ref"`
+	Items struct {
+		Ref string `json:"$ref"`
+	} `json:"items"`
+}
+
+// TestSchema_MatchesTypes は JSON Schema と Go の型の JSON フィールドが一致していることを確認する
+func TestSchema_MatchesTypes(t *testing.T) {
+	var schema schemaObject
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		def string
+		typ reflect.Type
+	}{
+		{def: "", typ: reflect.TypeOf(Report{})},
+		{def: "position", typ: reflect.TypeOf(Position{})},
+		{def: "root", typ: reflect.TypeOf(Root{})},
+		{def: "field", typ: reflect.TypeOf(Field{})},
+		{def: "provider", typ: reflect.TypeOf(Provider{})},
+		{def: "param", typ: reflect.TypeOf(Param{})},
+		{def: "binding", typ: reflect.TypeOf(Binding{})},
+		{def: "input", typ: reflect.TypeOf(Input{})},
+		{def: "diagnostic", typ: reflect.TypeOf(Diagnostic{})},
+		{def: "related", typ: reflect.TypeOf(Related{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ.Name(), func(t *testing.T) {
+			obj := schema
+			if tt.def != "" {
+				var ok bool
+				if obj, ok = schema.Defs[tt.def]; !ok {
+					t.Fatalf("schema has no definition %q", tt.def)
+				}
+			}
+
+			for i := 0; i < tt.typ.NumField(); i++ {
+				tag := tt.typ.Field(i).Tag.Get("json")
+				name, opts, _ := strings.Cut(tag, ",")
+				if _, ok := obj.Properties[name]; !ok {
+					t.Errorf("schema %q has no property %q", tt.def, name)
+				}
+				if required := slices.Contains(obj.Required, name); required == (opts == "omitempty") {
+					t.Errorf("schema %q: property %q required = %v, but json tag is %q", tt.def, name, required, tag)
+				}
+			}
+			if len(obj.Properties) != tt.typ.NumField() {
+				t.Errorf("schema %q has %d properties, want %d", tt.def, len(obj.Properties), tt.typ.NumField())
+			}
+		})
+	}
+}
+
+func TestBuild_ParamEdgesAndInputs(t *testing.T) {
+	repo := &analyze.FnDITreeNode{
+		Name:        "NewRepository",
+		PkgPath:     "example.com/app/repository",
+		PkgName:     "repository",
+		ReturnTypes: []string{"*example.com/app/repository.Repository"},
+		Params:      []string{"string"},
+	}
+	svc := &analyze.FnDITreeNode{
+		Name:             "NewService",
+		PkgPath:          "example.com/app/service",
+		PkgName:          "service",
+		Childs:           []*analyze.FnDITreeNode{repo},
+		ReturnTypes:      []string{"example.com/app/service.Service"},
+		Params:           []string{"*example.com/app/repository.Repository", "string"},
+		ReturnsInterface: true,
+	}
+	r := Build(&Source{
+		Trees:   analyze.RootTrees{{Name: "App", Trees: []*analyze.FnDITreeNode{svc}}},
+		Version: "v1.0.0",
+	})
+
+	if r.SchemaVersion != SchemaVersion || r.Generator.Version != "v1.0.0" {
+		t.Errorf("header = %d %q", r.SchemaVersion, r.Generator.Version)
+	}
+	root := r.Roots[0]
+	if len(root.Providers) != 2 {
+		t.Fatalf("len(Providers) = %d, want 2", len(root.Providers))
+	}
+	params := root.Providers[0].Params
+	if params[0].Provider != "example.com/app/repository.NewRepository" || params[0].Input {
+		t.Errorf("params[0] = %+v, want fed by NewRepository", params[0])
+	}
+	if params[1].Provider != "" || !params[1].Input {
+		t.Errorf("params[1] = %+v, want input", params[1])
+	}
+	if root.Providers[0].Binding == nil || root.Providers[0].Binding.Interface != "example.com/app/service.Service" {
+		t.Errorf("Binding = %+v", root.Providers[0].Binding)
+	}
+	want := []Input{{Type: "string", ConsumedBy: []string{"example.com/app/service.NewService", "example.com/app/repository.NewRepository"}}}
+	if !reflect.DeepEqual(root.Inputs, want) {
+		t.Errorf("Inputs = %+v, want %+v", root.Inputs, want)
+	}
+}
@@ -0,0 +1,8 @@
+package report
+
+import _ "embed"
+
+// Schema はレポートの JSON Schema
+//
+//go:embed report.schema.json
+var Schema []byte
@@ -0,0 +1,13 @@
+package version
+
+import "runtime/debug"
+
+// Version は実行中の cire のバージョンを返す。
+// go install でインストールされた場合はモジュールのバージョン、それ以外は "(devel)" になる
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "(devel)"
+	}
+	return info.Main.Version
+}